@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS movies_available_from_idx;
+
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS available_from,
+    DROP COLUMN IF EXISTS available_until;
\ No newline at end of file
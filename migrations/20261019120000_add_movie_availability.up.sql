@@ -0,0 +1,5 @@
+ALTER TABLE movies
+    ADD COLUMN available_from  TIMESTAMPTZ,
+    ADD COLUMN available_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS movies_available_from_idx ON movies (available_from);
\ No newline at end of file
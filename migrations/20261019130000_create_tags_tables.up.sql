@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS tags (
+    tag_id SERIAL PRIMARY KEY,
+    name   VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS movie_tags (
+    movie_tag_id SERIAL PRIMARY KEY,
+    movie_id     INTEGER NOT NULL,
+    tag_id       INTEGER NOT NULL,
+    user_id      INTEGER NOT NULL,
+    visibility   VARCHAR(10) NOT NULL CHECK (visibility IN ('public', 'private')),
+    status       VARCHAR(10) NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (movie_id, tag_id, user_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(tag_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS movie_tags_tag_id_idx ON movie_tags (tag_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shelf_movies;
+DROP TABLE IF EXISTS shelves;
\ No newline at end of file
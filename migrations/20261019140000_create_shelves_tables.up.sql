@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS shelves (
+    shelf_id        SERIAL PRIMARY KEY,
+    slug            VARCHAR(64) NOT NULL UNIQUE,
+    title           VARCHAR(255) NOT NULL,
+    position        INTEGER NOT NULL DEFAULT 0,
+    starts_at       TIMESTAMPTZ,
+    ends_at         TIMESTAMPTZ,
+    regions         TEXT[] NOT NULL DEFAULT '{}',
+    content_ratings TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS shelf_movies (
+    shelf_id INTEGER NOT NULL,
+    movie_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (shelf_id, movie_id),
+    FOREIGN KEY (shelf_id) REFERENCES shelves(shelf_id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
\ No newline at end of file
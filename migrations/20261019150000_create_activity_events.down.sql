@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS trending_scores;
+DROP TABLE IF EXISTS activity_events;
+
+ALTER TABLE cart DROP COLUMN IF EXISTS added_at;
\ No newline at end of file
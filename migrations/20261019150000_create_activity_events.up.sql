@@ -0,0 +1,21 @@
+ALTER TABLE cart ADD COLUMN added_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS activity_events (
+    event_id    BIGSERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL,
+    movie_id    INTEGER NOT NULL,
+    kind        VARCHAR(20) NOT NULL CHECK (kind IN ('add_to_cart', 'rental')),
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS activity_events_occurred_at_idx ON activity_events (occurred_at);
+
+CREATE TABLE IF NOT EXISTS trending_scores (
+    time_window VARCHAR(8) NOT NULL,
+    movie_id    INTEGER NOT NULL,
+    score       DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (time_window, movie_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
\ No newline at end of file
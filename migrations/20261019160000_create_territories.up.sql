@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id INTEGER PRIMARY KEY,
+    region  CHAR(2) NOT NULL
+);
+
+-- A movie with no rows here is licensed everywhere. Otherwise it is only
+-- licensed in the listed regions, each within [licensed_from, licensed_until).
+CREATE TABLE IF NOT EXISTS movie_territories (
+    movie_id       INTEGER NOT NULL,
+    region         CHAR(2) NOT NULL,
+    licensed_from  TIMESTAMPTZ,
+    licensed_until TIMESTAMPTZ,
+    PRIMARY KEY (movie_id, region),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE,
+    CHECK (licensed_until IS NULL OR licensed_from IS NULL OR licensed_until > licensed_from)
+);
\ No newline at end of file
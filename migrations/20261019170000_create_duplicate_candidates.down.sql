@@ -0,0 +1 @@
+DROP TABLE IF EXISTS duplicate_candidates;
\ No newline at end of file
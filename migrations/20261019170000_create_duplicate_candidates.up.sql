@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS duplicate_candidates (
+    movie_id     INTEGER NOT NULL,
+    duplicate_id INTEGER NOT NULL,
+    score        DOUBLE PRECISION NOT NULL,
+    detected_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (movie_id, duplicate_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE,
+    FOREIGN KEY (duplicate_id) REFERENCES movies(movie_id) ON DELETE CASCADE,
+    CHECK (movie_id < duplicate_id)
+);
\ No newline at end of file
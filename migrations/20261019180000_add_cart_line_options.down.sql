@@ -0,0 +1,5 @@
+ALTER TABLE cart
+    DROP COLUMN IF EXISTS rental_days,
+    DROP COLUMN IF EXISTS format;
+
+DROP TABLE IF EXISTS movie_offers;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS movie_offers (
+    movie_id    INTEGER NOT NULL,
+    format      VARCHAR(10) NOT NULL CHECK (format IN ('digital', 'dvd', 'bluray')),
+    rental_days INTEGER NOT NULL CHECK (rental_days > 0),
+    PRIMARY KEY (movie_id, format, rental_days),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
+
+-- Existing titles stay rentable as 2- or 7-day digital rentals.
+INSERT INTO movie_offers (movie_id, format, rental_days)
+SELECT movie_id, 'digital', days FROM movies, (VALUES (2), (7)) AS d(days)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE cart
+    ADD COLUMN format VARCHAR(10) NOT NULL DEFAULT 'digital',
+    ADD COLUMN rental_days INTEGER NOT NULL DEFAULT 2;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS movie_prices;
\ No newline at end of file
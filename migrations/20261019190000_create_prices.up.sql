@@ -0,0 +1,23 @@
+-- Prices are in integer minor units (cents) and belong to an offer, so
+-- they follow it when an offer moves to another movie.
+CREATE TABLE IF NOT EXISTS movie_prices (
+    movie_id    INTEGER NOT NULL,
+    format      VARCHAR(10) NOT NULL,
+    rental_days INTEGER NOT NULL,
+    amount      INTEGER NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (movie_id, format, rental_days),
+    FOREIGN KEY (movie_id, format, rental_days) REFERENCES movie_offers(movie_id, format, rental_days)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+INSERT INTO movie_prices (movie_id, format, rental_days, amount)
+SELECT movie_id, format, rental_days, CASE rental_days WHEN 2 THEN 399 ELSE 599 END
+FROM movie_offers
+WHERE format = 'digital'
+ON CONFLICT DO NOTHING;
+
+-- Sales tax by region, in basis points. Regions without a row are untaxed.
+CREATE TABLE IF NOT EXISTS tax_rates (
+    region   CHAR(2) PRIMARY KEY,
+    rate_bps INTEGER NOT NULL CHECK (rate_bps BETWEEN 0 AND 10000)
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS cart_promos;
+DROP TABLE IF EXISTS promo_codes;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- Amounts are in minor units. A NULL limit or date means no limit.
+CREATE TABLE IF NOT EXISTS promo_codes (
+    code               VARCHAR(32) PRIMARY KEY CHECK (code = UPPER(code)),
+    description        TEXT NOT NULL DEFAULT '',
+    kind               VARCHAR(16) NOT NULL CHECK (kind IN ('percentage', 'fixed', 'buy_get')),
+    percent            INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+    amount             INTEGER NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    buy_quantity       INTEGER NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0),
+    get_quantity       INTEGER NOT NULL DEFAULT 0 CHECK (get_quantity >= 0),
+    max_discount       INTEGER CHECK (max_discount >= 0),
+    genre              VARCHAR(255),
+    new_customers_only BOOLEAN NOT NULL DEFAULT FALSE,
+    min_subtotal       INTEGER NOT NULL DEFAULT 0 CHECK (min_subtotal >= 0),
+    max_uses           INTEGER CHECK (max_uses > 0),
+    max_uses_per_user  INTEGER CHECK (max_uses_per_user > 0),
+    starts_at          TIMESTAMPTZ,
+    expires_at         TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The promo code applied to a user's cart; at most one per cart.
+CREATE TABLE IF NOT EXISTS cart_promos (
+    user_id    INTEGER PRIMARY KEY,
+    code       VARCHAR(32) NOT NULL REFERENCES promo_codes(code) ON DELETE CASCADE,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per completed order that used a code; counted for usage limits.
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    redemption_id SERIAL PRIMARY KEY,
+    code          VARCHAR(32) NOT NULL REFERENCES promo_codes(code) ON DELETE CASCADE,
+    user_id       INTEGER NOT NULL,
+    redeemed_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_code_user ON promo_redemptions (code, user_id);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS cart_added_at_idx;
+
+DELETE FROM activity_events WHERE kind = 'cart_abandoned';
+ALTER TABLE activity_events DROP CONSTRAINT IF EXISTS activity_events_kind_check;
+ALTER TABLE activity_events ADD CONSTRAINT activity_events_kind_check
+    CHECK (kind IN ('add_to_cart', 'rental'));
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE activity_events DROP CONSTRAINT IF EXISTS activity_events_kind_check;
+ALTER TABLE activity_events ADD CONSTRAINT activity_events_kind_check
+    CHECK (kind IN ('add_to_cart', 'rental', 'cart_abandoned'));
+
+CREATE INDEX IF NOT EXISTS cart_added_at_idx ON cart (added_at);
\ No newline at end of file
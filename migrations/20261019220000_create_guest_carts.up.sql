@@ -0,0 +1,13 @@
+-- Carts of visitors who are not logged in, keyed by the id in their signed
+-- guest token. They are merged into the user's cart on login.
+CREATE TABLE IF NOT EXISTS guest_carts (
+    guest_id    VARCHAR(32) NOT NULL,
+    movie_id    INTEGER NOT NULL,
+    format      VARCHAR(10) NOT NULL DEFAULT 'digital',
+    rental_days INTEGER NOT NULL DEFAULT 2,
+    added_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (guest_id, movie_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS guest_carts_added_at_idx ON guest_carts (added_at);
\ No newline at end of file
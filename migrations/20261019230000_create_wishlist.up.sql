@@ -0,0 +1,12 @@
+-- Movies users have saved to rent someday, kept apart from the cart.
+-- format and rental_days remember how a movie saved from the cart was to
+-- be rented, so moving it back restores the same line.
+CREATE TABLE IF NOT EXISTS wishlist (
+    user_id     INTEGER NOT NULL,
+    movie_id    INTEGER NOT NULL,
+    format      VARCHAR(10) NOT NULL DEFAULT 'digital',
+    rental_days INTEGER NOT NULL DEFAULT 2,
+    added_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, movie_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS membership_tiers;
\ No newline at end of file
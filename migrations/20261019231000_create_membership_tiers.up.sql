@@ -0,0 +1,18 @@
+-- Limits of each membership tier. NULL means no limit.
+CREATE TABLE IF NOT EXISTS membership_tiers (
+    tier                 VARCHAR(32) PRIMARY KEY,
+    max_cart_items       INTEGER CHECK (max_cart_items > 0),
+    max_physical_rentals INTEGER CHECK (max_physical_rentals > 0),
+    max_new_releases     INTEGER CHECK (max_new_releases > 0)
+);
+
+INSERT INTO membership_tiers (tier, max_cart_items, max_physical_rentals, max_new_releases)
+VALUES ('standard', 5, 3, 2), ('premium', 20, 6, 5)
+ON CONFLICT DO NOTHING;
+
+-- Users without a membership are on the standard tier.
+CREATE TABLE IF NOT EXISTS memberships (
+    user_id INTEGER PRIMARY KEY,
+    tier    VARCHAR(32) NOT NULL,
+    FOREIGN KEY (tier) REFERENCES membership_tiers(tier) ON UPDATE CASCADE
+);
\ No newline at end of file
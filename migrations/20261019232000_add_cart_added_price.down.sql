@@ -0,0 +1,2 @@
+ALTER TABLE guest_carts DROP COLUMN IF EXISTS price;
+ALTER TABLE cart DROP COLUMN IF EXISTS price;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- The list price of each item when it was added, so that carts can warn
+-- when it changes. NULL when the item had no price then.
+ALTER TABLE cart ADD COLUMN price INTEGER;
+ALTER TABLE guest_carts ADD COLUMN price INTEGER;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- Responses to requests sent with an Idempotency-Key header, replayed when
+-- a client retries the request. status is NULL while the first request is
+-- still being handled.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status       INTEGER,
+    headers      JSONB,
+    body         BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS gifts;
+
+ALTER TABLE cart
+    DROP CONSTRAINT IF EXISTS cart_gift_one_recipient,
+    DROP COLUMN IF EXISTS gift_message,
+    DROP COLUMN IF EXISTS gift_recipient_email,
+    DROP COLUMN IF EXISTS gift_recipient_id;
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- A cart line may be a gift, naming its recipient by user id or, for
+-- people without an account, by email, and carrying an optional message.
+ALTER TABLE cart
+    ADD COLUMN gift_recipient_id    INTEGER,
+    ADD COLUMN gift_recipient_email VARCHAR(254),
+    ADD COLUMN gift_message         VARCHAR(500),
+    ADD CONSTRAINT cart_gift_one_recipient CHECK (gift_recipient_id IS NULL OR gift_recipient_email IS NULL);
+
+-- Gifts bought at checkout. The rental belongs to the recipient, who
+-- starts it by claiming the gift with its code; gifts addressed to a user
+-- can only be claimed by that user.
+CREATE TABLE IF NOT EXISTS gifts (
+    gift_id         SERIAL PRIMARY KEY,
+    claim_code      VARCHAR(32) NOT NULL UNIQUE,
+    sender_id       INTEGER NOT NULL,
+    recipient_id    INTEGER,
+    recipient_email VARCHAR(254),
+    movie_id        INTEGER NOT NULL,
+    format          VARCHAR(10) NOT NULL,
+    rental_days     INTEGER NOT NULL,
+    message         VARCHAR(500) NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_by      INTEGER,
+    claimed_at      TIMESTAMPTZ,
+    CHECK ((recipient_id IS NULL) <> (recipient_email IS NULL)),
+    FOREIGN KEY (movie_id) REFERENCES movies(movie_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS gifts_recipient_id_idx ON gifts (recipient_id);
+CREATE INDEX IF NOT EXISTS gifts_claimed_by_idx ON gifts (claimed_by);
\ No newline at end of file
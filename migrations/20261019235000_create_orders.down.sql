@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rentals;
+DROP TABLE IF EXISTS orders;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- Orders are checked-out carts. Amounts are in minor units of currency,
+-- stamped at checkout so that later price changes leave them alone.
+CREATE TABLE IF NOT EXISTS orders (
+    order_id       SERIAL PRIMARY KEY,
+    user_id        INTEGER NOT NULL,
+    region         CHAR(2),
+    currency       CHAR(3) NOT NULL,
+    subtotal       BIGINT NOT NULL,
+    discounts      JSONB NOT NULL DEFAULT '[]',
+    discount_total BIGINT NOT NULL,
+    tax            BIGINT NOT NULL,
+    total          BIGINT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id);
+
+-- The rental lines of orders. A rental belongs to user_id from starts_at
+-- until ends_at. Gifted rentals have neither until their gift is claimed.
+-- Rentals keep their movies from being deleted.
+CREATE TABLE IF NOT EXISTS rentals (
+    rental_id   SERIAL PRIMARY KEY,
+    order_id    INTEGER NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    user_id     INTEGER,
+    movie_id    INTEGER NOT NULL REFERENCES movies(movie_id),
+    format      VARCHAR(10) NOT NULL,
+    rental_days INTEGER NOT NULL,
+    price       BIGINT NOT NULL,
+    gift_id     INTEGER UNIQUE REFERENCES gifts(gift_id),
+    starts_at   TIMESTAMPTZ,
+    ends_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS rentals_user_id_idx ON rentals (user_id, ends_at);
+CREATE INDEX IF NOT EXISTS rentals_order_id_idx ON rentals (order_id);
\ No newline at end of file
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// writeCSV renders rows in full before writing anything, so that a failure
+// is reported as an error rather than as a truncated 200.
+func writeCSV(c *gin.Context, status int, rows interface{}) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, rows); err != nil {
+		InternalError(c, err)
+		return
+	}
+
+	c.Data(status, MIMECSV+"; charset=utf-8", buf.Bytes())
+}
+
+// WriteCSV writes rows to out in the same layout as CSV responses. rows must
+// be a slice of structs or maps.
+func WriteCSV(out io.Writer, rows interface{}) error {
+	header, records, err := csvRecords(reflect.ValueOf(rows))
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(out)
+	if header != nil {
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+	return w.WriteAll(records)
+}
+
+// csvRecords flattens a slice of structs or maps into a header and rows.
+// Struct columns are named after their json tags; map columns are sorted.
+func csvRecords(rows reflect.Value) ([]string, [][]string, error) {
+	if rows.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("csv: rows must be a slice, not %s", rows.Kind())
+	}
+	if rows.Len() == 0 {
+		return nil, nil, nil
+	}
+
+	elem := indirect(rows.Index(0))
+	switch elem.Kind() {
+	case reflect.Struct:
+		var header []string
+		var fields []int
+		for i := 0; i < elem.NumField(); i++ {
+			f := elem.Type().Field(i)
+			name := csvColumnName(f)
+			if name == "" {
+				continue
+			}
+			header = append(header, name)
+			fields = append(fields, i)
+		}
+		records := make([][]string, rows.Len())
+		for r := range records {
+			row := indirect(rows.Index(r))
+			records[r] = make([]string, len(fields))
+			for i, f := range fields {
+				records[r][i] = csvValue(row.Field(f))
+			}
+		}
+		return header, records, nil
+	case reflect.Map:
+		var header []string
+		for _, k := range elem.MapKeys() {
+			header = append(header, fmt.Sprint(k.Interface()))
+		}
+		sort.Strings(header)
+		records := make([][]string, rows.Len())
+		for r := range records {
+			row := indirect(rows.Index(r))
+			records[r] = make([]string, len(header))
+			for i, key := range header {
+				if v := row.MapIndex(reflect.ValueOf(key)); v.IsValid() {
+					records[r][i] = csvValue(v)
+				}
+			}
+		}
+		return header, records, nil
+	}
+	return nil, nil, fmt.Errorf("csv: unsupported row type %s", rows.Type().Elem())
+}
+
+func csvColumnName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := strings.Split(f.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return tag
+}
+
+// csvValue formats scalars directly and falls back to JSON for anything
+// nested, so that no information is silently dropped.
+func csvValue(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Map, reflect.Array:
+		b, _ := json.Marshal(v.Interface())
+		return string(b)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	router.GET("/", Deprecated(sunset, "/v2"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	recorder := serve(router, "/")
+
+	assert.Equal(t, "true", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	assert.Equal(t, `</v2>; rel="successor-version"`, recorder.Header().Get("Link"))
+}
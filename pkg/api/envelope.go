@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Version = "v2"
+
+// Envelope wraps every successful v2 response body.
+type Envelope struct {
+	XMLName xml.Name    `json:"-" xml:"response"`
+	Data    interface{} `json:"data" xml:"data"`
+	Meta    Meta        `json:"meta" xml:"meta"`
+}
+
+type Meta struct {
+	APIVersion string `json:"api_version" xml:"api_version"`
+	Count      *int   `json:"count,omitempty" xml:"count,omitempty"`
+}
+
+// ErrorResponse is the body of every failed v2 response. Code is stable and
+// meant for programmatic checks; Message is for humans and may change.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    string `json:"code" xml:"code"`
+	Message string `json:"message" xml:"message"`
+	// Details optionally carries machine-readable specifics of the error.
+	Details interface{} `json:"details,omitempty" xml:"details,omitempty"`
+}
+
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeNotFound       = "not_found"
+	CodeInternal       = "internal_error"
+)
+
+func OK(c *gin.Context, status int, data interface{}) {
+	Respond(c, status, Envelope{Data: data, Meta: Meta{APIVersion: Version}})
+}
+
+// OKList responds with a resource that holds a list, such as a cart. CSV
+// clients receive only rows.
+func OKList(c *gin.Context, data, rows interface{}) {
+	RespondList(c, http.StatusOK, Envelope{Data: data, Meta: Meta{APIVersion: Version}}, rows)
+}
+
+func List(c *gin.Context, data interface{}, count int) {
+	RespondList(c, http.StatusOK, Envelope{Data: data, Meta: Meta{APIVersion: Version, Count: &count}}, data)
+}
+
+func Error(c *gin.Context, status int, code, message string) {
+	ErrorWithDetails(c, status, code, message, nil)
+}
+
+func ErrorWithDetails(c *gin.Context, status int, code, message string, details interface{}) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: ErrorDetail{Code: code, Message: message, Details: details}})
+}
+
+// InternalError records err on the context for the logger and responds with a
+// generic message, so that database details never reach v2 clients.
+func InternalError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	Error(c, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+)
+
+const (
+	MIMECSV = "text/csv"
+
+	CodeNotAcceptable = "not_acceptable"
+)
+
+var (
+	resourceFormats = []string{
+		binding.MIMEJSON,
+		binding.MIMEXML,
+		binding.MIMEXML2,
+		binding.MIMEMSGPACK,
+		binding.MIMEMSGPACK2,
+	}
+	listFormats = append(append([]string{}, resourceFormats...), MIMECSV)
+)
+
+// Respond writes body as JSON, XML or MessagePack, whichever the Accept
+// header prefers. JSON is used when the client expresses no preference.
+func Respond(c *gin.Context, status int, body interface{}) {
+	respond(c, status, body, nil, resourceFormats)
+}
+
+// RespondList is like Respond but also offers CSV, in which case only rows is
+// written, one line per element.
+func RespondList(c *gin.Context, status int, body, rows interface{}) {
+	respond(c, status, body, rows, listFormats)
+}
+
+func respond(c *gin.Context, status int, body, rows interface{}, offered []string) {
+	switch c.NegotiateFormat(offered...) {
+	case binding.MIMEJSON:
+		c.JSON(status, body)
+	case binding.MIMEXML, binding.MIMEXML2:
+		c.XML(status, xmlDocument(body))
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		c.Render(status, render.MsgPack{Data: body})
+	case MIMECSV:
+		writeCSV(c, status, rows)
+	default:
+		Error(c, http.StatusNotAcceptable, CodeNotAcceptable, "supported formats are JSON, XML, MessagePack and, for lists, CSV")
+	}
+}
+
+// xmlList gives top-level slices a single root element, which XML requires.
+type xmlList struct {
+	XMLName xml.Name    `xml:"items"`
+	Items   interface{} `xml:"item"`
+}
+
+func xmlDocument(body interface{}) interface{} {
+	if v := reflect.ValueOf(body); v.Kind() == reflect.Slice {
+		return xmlList{Items: body}
+	}
+	return body
+}
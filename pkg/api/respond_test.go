@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+type testRow struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	Released *time.Time `json:"released"`
+	Tags     []string   `json:"tags"`
+	Internal string     `json:"-"`
+}
+
+func serveAccept(accept string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", handler)
+	req, _ := http.NewRequest("GET", "/", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func testRows() []testRow {
+	released := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	return []testRow{
+		{ID: 1, Name: "Movie 1", Released: &released, Tags: []string{"a", "b"}, Internal: "x"},
+		{ID: 2, Name: "Movie, 2"},
+	}
+}
+
+func TestRespondList_DefaultsToJSON(t *testing.T) {
+	recorder := serveAccept("", func(c *gin.Context) { RespondList(c, http.StatusOK, testRows(), testRows()) })
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+}
+
+func TestRespondList_CSV(t *testing.T) {
+	recorder := serveAccept("text/csv", func(c *gin.Context) { RespondList(c, http.StatusOK, testRows(), testRows()) })
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/csv")
+	assert.Equal(t, "id,name,released,tags\n"+
+		"1,Movie 1,2020-01-02T00:00:00Z,\"[\"\"a\"\",\"\"b\"\"]\"\n"+
+		"2,\"Movie, 2\",,null\n", recorder.Body.String())
+}
+
+func TestRespondList_CSVFromMaps(t *testing.T) {
+	rows := []gin.H{{"Title": "Movie 1", "MovieID": 1}}
+	recorder := serveAccept("text/csv", func(c *gin.Context) { RespondList(c, http.StatusOK, rows, rows) })
+
+	assert.Equal(t, "MovieID,Title\n1,Movie 1\n", recorder.Body.String())
+}
+
+func TestRespondList_CSVUnsupportedRows(t *testing.T) {
+	rows := []int{1, 2}
+	recorder := serveAccept("text/csv", func(c *gin.Context) { RespondList(c, http.StatusOK, rows, rows) })
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+	assert.Contains(t, recorder.Body.String(), CodeInternal)
+}
+
+func TestRespondList_XMLHasSingleRoot(t *testing.T) {
+	recorder := serveAccept("application/xml", func(c *gin.Context) { RespondList(c, http.StatusOK, testRows(), testRows()) })
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<items><item><ID>1</ID>")
+}
+
+func TestRespond_MessagePack(t *testing.T) {
+	recorder := serveAccept("application/x-msgpack", func(c *gin.Context) { Respond(c, http.StatusOK, testRows()[1]) })
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var decoded map[string]interface{}
+	err := codec.NewDecoderBytes(recorder.Body.Bytes(), new(codec.MsgpackHandle)).Decode(&decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "Movie, 2", string(decoded["name"].([]byte)))
+}
+
+func TestRespond_CSVNotOfferedForResources(t *testing.T) {
+	recorder := serveAccept("text/csv", func(c *gin.Context) { Respond(c, http.StatusOK, testRows()[0]) })
+
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeNotAcceptable)
+}
+
+func TestRespond_Unsupported(t *testing.T) {
+	recorder := serveAccept("text/html", func(c *gin.Context) { Respond(c, http.StatusOK, testRows()[0]) })
+
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
@@ -0,0 +1,162 @@
+package cart
+
+import (
+	"encoding/xml"
+	"time"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/pricing"
+)
+
+// AddToCartRequestV2 is the v2 request body for adding a movie to a cart.
+// Format and RentalDays default to a 48-hour digital rental.
+type AddToCartRequestV2 struct {
+	UserID     int    `json:"user_id" binding:"required,gt=0"`
+	MovieID    int    `json:"movie_id" binding:"required,gt=0"`
+	Format     Format `json:"format" binding:"omitempty,oneof=digital dvd bluray"`
+	RentalDays int    `json:"rental_days" binding:"omitempty,gt=0"`
+}
+
+// AddToGuestCartRequest is the request body for adding a movie to a guest
+// cart.
+type AddToGuestCartRequest struct {
+	MovieID    int    `json:"movie_id" binding:"required,gt=0"`
+	Format     Format `json:"format" binding:"omitempty,oneof=digital dvd bluray"`
+	RentalDays int    `json:"rental_days" binding:"omitempty,gt=0"`
+}
+
+// BatchAddRequest is the request body for adding several movies to a cart
+// at once. With AllOrNothing, either every item is added or none is.
+type BatchAddRequest struct {
+	Items        []BatchItemRequest `json:"items" binding:"required,min=1,max=50,dive"`
+	AllOrNothing bool               `json:"all_or_nothing"`
+}
+
+// BatchItemRequest is an item of a batch add. Format and RentalDays
+// default to a 48-hour digital rental.
+type BatchItemRequest struct {
+	MovieID    int    `json:"movie_id" binding:"required,gt=0"`
+	Format     Format `json:"format" binding:"omitempty,oneof=digital dvd bluray"`
+	RentalDays int    `json:"rental_days" binding:"omitempty,gt=0"`
+}
+
+// CartItemResponse acknowledges a movie added to a cart. UserID is omitted
+// for guest carts.
+type CartItemResponse struct {
+	XMLName    xml.Name `json:"-" xml:"cart_item"`
+	UserID     int      `json:"user_id,omitempty" xml:"user_id,omitempty"`
+	MovieID    int      `json:"movie_id" xml:"movie_id"`
+	Format     Format   `json:"format" xml:"format"`
+	RentalDays int      `json:"rental_days" xml:"rental_days"`
+}
+
+// CartResponse is the v2 representation of a user's cart, revalidated and
+// priced. Amounts are in minor units of Currency and cover the lines that
+// can be rented. UserID is omitted for guest carts.
+type CartResponse struct {
+	XMLName       xml.Name           `json:"-" xml:"cart"`
+	UserID        int                `json:"user_id,omitempty" xml:"user_id,omitempty"`
+	Items         []CartLineResponse `json:"items" xml:"items>item"`
+	Currency      string             `json:"currency" xml:"currency"`
+	Subtotal      int64              `json:"subtotal" xml:"subtotal"`
+	Discounts     []pricing.Discount `json:"discounts" xml:"discounts>discount"`
+	DiscountTotal int64              `json:"discount_total" xml:"discount_total"`
+	Tax           int64              `json:"tax" xml:"tax"`
+	Total         int64              `json:"total" xml:"total"`
+	CheckoutReady bool               `json:"checkout_ready" xml:"checkout_ready"`
+}
+
+// CartLineResponse is a cart line. Price is null when the line cannot be
+// rented, and Warnings say why.
+type CartLineResponse struct {
+	XMLName    xml.Name             `json:"-" xml:"item"`
+	Movie      movies.MovieResponse `json:"movie" xml:"movie"`
+	Format     Format               `json:"format" xml:"format"`
+	RentalDays int                  `json:"rental_days" xml:"rental_days"`
+	AddedAt    time.Time            `json:"added_at" xml:"added_at"`
+	Price      *int64               `json:"price" xml:"price,omitempty"`
+	Warnings   []Warning            `json:"warnings" xml:"warnings>warning"`
+	Gift       *GiftResponse        `json:"gift,omitempty" xml:"gift,omitempty"`
+}
+
+// GiftRequest is the request body for making a cart line a gift. Exactly
+// one of RecipientUserID and RecipientEmail names the recipient.
+type GiftRequest struct {
+	RecipientUserID int    `json:"recipient_user_id" binding:"omitempty,gt=0"`
+	RecipientEmail  string `json:"recipient_email" binding:"omitempty,email,max=254"`
+	Message         string `json:"message" binding:"max=500"`
+}
+
+type GiftResponse struct {
+	XMLName         xml.Name `json:"-" xml:"gift"`
+	RecipientUserID int      `json:"recipient_user_id,omitempty" xml:"recipient_user_id,omitempty"`
+	RecipientEmail  string   `json:"recipient_email,omitempty" xml:"recipient_email,omitempty"`
+	Message         string   `json:"message" xml:"message"`
+}
+
+// NewGiftResponse returns nil when gift is nil.
+func NewGiftResponse(gift *Gift) *GiftResponse {
+	if gift == nil {
+		return nil
+	}
+	return &GiftResponse{RecipientUserID: gift.RecipientID, RecipientEmail: gift.RecipientEmail, Message: gift.Message}
+}
+
+func NewCartResponse(userID int, review Review) CartResponse {
+	items := make([]CartLineResponse, len(review.Lines))
+	for i, l := range review.Lines {
+		items[i] = CartLineResponse{
+			Movie:      movies.NewMovieResponse(l.Movie),
+			Format:     l.Format,
+			RentalDays: l.RentalDays,
+			AddedAt:    l.AddedAt,
+			Price:      l.Price,
+			Warnings:   l.Warnings,
+			Gift:       NewGiftResponse(l.Gift),
+		}
+	}
+	quote := review.Quote
+	return CartResponse{
+		UserID:        userID,
+		Items:         items,
+		Currency:      quote.Currency,
+		Subtotal:      quote.Subtotal,
+		Discounts:     quote.Discounts,
+		DiscountTotal: quote.DiscountTotal,
+		Tax:           quote.Tax,
+		Total:         quote.Total,
+		CheckoutReady: review.CheckoutReady(),
+	}
+}
+
+// MergeResult counts what happened to the items of a merged guest cart.
+type MergeResult struct {
+	XMLName    xml.Name `json:"-" xml:"merge"`
+	Merged     int      `json:"merged" xml:"merged"`
+	Duplicates int      `json:"duplicates" xml:"duplicates"`
+	Dropped    int      `json:"dropped" xml:"dropped"`
+}
+
+// BatchAddResponse reports what happened to each item of a batch add, in
+// the order they were sent.
+type BatchAddResponse struct {
+	XMLName xml.Name          `json:"-" xml:"batch"`
+	UserID  int               `json:"user_id" xml:"user_id"`
+	Added   int               `json:"added" xml:"added"`
+	Failed  int               `json:"failed" xml:"failed"`
+	Items   []BatchItemResult `json:"items" xml:"items>item"`
+}
+
+// BatchItemResult is the outcome of one item of a batch add. Error says
+// why the item was not added; it is absent for items that were added, and
+// for items left out only because another item of an all-or-nothing batch
+// failed.
+type BatchItemResult struct {
+	XMLName    xml.Name         `json:"-" xml:"item"`
+	MovieID    int              `json:"movie_id" xml:"movie_id"`
+	Format     Format           `json:"format" xml:"format"`
+	RentalDays int              `json:"rental_days" xml:"rental_days"`
+	Added      bool             `json:"added" xml:"added"`
+	Error      *api.ErrorDetail `json:"error,omitempty" xml:"error,omitempty"`
+}
@@ -1,13 +1,17 @@
 package cart
 
 import (
+	"errors"
+	"movie-rental/pkg/api"
 	"movie-rental/pkg/movies"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/region"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AddToCartHandler(repo Repository) gin.HandlerFunc {
+func AddToCartHandler(repo Repository, limiter Limiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req AddToCartRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -15,11 +19,31 @@ func AddToCartHandler(repo Repository) gin.HandlerFunc {
 			return
 		}
 
-		if err := repo.AddToCart(req.UserID, req.MovieID); err != nil {
+		userRegion, err := region.Resolve(c, req.UserID)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		item := NewItem(req.MovieID, req.Format, req.RentalDays)
+		err = limiter.CheckAdd(c.Request.Context(), req.UserID, PolicyItem(item))
+		if err == nil {
+			err = repo.AddToCart(req.UserID, item, userRegion)
+		}
+		if err != nil {
+			// v1 keeps its flat error body but shares the status and code
+			// of each reason with v2.
+			var v *policy.Violation
+			if errors.As(err, &v) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": policy.CodeLimitExceeded, "rule": v.Rule})
+			} else if status, detail, ok := addErrorDetail(err); ok {
+				c.JSON(status, gin.H{"error": detail.Message, "code": detail.Code})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Movie added to cart"})
 	}
 }
@@ -28,16 +52,17 @@ func ViewCartHandler(repo Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.Param("user_id")
 
-		moviesList, err := repo.GetCartItems(userID)
+		lines, err := repo.GetCartItems(userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-        if len(moviesList) == 0 {
-            c.JSON(http.StatusOK, gin.H{"movies": []movies.Movie{}})
-            return
-        }
+		// v1 carts are a bare list of movies.
+		moviesList := make([]movies.Movie, len(lines))
+		for i, l := range lines {
+			moviesList[i] = l.Movie
+		}
 
-		c.JSON(http.StatusOK, gin.H{"movies": moviesList})
+		api.RespondList(c, http.StatusOK, gin.H{"movies": moviesList}, moviesList)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package cart
+
+import (
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchAction is the last path segment of the batch add route.
+const BatchAction = "items:batch"
+
+// CodeBatchRejected is the error code of an all-or-nothing batch add that
+// added nothing because some items could not be added. The error details
+// report every item.
+const CodeBatchRejected = "batch_rejected"
+
+// BatchAddToCartHandler adds several movies to the user's cart in one
+// transaction and reports the outcome for each. Items that cannot be added
+// are skipped, or with all_or_nothing, none are added. It is routed as
+// /cart/:user_id/:action, since gin cannot route a literal colon, and
+// answers 404 for actions other than BatchAction.
+func BatchAddToCartHandler(repo Repository, limiter Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param("action") != BatchAction {
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, "no such cart action")
+			return
+		}
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+		var req BatchAddRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "items must hold 1 to 50 items with positive movie_ids")
+			return
+		}
+
+		userRegion, err := region.Resolve(c, userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		items := make([]Item, len(req.Items))
+		policyItems := make([]policy.Item, len(req.Items))
+		for i, r := range req.Items {
+			items[i] = NewItem(r.MovieID, r.Format, r.RentalDays)
+			policyItems[i] = PolicyItem(items[i])
+		}
+
+		ctx := c.Request.Context()
+		errs, err := limiter.CheckEach(ctx, userID, policyItems...)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		var adding []Item
+		var indexes []int
+		for i, item := range items {
+			if errs[i] == nil {
+				adding = append(adding, item)
+				indexes = append(indexes, i)
+			}
+		}
+		if !req.AllOrNothing || len(adding) == len(items) {
+			addErrs, err := repo.AddItemsToCart(ctx, userID, adding, userRegion, req.AllOrNothing)
+			if err != nil {
+				api.InternalError(c, err)
+				return
+			}
+			for j, err := range addErrs {
+				errs[indexes[j]] = err
+			}
+		}
+
+		resp, err := newBatchAddResponse(userID, items, errs, req.AllOrNothing)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if req.AllOrNothing && resp.Failed > 0 {
+			api.ErrorWithDetails(c, http.StatusUnprocessableEntity, CodeBatchRejected,
+				"no items were added because some of them cannot be", resp)
+			return
+		}
+		api.OK(c, http.StatusOK, resp)
+	}
+}
+
+// newBatchAddResponse reports errs for items. It returns the first error
+// that is not a reason an item cannot be added, if any.
+func newBatchAddResponse(userID int, items []Item, errs []error, allOrNothing bool) (BatchAddResponse, error) {
+	resp := BatchAddResponse{UserID: userID, Items: make([]BatchItemResult, len(items))}
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		detail, ok := policy.ErrorDetail(err)
+		if !ok {
+			_, detail, ok = addErrorDetail(err)
+		}
+		if !ok {
+			return resp, err
+		}
+		resp.Items[i].Error = &detail
+		resp.Failed++
+	}
+	for i, item := range items {
+		r := &resp.Items[i]
+		r.MovieID, r.Format, r.RentalDays = item.MovieID, item.Format, item.RentalDays
+		if r.Error == nil && !(allOrNothing && resp.Failed > 0) {
+			r.Added = true
+			resp.Added++
+		}
+	}
+	return resp, nil
+}
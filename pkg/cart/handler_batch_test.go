@@ -0,0 +1,106 @@
+package cart
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"movie-rental/pkg/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchLimiter refuses movie 9 as one too many for the cart.
+type batchLimiter struct{ mockLimiter }
+
+func (batchLimiter) CheckEach(_ context.Context, userID int, items ...policy.Item) ([]error, error) {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		if item.MovieID == 9 {
+			errs[i] = &policy.Violation{Rule: policy.RuleMaxCartItems, Tier: "standard", Limit: 5}
+		}
+	}
+	return errs, nil
+}
+
+func serveBatch(repo Repository, path, body string) *httptest.ResponseRecorder {
+	router := gin.Default()
+	router.POST("/v2/cart/:user_id/:action", BatchAddToCartHandler(repo, batchLimiter{}))
+
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestBatchAddToCartHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddItemsToCartFunc: func(userID int, items []Item, region string, allOrNothing bool) ([]error, error) {
+			assert.Equal(t, 1, userID)
+			assert.False(t, allOrNothing)
+			// Movie 9 was refused by the limiter and is not passed on.
+			assert.Equal(t, []Item{{MovieID: 2, Format: FormatDigital, RentalDays: 2}, {MovieID: 3, Format: FormatDVD, RentalDays: 7}}, items)
+			return []error{nil, ErrAlreadyInCart}, nil
+		},
+	}
+
+	recorder := serveBatch(repo, "/v2/cart/1/items:batch",
+		`{"items":[{"movie_id":2},{"movie_id":9},{"movie_id":3,"format":"dvd","rental_days":7}]}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"user_id":1,"added":1,"failed":2,"items":[
+		{"movie_id":2,"format":"digital","rental_days":2,"added":true},
+		{"movie_id":9,"format":"digital","rental_days":2,"added":false,"error":{"code":"limit_exceeded",
+			"message":"the standard tier allows at most 5 items in the cart","details":{"rule":"max_cart_items","tier":"standard","limit":5}}},
+		{"movie_id":3,"format":"dvd","rental_days":7,"added":false,"error":{"code":"already_in_cart","message":"movie is already in the cart"}}
+	]},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestBatchAddToCartHandler_AllOrNothing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddItemsToCartFunc: func(userID int, items []Item, region string, allOrNothing bool) ([]error, error) {
+			assert.True(t, allOrNothing)
+			return []error{nil, ErrOptionNotOffered}, nil
+		},
+	}
+
+	recorder := serveBatch(repo, "/v2/cart/1/items:batch", `{"items":[{"movie_id":2},{"movie_id":3}],"all_or_nothing":true}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.JSONEq(t, `{"error":{"code":"batch_rejected","message":"no items were added because some of them cannot be",
+		"details":{"user_id":1,"added":0,"failed":1,"items":[
+			{"movie_id":2,"format":"digital","rental_days":2,"added":false},
+			{"movie_id":3,"format":"digital","rental_days":2,"added":false,"error":{"code":"option_not_offered",
+				"message":"movie is not offered in that format and rental duration"}}
+		]}}}`, recorder.Body.String())
+
+	// Items refused by the limiter stop the batch before the repository is
+	// asked.
+	recorder = serveBatch(&mockRepository{}, "/v2/cart/1/items:batch", `{"items":[{"movie_id":2},{"movie_id":9}],"all_or_nothing":true}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeBatchRejected)
+}
+
+func TestBatchAddToCartHandler_BadRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		path, body string
+		status     int
+	}{
+		{"/v2/cart/1/items:batch", `{"items":[]}`, http.StatusBadRequest},
+		{"/v2/cart/1/items:batch", `{"items":[{"movie_id":0}]}`, http.StatusBadRequest},
+		{"/v2/cart/1/items:batch", `{"items":[{"movie_id":2,"format":"vhs"}]}`, http.StatusBadRequest},
+		{"/v2/cart/abc/items:batch", `{"items":[{"movie_id":2}]}`, http.StatusBadRequest},
+		{"/v2/cart/1/items:purge", `{"items":[{"movie_id":2}]}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		recorder := serveBatch(&mockRepository{}, tc.path, tc.body)
+		assert.Equal(t, tc.status, recorder.Code, tc.path+" "+tc.body)
+	}
+}
@@ -0,0 +1,84 @@
+package cart
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetGiftHandler makes a line of the user's cart a gift for someone else,
+// replacing any recipient it had. At checkout the rental goes to the
+// recipient, who claims it.
+func SetGiftHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, movieID, ok := lineParams(c)
+		if !ok {
+			return
+		}
+		var req GiftRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "recipient_email must be an email address and message at most 500 characters")
+			return
+		}
+		if (req.RecipientUserID == 0) == (req.RecipientEmail == "") {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "exactly one of recipient_user_id and recipient_email is required")
+			return
+		}
+		if req.RecipientUserID == userID {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "a gift must be for someone else")
+			return
+		}
+
+		gift := &Gift{RecipientID: req.RecipientUserID, RecipientEmail: req.RecipientEmail, Message: req.Message}
+		err := repo.SetGift(c.Request.Context(), userID, movieID, gift)
+		switch {
+		case errors.Is(err, ErrNotInCart):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			api.OK(c, http.StatusOK, NewGiftResponse(gift))
+		}
+	}
+}
+
+// RemoveGiftHandler makes a gift line of the user's cart their own rental
+// again.
+func RemoveGiftHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, movieID, ok := lineParams(c)
+		if !ok {
+			return
+		}
+
+		err := repo.SetGift(c.Request.Context(), userID, movieID, nil)
+		switch {
+		case errors.Is(err, ErrNotInCart):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+// lineParams parses the user_id and movie_id of a cart line route, and
+// responds with 400 if either is not an integer.
+func lineParams(c *gin.Context) (userID, movieID int, ok bool) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+		return 0, 0, false
+	}
+	movieID, err = strconv.Atoi(c.Param("movie_id"))
+	if err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie_id must be an integer")
+		return 0, 0, false
+	}
+	return userID, movieID, true
+}
@@ -0,0 +1,78 @@
+package cart
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveGift(repo Repository, method, path, body string) *httptest.ResponseRecorder {
+	router := gin.Default()
+	router.PUT("/v2/cart/:user_id/items/:movie_id/gift", SetGiftHandler(repo))
+	router.DELETE("/v2/cart/:user_id/items/:movie_id/gift", RemoveGiftHandler(repo))
+
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestSetGiftHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		SetGiftFunc: func(userID, movieID int, gift *Gift) error {
+			assert.Equal(t, 1, userID)
+			assert.Equal(t, 2, movieID)
+			assert.Equal(t, &Gift{RecipientEmail: "friend@example.com", Message: "Enjoy!"}, gift)
+			return nil
+		},
+	}
+
+	recorder := serveGift(repo, "PUT", "/v2/cart/1/items/2/gift", `{"recipient_email":"friend@example.com","message":"Enjoy!"}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"recipient_email":"friend@example.com","message":"Enjoy!"},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestSetGiftHandler_Invalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, body := range []string{
+		`{}`,
+		`{"recipient_user_id":5,"recipient_email":"friend@example.com"}`,
+		`{"recipient_user_id":1}`,
+		`{"recipient_email":"not an email"}`,
+	} {
+		recorder := serveGift(&mockRepository{}, "PUT", "/v2/cart/1/items/2/gift", body)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+}
+
+func TestSetGiftHandler_NotInCart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		SetGiftFunc: func(userID, movieID int, gift *Gift) error { return ErrNotInCart },
+	}
+
+	recorder := serveGift(repo, "PUT", "/v2/cart/1/items/2/gift", `{"recipient_user_id":5}`)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = serveGift(repo, "DELETE", "/v2/cart/1/items/2/gift", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestRemoveGiftHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		SetGiftFunc: func(userID, movieID int, gift *Gift) error {
+			assert.Nil(t, gift)
+			return nil
+		},
+	}
+
+	recorder := serveGift(repo, "DELETE", "/v2/cart/1/items/2/gift", "")
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+}
@@ -0,0 +1,115 @@
+package cart
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/guest"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddToGuestCartHandler adds a movie to the cart of a visitor who is not
+// logged in. Visitors without a valid guest token are issued a new one,
+// starting a new cart.
+func AddToGuestCartHandler(repo Repository, tokens *guest.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AddToGuestCartRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie_id must be a positive integer")
+			return
+		}
+
+		guestID, err := tokens.Verify(guest.FromRequest(c))
+		if err != nil {
+			var token string
+			token, guestID, err = tokens.New()
+			if err != nil {
+				api.InternalError(c, err)
+				return
+			}
+			guest.Issue(c, token)
+		}
+
+		userRegion, err := region.Resolve(c, 0)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		item := NewItem(req.MovieID, req.Format, req.RentalDays)
+		if err := repo.AddToGuestCart(guestID, item, userRegion); err != nil {
+			RespondAddError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusCreated, CartItemResponse{
+			MovieID:    item.MovieID,
+			Format:     item.Format,
+			RentalDays: item.RentalDays,
+		})
+	}
+}
+
+// ViewGuestCartHandler responds with the visitor's guest cart, revalidated
+// and priced. It is empty without a valid guest token.
+func ViewGuestCartHandler(repo Repository, tokens *guest.Signer, pricer Pricer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var lines []Line
+		if guestID, err := tokens.Verify(guest.FromRequest(c)); err == nil {
+			lines, err = repo.GetGuestCartItems(guestID)
+			if err != nil {
+				api.InternalError(c, err)
+				return
+			}
+		}
+
+		userRegion, err := region.Resolve(c, 0)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		respondReviewed(c, repo, pricer, 0, userRegion, lines)
+	}
+}
+
+// MergeGuestCartHandler moves the visitor's guest cart into the user's
+// cart once they have logged in, and discards the guest token. Items that
+// would break a limit of the user's tier, on top of the items merged before
+// them, are dropped.
+func MergeGuestCartHandler(repo Repository, tokens *guest.Signer, limiter Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+		guestID, err := tokens.Verify(guest.FromRequest(c))
+		if errors.Is(err, guest.ErrInvalidToken) {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "a valid guest token is required")
+			return
+		}
+
+		ctx := c.Request.Context()
+		check := func(items []Item) ([]error, error) {
+			checked := make([]policy.Item, len(items))
+			for i, item := range items {
+				checked[i] = PolicyItem(item)
+			}
+			return limiter.CheckEach(ctx, userID, checked...)
+		}
+		result, err := repo.MergeGuestCart(ctx, guestID, userID, check)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		guest.Clear(c)
+		api.OK(c, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,136 @@
+package cart
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"movie-rental/pkg/guest"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/pricing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTokens = guest.NewSigner([]byte("test-key"))
+
+func setupGuestRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.POST("/v2/guest/cart", AddToGuestCartHandler(repo, testTokens))
+	router.GET("/v2/guest/cart", ViewGuestCartHandler(repo, testTokens, pricing.NewPricer(mockPriceRepository{})))
+	router.POST("/v2/cart/:user_id/merge", MergeGuestCartHandler(repo, testTokens, mockLimiter{}))
+	return router
+}
+
+func serveGuest(router *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set(guest.Header, token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAddToGuestCartHandler_IssuesToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var added string
+	repo := &mockRepository{
+		AddToGuestCartFunc: func(guestID string, item Item, region string) error {
+			added = guestID
+			assert.Equal(t, Item{MovieID: 2, Format: FormatDigital, RentalDays: 2}, item)
+			return nil
+		},
+	}
+	router := setupGuestRouter(repo)
+
+	recorder := serveGuest(router, "POST", "/v2/guest/cart", "", `{"movie_id":2}`)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.JSONEq(t, `{"data":{"movie_id":2,"format":"digital","rental_days":2},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+	token := recorder.Header().Get(guest.Header)
+	id, err := testTokens.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, id, added)
+	assert.Contains(t, recorder.Header().Get("Set-Cookie"), guest.CookieName+"="+token)
+}
+
+func TestAddToGuestCartHandler_KeepsValidToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, id, _ := testTokens.New()
+	repo := &mockRepository{
+		AddToGuestCartFunc: func(guestID string, item Item, region string) error {
+			assert.Equal(t, id, guestID)
+			return ErrAlreadyInCart
+		},
+	}
+	router := setupGuestRouter(repo)
+
+	recorder := serveGuest(router, "POST", "/v2/guest/cart", token, `{"movie_id":2}`)
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeAlreadyInCart)
+	assert.Empty(t, recorder.Header().Get(guest.Header))
+}
+
+func TestViewGuestCartHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, id, _ := testTokens.New()
+	repo := &mockRepository{
+		GetGuestCartItemsFunc: func(guestID string) ([]Line, error) {
+			assert.Equal(t, id, guestID)
+			return []Line{{Movie: movies.Movie{MovieID: 2}, Format: FormatDigital, RentalDays: 2}}, nil
+		},
+	}
+	router := setupGuestRouter(repo)
+
+	recorder := serveGuest(router, "GET", "/v2/guest/cart", token, "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "user_id")
+	assert.Contains(t, recorder.Body.String(), `"total":399`)
+
+	recorder = serveGuest(router, "GET", "/v2/guest/cart", "forged", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"items":[]`)
+}
+
+func TestMergeGuestCartHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, id, _ := testTokens.New()
+	repo := &mockRepository{
+		MergeGuestCartFunc: func(guestID string, userID int, check func([]Item) ([]error, error)) (MergeResult, error) {
+			assert.Equal(t, id, guestID)
+			assert.Equal(t, 1, userID)
+			errs, err := check([]Item{{MovieID: 2, Format: FormatDVD}, {MovieID: 3, Format: FormatDigital}})
+			assert.NoError(t, err)
+			assert.Len(t, errs, 2)
+			assert.ErrorAs(t, errs[0], new(*policy.Violation))
+			return MergeResult{Merged: 2, Duplicates: 1}, nil
+		},
+	}
+	violation := &policy.Violation{Rule: policy.RuleMaxPhysicalRentals, Tier: policy.DefaultTier, Limit: 1}
+	router := gin.Default()
+	router.POST("/v2/cart/:user_id/merge", MergeGuestCartHandler(repo, testTokens, mockLimiter{err: violation}))
+
+	recorder := serveGuest(router, "POST", "/v2/cart/1/merge", token, "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"merged":2,"duplicates":1,"dropped":0},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+	assert.Contains(t, recorder.Header().Get("Set-Cookie"), guest.CookieName+"=;")
+}
+
+func TestMergeGuestCartHandler_InvalidToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupGuestRouter(&mockRepository{})
+
+	for _, token := range []string{"", "forged"} {
+		recorder := serveGuest(router, "POST", "/v2/cart/1/merge", token, "")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+	recorder := serveGuest(router, "POST", "/v2/cart/abc/merge", "", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -2,12 +2,15 @@ package cart
 
 import (
     "bytes"
+    "context"
     "encoding/json"
     "errors"
     "movie-rental/pkg/movies"
+    "movie-rental/pkg/policy"
     "net/http"
     "net/http/httptest"
     "testing"
+    "time"
 
     "github.com/gin-gonic/gin"
     "github.com/stretchr/testify/assert"
@@ -15,20 +18,82 @@ import (
 
 // Mock Repository for handler tests
 type mockRepository struct {
-    AddToCartFunc   func(userID, movieID int) error
-    GetCartItemsFunc func(userID string) ([]movies.Movie, error)
+    AddToCartFunc   func(userID int, item Item, region string) error
+    AddItemsToCartFunc func(userID int, items []Item, region string, allOrNothing bool) ([]error, error)
+    GetCartItemsFunc func(userID string) ([]Line, error)
+    RemoveFromCartFunc func(userID, movieID int) error
+    ClearCartFunc      func(userID int) error
+    CheckItemsFunc     func(items []Item, region string) ([]ItemCheck, error)
+    ExpireItemsFunc    func(now time.Time, policy ExpiryPolicy) (int64, error)
+    SetGiftFunc        func(userID, movieID int, gift *Gift) error
+
+    AddToGuestCartFunc    func(guestID string, item Item, region string) error
+    GetGuestCartItemsFunc func(guestID string) ([]Line, error)
+    MergeGuestCartFunc    func(guestID string, userID int, check func([]Item) ([]error, error)) (MergeResult, error)
 }
 
-func (m *mockRepository) AddToCart(userID, movieID int) error {
-    return m.AddToCartFunc(userID, movieID)
+func (m *mockRepository) AddToCart(userID int, item Item, region string) error {
+    return m.AddToCartFunc(userID, item, region)
+}
+func (m *mockRepository) AddItemsToCart(_ context.Context, userID int, items []Item, region string, allOrNothing bool) ([]error, error) {
+    return m.AddItemsToCartFunc(userID, items, region, allOrNothing)
 }
-func (m *mockRepository) GetCartItems(userID string) ([]movies.Movie, error) {
+func (m *mockRepository) GetCartItems(userID string) ([]Line, error) {
     return m.GetCartItemsFunc(userID)
 }
+func (m *mockRepository) RemoveFromCart(userID, movieID int) error {
+    return m.RemoveFromCartFunc(userID, movieID)
+}
+func (m *mockRepository) ClearCart(userID int) error {
+    return m.ClearCartFunc(userID)
+}
+// CheckItems finds every item rentable, except that movie 404 has no
+// price, unless CheckItemsFunc says otherwise.
+func (m *mockRepository) CheckItems(_ context.Context, items []Item, region string) ([]ItemCheck, error) {
+    if m.CheckItemsFunc != nil {
+        return m.CheckItemsFunc(items, region)
+    }
+    checks := make([]ItemCheck, len(items))
+    for i, item := range items {
+        checks[i] = ItemCheck{Licensed: true, Offered: true, Priced: item.MovieID != 404}
+    }
+    return checks, nil
+}
+func (m *mockRepository) SetGift(_ context.Context, userID, movieID int, gift *Gift) error {
+    return m.SetGiftFunc(userID, movieID, gift)
+}
+func (m *mockRepository) ExpireItems(_ context.Context, now time.Time, policy ExpiryPolicy) (int64, error) {
+    return m.ExpireItemsFunc(now, policy)
+}
+func (m *mockRepository) AddToGuestCart(guestID string, item Item, region string) error {
+    return m.AddToGuestCartFunc(guestID, item, region)
+}
+func (m *mockRepository) GetGuestCartItems(guestID string) ([]Line, error) {
+    return m.GetGuestCartItemsFunc(guestID)
+}
+func (m *mockRepository) MergeGuestCart(_ context.Context, guestID string, userID int, check func([]Item) ([]error, error)) (MergeResult, error) {
+    return m.MergeGuestCartFunc(guestID, userID, check)
+}
+
+// mockLimiter allows everything unless given an error to refuse with.
+type mockLimiter struct {
+    err error
+}
+
+func (m mockLimiter) CheckAdd(_ context.Context, userID int, items ...policy.Item) error {
+    return m.err
+}
+func (m mockLimiter) CheckEach(_ context.Context, userID int, items ...policy.Item) ([]error, error) {
+    errs := make([]error, len(items))
+    for i := range errs {
+        errs[i] = m.err
+    }
+    return errs, nil
+}
 
 func setupRouter(repo Repository) *gin.Engine {
     router := gin.Default()
-    router.POST("/cart", AddToCartHandler(repo))
+    router.POST("/cart", AddToCartHandler(repo, mockLimiter{}))
     router.GET("/cart/:user_id", ViewCartHandler(repo))
     return router
 }
@@ -36,9 +101,9 @@ func setupRouter(repo Repository) *gin.Engine {
 func TestAddToCartHandler_Success(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockRepository{
-        AddToCartFunc: func(userID, movieID int) error {
+        AddToCartFunc: func(userID int, item Item, region string) error {
             assert.Equal(t, 1, userID)
-            assert.Equal(t, 2, movieID)
+            assert.Equal(t, Item{MovieID: 2, Format: FormatDigital, RentalDays: 2}, item)
             return nil
         },
     }
@@ -73,7 +138,7 @@ func TestAddToCartHandler_BadRequest(t *testing.T) {
 func TestAddToCartHandler_DBError(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockRepository{
-        AddToCartFunc: func(userID, movieID int) error {
+        AddToCartFunc: func(userID int, item Item, region string) error {
             return errors.New("db error")
         },
     }
@@ -92,14 +157,84 @@ func TestAddToCartHandler_DBError(t *testing.T) {
     assert.Contains(t, recorder.Body.String(), "db error")
 }
 
+func TestAddToCartHandler_Unavailable(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockRepository{
+        AddToCartFunc: func(userID int, item Item, region string) error {
+            return ErrMovieUnavailable
+        },
+    }
+    router := setupRouter(repo)
+
+    buf := new(bytes.Buffer)
+    _ = json.NewEncoder(buf).Encode(AddToCartRequest{UserID: 1, MovieID: 2})
+
+    req, _ := http.NewRequest("POST", "/cart", buf)
+    req.Header.Set("Content-Type", "application/json")
+    recorder := httptest.NewRecorder()
+
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusConflict, recorder.Code)
+    assert.Contains(t, recorder.Body.String(), "not available")
+}
+
+func TestAddToCartHandler_TypedErrors(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    cases := []struct {
+        err    error
+        status int
+        code   string
+    }{
+        {ErrMovieNotFound, http.StatusNotFound, "not_found"},
+        {ErrAlreadyInCart, http.StatusConflict, "already_in_cart"},
+        {ErrMovieUnavailable, http.StatusConflict, CodeMovieUnavailable},
+        {ErrNotLicensed, http.StatusUnavailableForLegalReasons, CodeNotLicensed},
+        {ErrOptionNotOffered, http.StatusUnprocessableEntity, CodeOptionNotOffered},
+    }
+    for _, tc := range cases {
+        repo := &mockRepository{
+            AddToCartFunc: func(userID int, item Item, region string) error {
+                return tc.err
+            },
+        }
+        router := setupRouter(repo)
+
+        buf := new(bytes.Buffer)
+        _ = json.NewEncoder(buf).Encode(AddToCartRequest{UserID: 1, MovieID: 2})
+        req, _ := http.NewRequest("POST", "/cart", buf)
+        req.Header.Set("Content-Type", "application/json")
+        recorder := httptest.NewRecorder()
+        router.ServeHTTP(recorder, req)
+
+        assert.Equal(t, tc.status, recorder.Code)
+        assert.JSONEq(t, `{"error":"`+tc.err.Error()+`","code":"`+tc.code+`"}`, recorder.Body.String())
+    }
+}
+
+func TestAddToCartHandler_LimitExceeded(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    limiter := mockLimiter{err: &policy.Violation{Rule: policy.RuleMaxCartItems, Tier: "standard", Limit: 5}}
+    router := gin.Default()
+    router.POST("/cart", AddToCartHandler(&mockRepository{}, limiter))
+
+    req, _ := http.NewRequest("POST", "/cart", bytes.NewBufferString(`{"UserID":1,"MovieID":2}`))
+    req.Header.Set("Content-Type", "application/json")
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+    assert.JSONEq(t, `{"error":"the standard tier allows at most 5 items in the cart","code":"limit_exceeded","rule":"max_cart_items"}`, recorder.Body.String())
+}
+
 func TestViewCartHandler_Success(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockRepository{
-        GetCartItemsFunc: func(userID string) ([]movies.Movie, error) {
+        GetCartItemsFunc: func(userID string) ([]Line, error) {
             assert.Equal(t, "1", userID)
-            return []movies.Movie{
-                {MovieID: 1, Title: "Movie 1"},
-                {MovieID: 2, Title: "Movie 2"},
+            return []Line{
+                {Movie: movies.Movie{MovieID: 1, Title: "Movie 1"}},
+                {Movie: movies.Movie{MovieID: 2, Title: "Movie 2"}},
             }, nil
         },
     }
@@ -124,7 +259,7 @@ func TestViewCartHandler_Success(t *testing.T) {
 func TestViewCartHandler_DBError(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockRepository{
-        GetCartItemsFunc: func(userID string) ([]movies.Movie, error) {
+        GetCartItemsFunc: func(userID string) ([]Line, error) {
             return nil, errors.New("db error")
         },
     }
@@ -141,8 +276,8 @@ func TestViewCartHandler_DBError(t *testing.T) {
 func TestViewCartHandler_EmptyCart(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockRepository{
-        GetCartItemsFunc: func(userID string) ([]movies.Movie, error) {
-            return []movies.Movie{}, nil
+        GetCartItemsFunc: func(userID string) ([]Line, error) {
+            return []Line{}, nil
         },
     }
     router := setupRouter(repo)
@@ -159,4 +294,20 @@ func TestViewCartHandler_EmptyCart(t *testing.T) {
     err := json.NewDecoder(recorder.Body).Decode(&resp)
     assert.NoError(t, err)
     assert.Len(t, resp.Movies, 0)
-}
\ No newline at end of file
+}
+func TestViewCartHandler_NotAcceptable(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockRepository{
+        GetCartItemsFunc: func(userID string) ([]Line, error) {
+            return []Line{}, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/cart/1", nil)
+    req.Header.Set("Accept", "image/png")
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
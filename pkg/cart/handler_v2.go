@@ -0,0 +1,198 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/pricing"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeMovieUnavailable = "movie_unavailable"
+	CodeNotLicensed      = "not_licensed"
+	CodeAlreadyInCart    = "already_in_cart"
+	CodeOptionNotOffered = "option_not_offered"
+	CodePriceUnavailable = "price_unavailable"
+)
+
+// Pricer prices a cart. It is satisfied by *pricing.Pricer.
+type Pricer interface {
+	Price(ctx context.Context, req pricing.Request) (pricing.Quote, error)
+}
+
+// Limiter enforces the limits of a user's membership tier. It is satisfied
+// by *policy.Enforcer.
+type Limiter interface {
+	CheckAdd(ctx context.Context, userID int, items ...policy.Item) error
+	// CheckEach checks items as if added one after another, and returns
+	// the error for each that would break a limit.
+	CheckEach(ctx context.Context, userID int, items ...policy.Item) ([]error, error)
+}
+
+// PolicyItem describes item for a Limiter.
+func PolicyItem(item Item) policy.Item {
+	return policy.Item{MovieID: item.MovieID, Format: string(item.Format)}
+}
+
+func AddToCartV2Handler(repo Repository, limiter Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AddToCartRequestV2
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id and movie_id must be positive integers")
+			return
+		}
+
+		userRegion, err := region.Resolve(c, req.UserID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		item := NewItem(req.MovieID, req.Format, req.RentalDays)
+		if err := limiter.CheckAdd(c.Request.Context(), req.UserID, PolicyItem(item)); err != nil {
+			RespondAddError(c, err)
+			return
+		}
+		if err := repo.AddToCart(req.UserID, item, userRegion); err != nil {
+			RespondAddError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusCreated, CartItemResponse{
+			UserID:     req.UserID,
+			MovieID:    item.MovieID,
+			Format:     item.Format,
+			RentalDays: item.RentalDays,
+		})
+	}
+}
+
+// ViewCartV2Handler responds with the user's cart, revalidated and priced
+// for the user's region.
+func ViewCartV2Handler(repo Repository, pricer Pricer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+
+		lines, err := repo.GetCartItems(c.Param("user_id"))
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		userRegion, err := region.Resolve(c, userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		respondReviewed(c, repo, pricer, userID, userRegion, lines)
+	}
+}
+
+// respondReviewed responds with lines revalidated and priced as the cart
+// of userID, or of a guest when userID is 0.
+func respondReviewed(c *gin.Context, repo Repository, pricer Pricer, userID int, userRegion string, lines []Line) {
+	review, err := ReviewCart(c.Request.Context(), repo, pricer, userID, userRegion, lines, time.Now())
+	if errors.Is(err, pricing.ErrNoPrice) {
+		// A price was removed after the lines were checked.
+		api.Error(c, http.StatusConflict, CodePriceUnavailable, err.Error())
+		return
+	} else if err != nil {
+		api.InternalError(c, err)
+		return
+	}
+
+	resp := NewCartResponse(userID, review)
+	api.OKList(c, resp, resp.Items)
+}
+
+// RespondAddError responds with the reason an item could not be added to a
+// cart.
+func RespondAddError(c *gin.Context, err error) {
+	if policy.RespondError(c, err) {
+		return
+	}
+	status, detail, ok := addErrorDetail(err)
+	if !ok {
+		api.InternalError(c, err)
+		return
+	}
+	api.ErrorWithDetails(c, status, detail.Code, detail.Message, detail.Details)
+}
+
+// addErrorDetail describes why an item could not be added to a cart, and
+// reports whether err was such a reason rather than a failure. Limits are
+// left to policy.
+func addErrorDetail(err error) (int, api.ErrorDetail, bool) {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, ErrMovieNotFound):
+		status, code = http.StatusNotFound, api.CodeNotFound
+	case errors.Is(err, ErrAlreadyInCart):
+		status, code = http.StatusConflict, CodeAlreadyInCart
+	case errors.Is(err, ErrMovieUnavailable):
+		status, code = http.StatusConflict, CodeMovieUnavailable
+	case errors.Is(err, ErrNotLicensed):
+		status, code = http.StatusUnavailableForLegalReasons, CodeNotLicensed
+	case errors.Is(err, ErrOptionNotOffered):
+		status, code = http.StatusUnprocessableEntity, CodeOptionNotOffered
+	default:
+		return 0, api.ErrorDetail{}, false
+	}
+	return status, api.ErrorDetail{Code: code, Message: err.Error()}, true
+}
+
+func RemoveFromCartV2Handler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+		movieID, err := strconv.Atoi(c.Param("movie_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie_id must be an integer")
+			return
+		}
+
+		err = repo.RemoveFromCart(userID, movieID)
+		switch {
+		case errors.Is(err, ErrNotInCart):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+func ClearCartV2Handler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+
+		if err := repo.ClearCart(userID); err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,495 @@
+package cart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/pricing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockPriceRepository prices every item at 399 and taxes region GB at 20%.
+type mockPriceRepository struct{}
+
+func (mockPriceRepository) Prices(ctx context.Context, items []pricing.Item) (map[pricing.Item]int64, error) {
+	prices := make(map[pricing.Item]int64)
+	for _, item := range items {
+		if item.MovieID != 404 {
+			prices[item] = 399
+		}
+	}
+	return prices, nil
+}
+
+func (mockPriceRepository) TaxRate(ctx context.Context, region string) (int, error) {
+	if region == "GB" {
+		return 2000, nil
+	}
+	return 0, nil
+}
+
+func setupV2Router(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.POST("/v2/cart", AddToCartV2Handler(repo, mockLimiter{}))
+	router.GET("/v2/cart/:user_id", ViewCartV2Handler(repo, pricing.NewPricer(mockPriceRepository{})))
+	router.DELETE("/v2/cart/:user_id", ClearCartV2Handler(repo))
+	router.DELETE("/v2/cart/:user_id/items/:movie_id", RemoveFromCartV2Handler(repo))
+	return router
+}
+
+func TestAddToCartV2Handler_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddToCartFunc: func(userID int, item Item, region string) error {
+			assert.Equal(t, 1, userID)
+			assert.Equal(t, Item{MovieID: 2, Format: FormatDigital, RentalDays: 2}, item)
+			return nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.JSONEq(t, `{"data":{"user_id":1,"movie_id":2,"format":"digital","rental_days":2},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestAddToCartV2Handler_MissingFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockRepository{})
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"UserID":1,"MovieID":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid_request")
+}
+
+func TestAddToCartV2Handler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddToCartFunc: func(userID int, item Item, region string) error {
+			return errors.New("db error")
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "db error")
+}
+
+func TestAddToCartV2Handler_Unavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddToCartFunc: func(userID int, item Item, region string) error {
+			return ErrMovieUnavailable
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeMovieUnavailable)
+}
+
+func TestViewCartV2Handler_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		GetCartItemsFunc: func(userID string) ([]Line, error) {
+			return []Line{{Movie: movies.Movie{MovieID: 1, Title: "Movie 1"}, Format: FormatDVD, RentalDays: 7}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/cart/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"user_id":1`)
+	assert.Contains(t, recorder.Body.String(), `"movie_id":1`)
+	assert.Contains(t, recorder.Body.String(), `"format":"dvd","rental_days":7`)
+}
+
+func TestViewCartV2Handler_EmptyCart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		GetCartItemsFunc: func(userID string) ([]Line, error) {
+			return nil, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/cart/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"user_id":1,"items":[],"currency":"USD","subtotal":0,"discounts":[],"discount_total":0,"tax":0,"total":0,"checkout_ready":false},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestViewCartV2Handler_Priced(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		GetCartItemsFunc: func(userID string) ([]Line, error) {
+			return []Line{
+				{Movie: movies.Movie{MovieID: 1}, Format: FormatDigital, RentalDays: 2},
+				{Movie: movies.Movie{MovieID: 2}, Format: FormatDVD, RentalDays: 7},
+			}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/cart/1", nil)
+	req.Header.Set("X-Region", "GB")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"price":399`)
+	assert.Contains(t, body, `"currency":"USD","subtotal":798,"discounts":[],"discount_total":0,"tax":160,"total":958`)
+}
+
+func TestViewCartV2Handler_Revalidated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	retired := time.Now().Add(-time.Hour)
+	repo := &mockRepository{
+		GetCartItemsFunc: func(userID string) ([]Line, error) {
+			return []Line{
+				{Movie: movies.Movie{MovieID: 1}, Format: FormatDigital, RentalDays: 2},
+				{Movie: movies.Movie{MovieID: 404}, Format: FormatDigital, RentalDays: 2},
+				{Movie: movies.Movie{MovieID: 3, AvailableUntil: &retired}, Format: FormatDigital, RentalDays: 2},
+			}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/cart/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var resp struct {
+		Data struct {
+			Items []struct {
+				Price    *int64    `json:"price"`
+				Warnings []Warning `json:"warnings"`
+			} `json:"items"`
+			Subtotal      int64 `json:"subtotal"`
+			CheckoutReady bool  `json:"checkout_ready"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	items := resp.Data.Items
+	assert.Len(t, items, 3)
+	assert.Equal(t, int64(399), *items[0].Price)
+	assert.Empty(t, items[0].Warnings)
+	assert.Nil(t, items[1].Price)
+	assert.Equal(t, CodePriceUnavailable, items[1].Warnings[0].Code)
+	assert.Nil(t, items[2].Price)
+	assert.Equal(t, WarningRetired, items[2].Warnings[0].Code)
+	assert.True(t, items[2].Warnings[0].Blocking)
+	assert.Equal(t, int64(399), resp.Data.Subtotal)
+	assert.False(t, resp.Data.CheckoutReady)
+}
+
+func TestViewCartV2Handler_InvalidUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockRepository{})
+
+	req, _ := http.NewRequest("GET", "/v2/cart/abc", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestViewCartV2Handler_CSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		GetCartItemsFunc: func(userID string) ([]Line, error) {
+			return []Line{{Movie: movies.Movie{MovieID: 1, Title: "Movie 1", Year: 2020}, Format: FormatDigital, RentalDays: 2, AddedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/cart/1", nil)
+	req.Header.Set("Accept", "text/csv")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "movie,format,rental_days,added_at,price,warnings,gift\n"))
+	assert.Contains(t, recorder.Body.String(), `""title"":""Movie 1""`)
+	assert.Contains(t, recorder.Body.String(), ",digital,2,2026-10-01T12:00:00Z,399,[],\n")
+}
+
+func TestAddToCartV2Handler_NotLicensed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddToCartFunc: func(userID int, item Item, region string) error {
+			assert.Equal(t, "FR", region)
+			return ErrNotLicensed
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Region", "FR")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeNotLicensed)
+}
+
+func TestAddToCartV2Handler_Options(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddToCartFunc: func(userID int, item Item, region string) error {
+			assert.Equal(t, Item{MovieID: 2, Format: FormatBluRay, RentalDays: 7}, item)
+			return nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2,"format":"bluray","rental_days":7}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.JSONEq(t, `{"data":{"user_id":1,"movie_id":2,"format":"bluray","rental_days":7},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestAddToCartV2Handler_InvalidOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockRepository{})
+
+	for _, body := range []string{
+		`{"user_id":1,"movie_id":2,"format":"vhs"}`,
+		`{"user_id":1,"movie_id":2,"rental_days":-1}`,
+	} {
+		req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+		assert.Contains(t, recorder.Body.String(), "invalid_request")
+	}
+}
+
+func TestAddToCartV2Handler_OptionNotOffered(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddToCartFunc: func(userID int, item Item, region string) error {
+			return ErrOptionNotOffered
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2,"format":"dvd","rental_days":30}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeOptionNotOffered)
+}
+
+func TestRemoveFromCartV2Handler_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		RemoveFromCartFunc: func(userID, movieID int) error {
+			assert.Equal(t, 1, userID)
+			assert.Equal(t, 2, movieID)
+			return nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("DELETE", "/v2/cart/1/items/2", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestRemoveFromCartV2Handler_NotInCart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		RemoveFromCartFunc: func(userID, movieID int) error {
+			return ErrNotInCart
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("DELETE", "/v2/cart/1/items/99", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.JSONEq(t, `{"error":{"code":"not_found","message":"movie is not in the cart"}}`, recorder.Body.String())
+}
+
+func TestRemoveFromCartV2Handler_InvalidIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockRepository{})
+
+	for _, path := range []string{"/v2/cart/abc/items/2", "/v2/cart/1/items/abc"} {
+		req, _ := http.NewRequest("DELETE", path, nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+	}
+}
+
+func TestRemoveFromCartV2Handler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		RemoveFromCartFunc: func(userID, movieID int) error {
+			return errors.New("db error")
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("DELETE", "/v2/cart/1/items/2", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "db error")
+}
+
+func TestClearCartV2Handler_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ClearCartFunc: func(userID int) error {
+			assert.Equal(t, 1, userID)
+			return nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("DELETE", "/v2/cart/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+}
+
+func TestClearCartV2Handler_InvalidUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockRepository{})
+
+	req, _ := http.NewRequest("DELETE", "/v2/cart/abc", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestClearCartV2Handler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ClearCartFunc: func(userID int) error {
+			return errors.New("db error")
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("DELETE", "/v2/cart/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestAddToCartV2Handler_TypedErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrMovieNotFound, http.StatusNotFound, api.CodeNotFound},
+		{ErrAlreadyInCart, http.StatusConflict, CodeAlreadyInCart},
+	}
+	for _, tc := range cases {
+		repo := &mockRepository{
+			AddToCartFunc: func(userID int, item Item, region string) error {
+				return tc.err
+			},
+		}
+		router := setupV2Router(repo)
+
+		req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.status, recorder.Code)
+		assert.JSONEq(t, `{"error":{"code":"`+tc.code+`","message":"`+tc.err.Error()+`"}}`, recorder.Body.String())
+	}
+}
+
+func TestAddToCartV2Handler_LimitExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var checked []policy.Item
+	limiter := &recordingLimiter{check: func(userID int, items []policy.Item) error {
+		assert.Equal(t, 1, userID)
+		checked = items
+		return &policy.Violation{Rule: policy.RuleMaxNewReleases, Tier: "standard", Limit: 2}
+	}}
+	router := gin.Default()
+	router.POST("/v2/cart", AddToCartV2Handler(&mockRepository{}, limiter))
+
+	req, _ := http.NewRequest("POST", "/v2/cart", bytes.NewBufferString(`{"user_id":1,"movie_id":2,"format":"dvd","rental_days":7}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, []policy.Item{{MovieID: 2, Format: "dvd"}}, checked)
+	assert.JSONEq(t, `{"error":{"code":"limit_exceeded","message":"the standard tier allows at most 2 new releases at once",
+		"details":{"rule":"max_new_releases","tier":"standard","limit":2}}}`, recorder.Body.String())
+}
+
+type recordingLimiter struct {
+	mockLimiter
+	check func(userID int, items []policy.Item) error
+}
+
+func (l *recordingLimiter) CheckAdd(_ context.Context, userID int, items ...policy.Item) error {
+	return l.check(userID, items)
+}
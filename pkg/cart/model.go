@@ -1,6 +1,77 @@
 package cart
 
+import (
+    "movie-rental/pkg/movies"
+    "movie-rental/pkg/pricing"
+    "time"
+)
+
 type AddToCartRequest struct {
     UserID  int
     MovieID int
+    // Format and RentalDays default to a 48-hour digital rental.
+    Format     Format `binding:"omitempty,oneof=digital dvd bluray"`
+    RentalDays int    `binding:"omitempty,gt=0"`
+}
+
+// Format is how a movie is rented.
+type Format string
+
+const (
+    FormatDigital Format = "digital"
+    FormatDVD     Format = "dvd"
+    FormatBluRay  Format = "bluray"
+
+    DefaultFormat     = FormatDigital
+    DefaultRentalDays = 2
+)
+
+// Item is what to put in a cart: a movie, rented in one of the formats and
+// for one of the durations the movie offers.
+type Item struct {
+    MovieID    int
+    Format     Format
+    RentalDays int
+}
+
+// NewItem fills in the default format and duration where they are unset.
+func NewItem(movieID int, format Format, rentalDays int) Item {
+    if format == "" {
+        format = DefaultFormat
+    }
+    if rentalDays == 0 {
+        rentalDays = DefaultRentalDays
+    }
+    return Item{MovieID: movieID, Format: format, RentalDays: rentalDays}
+}
+
+// Line is an item in a cart.
+type Line struct {
+    Movie      movies.Movie
+    Format     Format
+    RentalDays int
+    AddedAt    time.Time
+    // AddedPrice is the list price when the item was added, or nil if it
+    // had none.
+    AddedPrice *int64
+    // Gift is nil unless the line is a gift for someone else.
+    Gift *Gift
+}
+
+// Gift makes a cart line a rental for someone else. The recipient is named
+// by RecipientID or, if they have no account, by RecipientEmail.
+type Gift struct {
+    RecipientID    int
+    RecipientEmail string
+    Message        string
+}
+
+
+// NewPricingRequest describes lines for pricing, in the same order.
+func NewPricingRequest(userID int, region string, lines []Line) pricing.Request {
+    items := make([]pricing.Item, len(lines))
+    for i, l := range lines {
+        items[i] = pricing.Item{MovieID: l.Movie.MovieID, Format: string(l.Format), RentalDays: l.RentalDays}
+    }
+    return pricing.Request{UserID: userID, Region: region, Items: items}
 }
\ No newline at end of file
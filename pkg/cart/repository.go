@@ -1,13 +1,64 @@
 package cart
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"movie-rental/pkg/movies"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrMovieUnavailable is returned when a movie is outside its rental window.
+	ErrMovieUnavailable = errors.New("movie is not available for rent")
+	// ErrNotLicensed is returned when a movie may not be rented in the
+	// user's region.
+	ErrNotLicensed = errors.New("movie is not licensed in your region")
+	ErrNotInCart   = errors.New("movie is not in the cart")
+
+	ErrMovieNotFound = errors.New("movie not found")
+	ErrAlreadyInCart = errors.New("movie is already in the cart")
+	// ErrOptionNotOffered is returned when a movie cannot be rented in the
+	// requested format for the requested number of days.
+	ErrOptionNotOffered = errors.New("movie is not offered in that format and rental duration")
 )
 
 type Repository interface {
-	AddToCart(userID, movieID int) error
-	GetCartItems(userID string) ([]movies.Movie, error)
+	AddToCart(userID int, item Item, region string) error
+	// AddItemsToCart adds items to the user's cart in one transaction and
+	// returns an error for each item that cannot be added, in the same
+	// order, or nil for those that were. Items that cannot be added are
+	// skipped, unless allOrNothing, in which case none are added.
+	AddItemsToCart(ctx context.Context, userID int, items []Item, region string, allOrNothing bool) ([]error, error)
+	GetCartItems(userID string) ([]Line, error)
+	RemoveFromCart(userID, movieID int) error
+	// ClearCart empties the user's cart. An already empty cart is not an
+	// error.
+	ClearCart(userID int) error
+	// CheckItems returns whether each item can still be rented in region,
+	// in the same order. Rental windows are left to the caller, which has
+	// the movies.
+	CheckItems(ctx context.Context, items []Item, region string) ([]ItemCheck, error)
+	// SetGift makes the user's cart line for movieID a gift, or with a nil
+	// gift, the user's own rental again.
+	SetGift(ctx context.Context, userID, movieID int, gift *Gift) error
+	// ExpireItems removes the items that have outlived policy as of now and
+	// returns how many were removed.
+	ExpireItems(ctx context.Context, now time.Time, policy ExpiryPolicy) (int64, error)
+
+	// AddToGuestCart adds an item to the cart of a visitor who is not logged
+	// in, identified by the id in their guest token.
+	AddToGuestCart(guestID string, item Item, region string) error
+	GetGuestCartItems(guestID string) ([]Line, error)
+	// MergeGuestCart moves a guest's items into the user's cart, oldest
+	// first, and deletes the guest cart. Movies the user already has are
+	// skipped. Unless check is nil, it is given the guest's items in that
+	// order, and the items it returns an error for are dropped.
+	MergeGuestCart(ctx context.Context, guestID string, userID int, check func([]Item) ([]error, error)) (MergeResult, error)
 }
 
 type repository struct {
@@ -18,29 +69,479 @@ func NewRepository(db *sql.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) AddToCart(userID, movieID int) error {
-	_, err := r.db.Exec("INSERT INTO cart (user_id, movie_id) VALUES ($1, $2)", userID, movieID)
-	return err
+// AddToCart adds an item for a user in region, which may be "" when the
+// region is unknown.
+func (r *repository) AddToCart(userID int, item Item, region string) error {
+	if err := r.checkItem(item, region); err != nil {
+		return err
+	}
+
+	// The activity event feeds trending rankings; it is written in the same
+	// statement so that it exists if and only if the movie was added.
+	_, err := r.db.Exec(`
+		WITH added AS (
+			INSERT INTO cart (user_id, movie_id, format, rental_days, price) VALUES ($1, $2, $3, $4, `+ListPrice+`)
+			RETURNING user_id, movie_id
+		)
+		INSERT INTO activity_events (user_id, movie_id, kind)
+		SELECT user_id, movie_id, 'add_to_cart' FROM added`, userID, item.MovieID, item.Format, item.RentalDays)
+	return mapError(err)
+}
+
+// ListPrice selects the current list price of an item, or NULL, for
+// inserts whose $2, $3 and $4 are its movie, format and rental days.
+const ListPrice = "(SELECT amount FROM movie_prices p WHERE p.movie_id = $2 AND p.format = $3 AND p.rental_days = $4)"
+
+func (r *repository) checkItem(item Item, region string) error {
+	return CheckItem(context.Background(), r.db, item, region)
+}
+
+// Querier is satisfied by both *sql.DB and *sql.Tx.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CheckItem returns why item cannot be put in a cart in region, if it
+// cannot. It takes a Querier so that other packages moving items into
+// carts can check them inside their own transactions.
+func CheckItem(ctx context.Context, q Querier, item Item, region string) error {
+	var m movies.Movie
+	var licensed, offered bool
+	err := q.QueryRowContext(ctx,
+		"SELECT available_from, available_until, "+movies.LicensedClause("movies.movie_id", 2)+", "+
+			"EXISTS (SELECT 1 FROM movie_offers o WHERE o.movie_id = movies.movie_id AND o.format = $3 AND o.rental_days = $4)"+
+			" FROM movies WHERE movie_id = $1", item.MovieID, region, item.Format, item.RentalDays).
+		Scan(&m.AvailableFrom, &m.AvailableUntil, &licensed, &offered)
+	if err == sql.ErrNoRows {
+		return ErrMovieNotFound
+	} else if err != nil {
+		return err
+	}
+	return itemError(m, licensed, offered, time.Now())
+}
+
+// itemError returns why an item for m cannot be put in a cart as of now,
+// given whether m is licensed in the region and offered in the item's
+// format and rental duration.
+func itemError(m movies.Movie, licensed, offered bool, now time.Time) error {
+	if !licensed {
+		return ErrNotLicensed
+	}
+	if err := checkAvailable(m, now); err != nil {
+		return err
+	}
+	if !offered {
+		return ErrOptionNotOffered
+	}
+	return nil
+}
+
+func (r *repository) AddItemsToCart(ctx context.Context, userID int, items []Item, region string, allOrNothing bool) ([]error, error) {
+	errs := make([]error, len(items))
+	if len(items) == 0 {
+		return errs, nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, formats, days := itemArrays(items)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT i.n, m.movie_id IS NOT NULL, m.available_from, m.available_until, `+movies.LicensedClause("i.movie_id", 1)+`,
+			EXISTS (SELECT 1 FROM movie_offers o WHERE o.movie_id = i.movie_id AND o.format = i.format AND o.rental_days = i.rental_days)
+		FROM unnest($2::int[], $3::text[], $4::int[]) WITH ORDINALITY AS i(movie_id, format, rental_days, n)
+		LEFT JOIN movies m ON m.movie_id = i.movie_id`,
+		region, pq.Array(ids), pq.Array(formats), pq.Array(days))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for rows.Next() {
+		var n int
+		var found, licensed, offered bool
+		var m movies.Movie
+		if err := rows.Scan(&n, &found, &m.AvailableFrom, &m.AvailableUntil, &licensed, &offered); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if n < 1 || n > len(errs) {
+			rows.Close()
+			return nil, fmt.Errorf("cart: check of item %d out of range", n)
+		}
+		if !found {
+			errs[n-1] = ErrMovieNotFound
+		} else {
+			errs[n-1] = itemError(m, licensed, offered, now)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Each movie is added once; later copies are duplicates.
+	seen := make(map[int]bool, len(items))
+	var adding []Item
+	for i, item := range items {
+		if errs[i] != nil {
+			continue
+		}
+		if seen[item.MovieID] {
+			errs[i] = ErrAlreadyInCart
+			continue
+		}
+		seen[item.MovieID] = true
+		adding = append(adding, item)
+	}
+	if len(adding) == 0 || allOrNothing && len(adding) < len(items) {
+		return errs, nil
+	}
+
+	// Movies already in the cart are skipped by the insert and reported
+	// as duplicates below.
+	ids, formats, days = itemArrays(adding)
+	rows, err = tx.QueryContext(ctx, `
+		WITH added AS (
+			INSERT INTO cart (user_id, movie_id, format, rental_days, price)
+			SELECT $1, i.movie_id, i.format, i.rental_days,
+				(SELECT amount FROM movie_prices p WHERE p.movie_id = i.movie_id AND p.format = i.format AND p.rental_days = i.rental_days)
+			FROM unnest($2::int[], $3::text[], $4::int[]) AS i(movie_id, format, rental_days)
+			ON CONFLICT (user_id, movie_id) DO NOTHING
+			RETURNING user_id, movie_id
+		), events AS (
+			INSERT INTO activity_events (user_id, movie_id, kind)
+			SELECT user_id, movie_id, 'add_to_cart' FROM added
+		)
+		SELECT movie_id FROM added`, userID, pq.Array(ids), pq.Array(formats), pq.Array(days))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	added := make(map[int]bool, len(adding))
+	for rows.Next() {
+		var movieID int
+		if err := rows.Scan(&movieID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		added[movieID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, item := range items {
+		if errs[i] == nil && !added[item.MovieID] {
+			errs[i] = ErrAlreadyInCart
+		}
+	}
+	if allOrNothing && len(added) < len(adding) {
+		return errs, nil
+	}
+	return errs, tx.Commit()
 }
 
-func (r *repository) GetCartItems(userID string) ([]movies.Movie, error) {
-	rows, err := r.db.Query(`
-		SELECT m.movie_id, m.title, m.year, m.plot, m.genre, m.imdbid, m.actors
+// itemArrays splits items into arrays for unnest.
+func itemArrays(items []Item) (ids []int64, formats []string, days []int64) {
+	ids = make([]int64, len(items))
+	formats = make([]string, len(items))
+	days = make([]int64, len(items))
+	for i, item := range items {
+		ids[i], formats[i], days[i] = int64(item.MovieID), string(item.Format), int64(item.RentalDays)
+	}
+	return ids, formats, days
+}
+
+// GetCartItems returns the user's cart lines, oldest first.
+func (r *repository) GetCartItems(userID string) ([]Line, error) {
+	return r.queryLines(`
+		SELECT `+movies.SelectColumns("m")+`, c.format, c.rental_days, c.added_at, c.price,
+			c.gift_recipient_id, c.gift_recipient_email, c.gift_message
 		FROM cart c
 		JOIN movies m ON c.movie_id = m.movie_id
-		WHERE c.user_id = $1`, userID)
+		WHERE c.user_id = $1
+		ORDER BY c.added_at, m.movie_id`, userID)
+}
+
+func (r *repository) queryLines(query string, args ...interface{}) ([]Line, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var items []movies.Movie
+	var lines []Line
 	for rows.Next() {
-		var m movies.Movie
-		if err := rows.Scan(&m.MovieID, &m.Title, &m.Year, &m.Plot, &m.Genre, &m.ImdbID, &m.Actors); err != nil {
+		var l Line
+		var recipientID sql.NullInt64
+		var recipientEmail, message sql.NullString
+		if err := rows.Scan(append(movies.ScanFields(&l.Movie), &l.Format, &l.RentalDays, &l.AddedAt, &l.AddedPrice,
+			&recipientID, &recipientEmail, &message)...); err != nil {
 			return nil, err
 		}
-		items = append(items, m)
+		if recipientID.Valid || recipientEmail.Valid {
+			l.Gift = &Gift{RecipientID: int(recipientID.Int64), RecipientEmail: recipientEmail.String, Message: message.String}
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (r *repository) RemoveFromCart(userID, movieID int) error {
+	res, err := r.db.Exec("DELETE FROM cart WHERE user_id = $1 AND movie_id = $2", userID, movieID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotInCart
+	}
+	return nil
+}
+
+func (r *repository) ClearCart(userID int) error {
+	_, err := r.db.Exec("DELETE FROM cart WHERE user_id = $1", userID)
+	return err
+}
+
+func (r *repository) SetGift(ctx context.Context, userID, movieID int, gift *Gift) error {
+	var recipientID, recipientEmail, message interface{}
+	if gift != nil {
+		message = gift.Message
+		if gift.RecipientID != 0 {
+			recipientID = gift.RecipientID
+		} else {
+			recipientEmail = gift.RecipientEmail
+		}
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE cart SET gift_recipient_id = $3, gift_recipient_email = $4, gift_message = $5
+		WHERE user_id = $1 AND movie_id = $2`, userID, movieID, recipientID, recipientEmail, message)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotInCart
+	}
+	return nil
+}
+
+func (r *repository) CheckItems(ctx context.Context, items []Item, region string) ([]ItemCheck, error) {
+	checks := make([]ItemCheck, len(items))
+	if len(items) == 0 {
+		return checks, nil
+	}
+	ids, formats, days := itemArrays(items)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT i.n, `+movies.LicensedClause("i.movie_id", 1)+`,
+			EXISTS (SELECT 1 FROM movie_offers o WHERE o.movie_id = i.movie_id AND o.format = i.format AND o.rental_days = i.rental_days),
+			EXISTS (SELECT 1 FROM movie_prices p WHERE p.movie_id = i.movie_id AND p.format = i.format AND p.rental_days = i.rental_days)
+		FROM unnest($2::int[], $3::text[], $4::int[]) WITH ORDINALITY AS i(movie_id, format, rental_days, n)`,
+		region, pq.Array(ids), pq.Array(formats), pq.Array(days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n int
+		var c ItemCheck
+		if err := rows.Scan(&n, &c.Licensed, &c.Offered, &c.Priced); err != nil {
+			return nil, err
+		}
+		if n < 1 || n > len(checks) {
+			return nil, fmt.Errorf("cart: check of item %d out of range", n)
+		}
+		checks[n-1] = c
+	}
+	return checks, rows.Err()
+}
+
+// expiredClause matches the rows of alias that have outlived the policy
+// passed as $1 (now), $2 and $3 (per-format TTLs) and $4 (default TTL).
+func expiredClause(alias string) string {
+	return alias + `.added_at <= $1::timestamptz - make_interval(secs => COALESCE(
+		(SELECT t.secs FROM unnest($2::text[], $3::float8[]) AS t(format, secs) WHERE t.format = ` + alias + `.format),
+		$4))`
+}
+
+// ExpireItems records a cart_abandoned event for every user item it
+// removes, in the same statement, so that no item is purged without one.
+// Guest carts are expired by the same policy but, having no user to remind,
+// without events.
+func (r *repository) ExpireItems(ctx context.Context, now time.Time, policy ExpiryPolicy) (int64, error) {
+	var formats []string
+	var ttls []float64
+	for format, ttl := range policy.Formats {
+		formats = append(formats, string(format))
+		ttls = append(ttls, ttl.Seconds())
+	}
+	args := []interface{}{now, pq.Array(formats), pq.Array(ttls), policy.TTL.Seconds()}
+
+	res, err := r.db.ExecContext(ctx, `
+		WITH expired AS (
+			DELETE FROM cart c WHERE `+expiredClause("c")+`
+			RETURNING c.user_id, c.movie_id
+		)
+		INSERT INTO activity_events (user_id, movie_id, kind, occurred_at)
+		SELECT user_id, movie_id, 'cart_abandoned', $1 FROM expired`, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err = r.db.ExecContext(ctx, "DELETE FROM guest_carts g WHERE "+expiredClause("g"), args...)
+	if err != nil {
+		return n, err
+	}
+	guests, err := res.RowsAffected()
+	return n + guests, err
+}
+
+func (r *repository) AddToGuestCart(guestID string, item Item, region string) error {
+	if err := r.checkItem(item, region); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(
+		"INSERT INTO guest_carts (guest_id, movie_id, format, rental_days, price) VALUES ($1, $2, $3, $4, "+ListPrice+")",
+		guestID, item.MovieID, item.Format, item.RentalDays)
+	return mapError(err)
+}
+
+// GetGuestCartItems returns the guest's cart lines, oldest first.
+func (r *repository) GetGuestCartItems(guestID string) ([]Line, error) {
+	return r.queryLines(`
+		SELECT `+movies.SelectColumns("m")+`, g.format, g.rental_days, g.added_at, g.price,
+			NULL, NULL, NULL
+		FROM guest_carts g
+		JOIN movies m ON g.movie_id = m.movie_id
+		WHERE g.guest_id = $1
+		ORDER BY g.added_at, m.movie_id`, guestID)
+}
+
+func (r *repository) MergeGuestCart(ctx context.Context, guestID string, userID int, check func([]Item) ([]error, error)) (MergeResult, error) {
+	var result MergeResult
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	// Merges into the same cart take turns, so that each is checked against
+	// what the one before it added. Other changes to the cart are not held
+	// up, and can still push it past a limit, as they can each other;
+	// checkout checks the limits again.
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('cart_merge'), $1)", userID); err != nil {
+		return result, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM guest_carts WHERE guest_id = $1
+		RETURNING movie_id, format, rental_days, added_at, price`, guestID)
+	if err != nil {
+		return result, err
+	}
+	type guestItem struct {
+		Item
+		addedAt time.Time
+		price   *int64
+	}
+	var items []guestItem
+	for rows.Next() {
+		var g guestItem
+		if err := rows.Scan(&g.MovieID, &g.Format, &g.RentalDays, &g.addedAt, &g.price); err != nil {
+			rows.Close()
+			return result, err
+		}
+		items = append(items, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].addedAt.Equal(items[j].addedAt) {
+			return items[i].addedAt.Before(items[j].addedAt)
+		}
+		return items[i].MovieID < items[j].MovieID
+	})
+
+	errs := make([]error, len(items))
+	if check != nil && len(items) > 0 {
+		checked := make([]Item, len(items))
+		for i, g := range items {
+			checked[i] = g.Item
+		}
+		if errs, err = check(checked); err != nil {
+			return result, err
+		}
+	}
+
+	for i, g := range items {
+		if errs[i] != nil {
+			result.Dropped++
+			continue
+		}
+		res, err := tx.ExecContext(ctx, `
+			WITH added AS (
+				INSERT INTO cart (user_id, movie_id, format, rental_days, added_at, price) VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (user_id, movie_id) DO NOTHING
+				RETURNING user_id, movie_id
+			)
+			INSERT INTO activity_events (user_id, movie_id, kind)
+			SELECT user_id, movie_id, 'add_to_cart' FROM added`,
+			userID, g.MovieID, g.Format, g.RentalDays, g.addedAt, g.price)
+		if err != nil {
+			return result, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		if n == 0 {
+			result.Duplicates++
+			continue
+		}
+		result.Merged++
+	}
+
+	return result, tx.Commit()
+}
+
+// mapError turns constraint violations on cart into domain errors. The
+// movie can still vanish between the availability check and the insert.
+func mapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503":
+			return ErrMovieNotFound
+		case "23505":
+			return ErrAlreadyInCart
+		}
+	}
+	return err
+}
+
+func checkAvailable(m movies.Movie, now time.Time) error {
+	if m.AvailableAt(now) {
+		return nil
+	}
+	if m.AvailableFrom != nil && now.Before(*m.AvailableFrom) {
+		return fmt.Errorf("%w until %s", ErrMovieUnavailable, m.AvailableFrom.Format(time.RFC3339))
 	}
-	return items, nil
+	return fmt.Errorf("%w since %s", ErrMovieUnavailable, m.AvailableUntil.Format(time.RFC3339))
 }
\ No newline at end of file
@@ -1,24 +1,44 @@
 package cart
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
+func expectAvailability(mock sqlmock.Sqlmock, movieID int, from, until interface{}) {
+    expectMovieCheck(mock, movieID, "", true, from, until)
+}
+
+func expectOffer(mock sqlmock.Sqlmock, movieID int, format Format, rentalDays int, offered bool) {
+    mock.ExpectQuery(`SELECT available_from, available_until, (.+) EXISTS \(SELECT 1 FROM movie_offers o WHERE o.movie_id = movies.movie_id AND o.format = \$3 AND o.rental_days = \$4\) FROM movies WHERE movie_id = \$1`).
+        WithArgs(movieID, "", format, rentalDays).
+        WillReturnRows(sqlmock.NewRows([]string{"available_from", "available_until", "licensed", "offered"}).AddRow(nil, nil, true, offered))
+}
+
+func expectMovieCheck(mock sqlmock.Sqlmock, movieID int, region string, licensed bool, from, until interface{}) {
+    mock.ExpectQuery(`SELECT available_from, available_until, \(NOT EXISTS \(SELECT 1 FROM movie_territories (.+)\) FROM movies WHERE movie_id = \$1`).
+        WithArgs(movieID, region, FormatDigital, DefaultRentalDays).
+        WillReturnRows(sqlmock.NewRows([]string{"available_from", "available_until", "licensed", "offered"}).AddRow(from, until, licensed, true))
+}
+
 func TestAddToCart_Success(t *testing.T) {
     db, mock, err := sqlmock.New()
     assert.NoError(t, err)
     defer db.Close()
 
-    mock.ExpectExec("INSERT INTO cart").
-        WithArgs(1, 2).
+    expectAvailability(mock, 2, nil, nil)
+    mock.ExpectExec(`INSERT INTO cart (.+) INSERT INTO activity_events \(user_id, movie_id, kind\) SELECT user_id, movie_id, 'add_to_cart'`).
+        WithArgs(1, 2, FormatDigital, 2).
         WillReturnResult(sqlmock.NewResult(1, 1))
 
     repo := NewRepository(db)
-    err = repo.AddToCart(1, 2)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "")
     assert.NoError(t, err)
 }
 
@@ -27,34 +47,176 @@ func TestAddToCart_DBError(t *testing.T) {
     assert.NoError(t, err)
     defer db.Close()
 
+    expectAvailability(mock, 2, nil, nil)
     mock.ExpectExec("INSERT INTO cart").
-        WithArgs(1, 2).
+        WithArgs(1, 2, FormatDigital, 2).
         WillReturnError(errors.New("db error"))
 
     repo := NewRepository(db)
-    err = repo.AddToCart(1, 2)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "")
     assert.Error(t, err)
 }
 
+func TestAddToCart_NotYetAvailable(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectAvailability(mock, 2, time.Now().Add(24*time.Hour), nil)
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "")
+    assert.ErrorIs(t, err, ErrMovieUnavailable)
+    assert.Contains(t, err.Error(), "until")
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToCart_NoLongerAvailable(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectAvailability(mock, 2, nil, time.Now().Add(-24*time.Hour))
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "")
+    assert.ErrorIs(t, err, ErrMovieUnavailable)
+    assert.Contains(t, err.Error(), "since")
+}
+
+func TestAddToCart_LicensedInRegion(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectMovieCheck(mock, 2, "GB", true, nil, nil)
+    mock.ExpectExec("INSERT INTO cart").
+        WithArgs(1, 2, FormatDigital, 2).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "GB")
+    assert.NoError(t, err)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToCart_NotLicensed(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectMovieCheck(mock, 2, "FR", false, nil, nil)
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "FR")
+    assert.ErrorIs(t, err, ErrNotLicensed)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToCart_MovieNotFound(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT available_from, available_until`).
+        WithArgs(99, "", FormatDigital, 2).
+        WillReturnRows(sqlmock.NewRows([]string{"available_from", "available_until", "licensed", "offered"}))
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(99, "", 0), "")
+    assert.ErrorIs(t, err, ErrMovieNotFound)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToCart_ConstraintViolations(t *testing.T) {
+    for code, want := range map[pq.ErrorCode]error{"23503": ErrMovieNotFound, "23505": ErrAlreadyInCart} {
+        db, mock, err := sqlmock.New()
+        assert.NoError(t, err)
+
+        expectAvailability(mock, 2, nil, nil)
+        mock.ExpectExec("INSERT INTO cart").
+            WithArgs(1, 2, FormatDigital, 2).
+            WillReturnError(&pq.Error{Code: code, Message: "violates constraint"})
+
+        repo := NewRepository(db)
+        err = repo.AddToCart(1, NewItem(2, "", 0), "")
+        assert.ErrorIs(t, err, want)
+        assert.NotContains(t, err.Error(), "violates")
+        db.Close()
+    }
+}
+
+func TestAddToCart_Options(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectOffer(mock, 2, FormatBluRay, 7, true)
+    mock.ExpectExec("INSERT INTO cart").
+        WithArgs(1, 2, FormatBluRay, 7).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, FormatBluRay, 7), "")
+    assert.NoError(t, err)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToCart_OptionNotOffered(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectOffer(mock, 2, FormatDVD, 30, false)
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, FormatDVD, 30), "")
+    assert.ErrorIs(t, err, ErrOptionNotOffered)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToCart_AvailabilityCheckError(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT available_from, available_until FROM movies`).
+        WillReturnError(errors.New("db error"))
+
+    repo := NewRepository(db)
+    err = repo.AddToCart(1, NewItem(2, "", 0), "")
+    assert.Error(t, err)
+    assert.NotErrorIs(t, err, ErrMovieUnavailable)
+}
+
 func TestGetCartItems_Success(t *testing.T) {
     db, mock, err := sqlmock.New()
     assert.NoError(t, err)
     defer db.Close()
 
-    rows := sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors"}).
-        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A, Actor B").
-        AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor C, Actor D")
+    added := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+    rows := sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until", "format", "rental_days", "added_at", "price", "gift_recipient_id", "gift_recipient_email", "gift_message"}).
+        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A, Actor B", nil, nil, "digital", 2, added, 399, nil, nil, nil).
+        AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor C, Actor D", nil, nil, "bluray", 7, added, nil, 5, nil, "Enjoy")
 
-    mock.ExpectQuery(`SELECT m.movie_id, m.title, m.year, m.plot, m.genre, m.imdbid, m.actors FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1`).
+    mock.ExpectQuery(`SELECT m.movie_id, (.+), m.available_until, c.format, c.rental_days, c.added_at, c.price, c.gift_recipient_id, c.gift_recipient_email, c.gift_message FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1 ORDER BY c.added_at`).
         WithArgs("1").
         WillReturnRows(rows)
 
     repo := NewRepository(db)
-    moviesList, err := repo.GetCartItems("1")
+    lines, err := repo.GetCartItems("1")
     assert.NoError(t, err)
-    assert.Len(t, moviesList, 2)
-    assert.Equal(t, "Movie 1", moviesList[0].Title)
-    assert.Equal(t, "Movie 2", moviesList[1].Title)
+    assert.Len(t, lines, 2)
+    assert.Equal(t, "Movie 1", lines[0].Movie.Title)
+    assert.Equal(t, FormatDigital, lines[0].Format)
+    assert.Equal(t, added, lines[0].AddedAt)
+    assert.Equal(t, int64(399), *lines[0].AddedPrice)
+    assert.Nil(t, lines[1].AddedPrice)
+    assert.Nil(t, lines[0].Gift)
+    assert.Equal(t, &Gift{RecipientID: 5, Message: "Enjoy"}, lines[1].Gift)
+    assert.Equal(t, "Movie 2", lines[1].Movie.Title)
+    assert.Equal(t, FormatBluRay, lines[1].Format)
+    assert.Equal(t, 7, lines[1].RentalDays)
 }
 
 func TestGetCartItems_DBError(t *testing.T) {
@@ -62,7 +224,7 @@ func TestGetCartItems_DBError(t *testing.T) {
     assert.NoError(t, err)
     defer db.Close()
 
-    mock.ExpectQuery(`SELECT m.movie_id, m.title, m.year, m.plot, m.genre, m.imdbid, m.actors FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1`).
+    mock.ExpectQuery(`SELECT m.movie_id, (.+), m.available_until, c.format, c.rental_days, c.added_at, c.price, c.gift_recipient_id, c.gift_recipient_email, c.gift_message FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1 ORDER BY c.added_at`).
         WithArgs("1").
         WillReturnError(errors.New("db error"))
 
@@ -77,9 +239,9 @@ func TestGetCartItems_RowScanError(t *testing.T) {
     assert.NoError(t, err)
     defer db.Close()
 
-    rows := sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors"}).
-        AddRow("not-an-int", "Title", 2020, "Plot", "Genre", "imdbid", "Actors")
-    mock.ExpectQuery(`SELECT m.movie_id, m.title, m.year, m.plot, m.genre, m.imdbid, m.actors FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1`).
+    rows := sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until", "format", "rental_days", "added_at", "price", "gift_recipient_id", "gift_recipient_email", "gift_message"}).
+        AddRow("not-an-int", "Title", 2020, "Plot", "Genre", "imdbid", "Actors", nil, nil, "digital", 2, time.Now(), nil, nil, nil, nil)
+    mock.ExpectQuery(`SELECT m.movie_id, (.+), m.available_until, c.format, c.rental_days, c.added_at, c.price, c.gift_recipient_id, c.gift_recipient_email, c.gift_message FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1 ORDER BY c.added_at`).
         WithArgs("1").
         WillReturnRows(rows)
 
@@ -87,4 +249,277 @@ func TestGetCartItems_RowScanError(t *testing.T) {
     moviesList, err := repo.GetCartItems("1")
     assert.Error(t, err)
     assert.Nil(t, moviesList)
-}
\ No newline at end of file
+}
+
+func TestRemoveFromCart_Success(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`DELETE FROM cart WHERE user_id = \$1 AND movie_id = \$2`).
+        WithArgs(1, 2).
+        WillReturnResult(sqlmock.NewResult(0, 1))
+
+    repo := NewRepository(db)
+    err = repo.RemoveFromCart(1, 2)
+    assert.NoError(t, err)
+}
+
+func TestRemoveFromCart_NotInCart(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`DELETE FROM cart WHERE user_id = \$1 AND movie_id = \$2`).
+        WithArgs(1, 99).
+        WillReturnResult(sqlmock.NewResult(0, 0))
+
+    repo := NewRepository(db)
+    err = repo.RemoveFromCart(1, 99)
+    assert.ErrorIs(t, err, ErrNotInCart)
+}
+
+func TestClearCart(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`DELETE FROM cart WHERE user_id = \$1`).
+        WithArgs(1).
+        WillReturnResult(sqlmock.NewResult(0, 0))
+
+    repo := NewRepository(db)
+    err = repo.ClearCart(1)
+    assert.NoError(t, err)
+}
+
+func TestExpireItems(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+    mock.ExpectExec(`WITH expired AS \( DELETE FROM cart c WHERE c.added_at <= (.+) RETURNING c.user_id, c.movie_id \) INSERT INTO activity_events \(user_id, movie_id, kind, occurred_at\) SELECT user_id, movie_id, 'cart_abandoned', \$1 FROM expired`).
+        WithArgs(now, "{\"dvd\"}", "{86400}", 259200.0).
+        WillReturnResult(sqlmock.NewResult(0, 4))
+    mock.ExpectExec(`DELETE FROM guest_carts g WHERE g.added_at <= (.+)`).
+        WithArgs(now, "{\"dvd\"}", "{86400}", 259200.0).
+        WillReturnResult(sqlmock.NewResult(0, 2))
+
+    repo := NewRepository(db)
+    n, err := repo.ExpireItems(context.Background(), now, ExpiryPolicy{
+        TTL:     72 * time.Hour,
+        Formats: map[Format]time.Duration{FormatDVD: 24 * time.Hour},
+    })
+    assert.NoError(t, err)
+    assert.Equal(t, int64(6), n)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddToGuestCart(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    expectAvailability(mock, 2, nil, nil)
+    mock.ExpectExec(`INSERT INTO guest_carts \(guest_id, movie_id, format, rental_days, price\) VALUES \(\$1, \$2, \$3, \$4, \(SELECT amount FROM movie_prices p WHERE p.movie_id = \$2 AND p.format = \$3 AND p.rental_days = \$4\)\)`).
+        WithArgs("g1", 2, FormatDigital, 2).
+        WillReturnError(&pq.Error{Code: "23505"})
+
+    repo := NewRepository(db)
+    err = repo.AddToGuestCart("g1", NewItem(2, "", 0), "")
+    assert.ErrorIs(t, err, ErrAlreadyInCart)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetGuestCartItems(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT m.movie_id, (.+), g.format, g.rental_days, g.added_at, g.price, NULL, NULL, NULL FROM guest_carts g JOIN movies m ON g.movie_id = m.movie_id WHERE g.guest_id = \$1`).
+        WithArgs("g1").
+        WillReturnRows(sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until", "format", "rental_days", "added_at", "price", "gift_recipient_id", "gift_recipient_email", "gift_message"}).
+            AddRow(2, "Movie 2", 2021, "", "", "", "", nil, nil, "dvd", 7, time.Now(), 599, nil, nil, nil))
+
+    repo := NewRepository(db)
+    lines, err := repo.GetGuestCartItems("g1")
+    assert.NoError(t, err)
+    assert.Len(t, lines, 1)
+    assert.Equal(t, FormatDVD, lines[0].Format)
+}
+
+func TestMergeGuestCart(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    older := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+    newer := older.Add(time.Hour)
+    mock.ExpectBegin()
+    mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\('cart_merge'\), \$1\)`).
+        WithArgs(1).
+        WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectQuery(`DELETE FROM guest_carts WHERE guest_id = \$1 RETURNING movie_id, format, rental_days, added_at, price`).
+        WithArgs("g1").
+        WillReturnRows(sqlmock.NewRows([]string{"movie_id", "format", "rental_days", "added_at", "price"}).
+            AddRow(4, "digital", 2, newer, 399).
+            AddRow(3, "dvd", 7, newer, nil).
+            AddRow(2, "digital", 2, older, 399).
+            AddRow(5, "digital", 7, newer, 599))
+    mock.ExpectExec(`INSERT INTO cart \(user_id, movie_id, format, rental_days, added_at, price\) (.+) ON CONFLICT \(user_id, movie_id\) DO NOTHING (.+) 'add_to_cart'`).
+        WithArgs(1, 2, FormatDigital, 2, older, int64(399)).
+        WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(`INSERT INTO cart`).
+        WithArgs(1, 3, FormatDVD, 7, newer, nil).
+        WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(`INSERT INTO cart`).
+        WithArgs(1, 4, FormatDigital, 2, newer, int64(399)).
+        WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectCommit()
+
+    // The cart holds one item and may hold three.
+    check := func(items []Item) ([]error, error) {
+        assert.Equal(t, []int{2, 3, 4, 5}, []int{items[0].MovieID, items[1].MovieID, items[2].MovieID, items[3].MovieID})
+        return []error{nil, nil, nil, errors.New("cart is full")}, nil
+    }
+    repo := NewRepository(db)
+    result, err := repo.MergeGuestCart(context.Background(), "g1", 1, check)
+    assert.NoError(t, err)
+    assert.Equal(t, MergeResult{Merged: 2, Duplicates: 1, Dropped: 1}, result)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeGuestCart_RollsBackOnError(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectBegin()
+    mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectQuery(`DELETE FROM guest_carts`).
+        WillReturnRows(sqlmock.NewRows([]string{"movie_id", "format", "rental_days", "added_at", "price"}).AddRow(2, "digital", 2, time.Now(), nil))
+    mock.ExpectExec(`INSERT INTO cart`).WillReturnError(errors.New("db error"))
+    mock.ExpectRollback()
+
+    repo := NewRepository(db)
+    _, err = repo.MergeGuestCart(context.Background(), "g1", 1, nil)
+    assert.Error(t, err)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckItems(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT i.n, \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = i.movie_id\)(.+) FROM unnest\(\$2::int\[\], \$3::text\[\], \$4::int\[\]\) WITH ORDINALITY AS i\(movie_id, format, rental_days, n\)`).
+        WithArgs("US", pq.Array([]int64{2, 3}), pq.Array([]string{"digital", "dvd"}), pq.Array([]int64{2, 7})).
+        WillReturnRows(sqlmock.NewRows([]string{"n", "licensed", "offered", "priced"}).
+            AddRow(2, true, false, false).
+            AddRow(1, true, true, true))
+
+    repo := NewRepository(db)
+    checks, err := repo.CheckItems(context.Background(), []Item{NewItem(2, "", 0), NewItem(3, FormatDVD, 7)}, "US")
+    assert.NoError(t, err)
+    assert.Equal(t, []ItemCheck{{Licensed: true, Offered: true, Priced: true}, {Licensed: true}}, checks)
+
+    checks, err = repo.CheckItems(context.Background(), nil, "US")
+    assert.NoError(t, err)
+    assert.Empty(t, checks)
+}
+func expectBatchCheck(mock sqlmock.Sqlmock, ids []int64, rows *sqlmock.Rows) {
+    formats := make([]string, len(ids))
+    days := make([]int64, len(ids))
+    for i := range ids {
+        formats[i], days[i] = "digital", 2
+    }
+    mock.ExpectQuery(`SELECT i.n, m.movie_id IS NOT NULL, m.available_from, m.available_until, (.+) FROM unnest\(\$2::int\[\], \$3::text\[\], \$4::int\[\]\) WITH ORDINALITY AS i\(movie_id, format, rental_days, n\) LEFT JOIN movies m ON m.movie_id = i.movie_id`).
+        WithArgs("", pq.Array(ids), pq.Array(formats), pq.Array(days)).
+        WillReturnRows(rows)
+}
+
+func batchCheckRows() *sqlmock.Rows {
+    return sqlmock.NewRows([]string{"n", "found", "available_from", "available_until", "licensed", "offered"})
+}
+
+func TestAddItemsToCart(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectBegin()
+    expectBatchCheck(mock, []int64{2, 3, 99, 2, 4}, batchCheckRows().
+        AddRow(1, true, nil, nil, true, true).
+        AddRow(2, true, nil, nil, true, true).
+        AddRow(3, false, nil, nil, false, false).
+        AddRow(4, true, nil, nil, true, true).
+        AddRow(5, true, nil, nil, false, true))
+    mock.ExpectQuery(`WITH added AS \( INSERT INTO cart \(user_id, movie_id, format, rental_days, price\) SELECT \$1, (.+) FROM unnest(.+) ON CONFLICT \(user_id, movie_id\) DO NOTHING RETURNING user_id, movie_id \), events AS \( INSERT INTO activity_events (.+) SELECT movie_id FROM added`).
+        WithArgs(1, pq.Array([]int64{2, 3}), pq.Array([]string{"digital", "digital"}), pq.Array([]int64{2, 2})).
+        WillReturnRows(sqlmock.NewRows([]string{"movie_id"}).AddRow(2))
+    mock.ExpectCommit()
+
+    repo := NewRepository(db)
+    items := []Item{NewItem(2, "", 0), NewItem(3, "", 0), NewItem(99, "", 0), NewItem(2, "", 0), NewItem(4, "", 0)}
+    errs, err := repo.AddItemsToCart(context.Background(), 1, items, "", false)
+    assert.NoError(t, err)
+    assert.Equal(t, []error{nil, ErrAlreadyInCart, ErrMovieNotFound, ErrAlreadyInCart, ErrNotLicensed}, errs)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddItemsToCart_AllOrNothing(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    // An invalid item stops the batch before anything is inserted.
+    mock.ExpectBegin()
+    expectBatchCheck(mock, []int64{2, 3}, batchCheckRows().
+        AddRow(1, true, nil, nil, true, true).
+        AddRow(2, true, nil, time.Now().Add(-time.Hour), true, true))
+    mock.ExpectRollback()
+
+    repo := NewRepository(db)
+    errs, err := repo.AddItemsToCart(context.Background(), 1, []Item{NewItem(2, "", 0), NewItem(3, "", 0)}, "", true)
+    assert.NoError(t, err)
+    assert.Nil(t, errs[0])
+    assert.ErrorIs(t, errs[1], ErrMovieUnavailable)
+
+    // So does a movie that is already in the cart.
+    mock.ExpectBegin()
+    expectBatchCheck(mock, []int64{2, 3}, batchCheckRows().
+        AddRow(1, true, nil, nil, true, true).
+        AddRow(2, true, nil, nil, true, true))
+    mock.ExpectQuery(`WITH added AS`).
+        WillReturnRows(sqlmock.NewRows([]string{"movie_id"}).AddRow(3))
+    mock.ExpectRollback()
+
+    errs, err = repo.AddItemsToCart(context.Background(), 1, []Item{NewItem(2, "", 0), NewItem(3, "", 0)}, "", true)
+    assert.NoError(t, err)
+    assert.Equal(t, []error{ErrAlreadyInCart, nil}, errs)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetGift(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`UPDATE cart SET gift_recipient_id = \$3, gift_recipient_email = \$4, gift_message = \$5 WHERE user_id = \$1 AND movie_id = \$2`).
+        WithArgs(1, 2, nil, "friend@example.com", "Happy birthday").
+        WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(`UPDATE cart SET gift_recipient_id`).
+        WithArgs(1, 2, nil, nil, nil).
+        WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(`UPDATE cart SET gift_recipient_id`).
+        WithArgs(1, 3, 5, nil, "").
+        WillReturnResult(sqlmock.NewResult(0, 0))
+
+    repo := NewRepository(db)
+    ctx := context.Background()
+    assert.NoError(t, repo.SetGift(ctx, 1, 2, &Gift{RecipientEmail: "friend@example.com", Message: "Happy birthday"}))
+    assert.NoError(t, repo.SetGift(ctx, 1, 2, nil))
+    assert.ErrorIs(t, repo.SetGift(ctx, 1, 3, &Gift{RecipientID: 5}), ErrNotInCart)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,141 @@
+package cart
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"movie-rental/pkg/pricing"
+)
+
+// Warning codes of cart lines that are not also errors of adding to a
+// cart. A line that was added fine can since have become unavailable
+// (CodeMovieUnavailable), unlicensed (CodeNotLicensed), withdrawn
+// (CodeOptionNotOffered) or unpriced (CodePriceUnavailable) too.
+const (
+	WarningRetired      = "movie_retired"
+	WarningPriceChanged = "price_changed"
+)
+
+// Warning tells why a cart line may not be what the user expects. Blocking
+// warnings keep the cart from being checked out.
+type Warning struct {
+	XMLName  xml.Name `json:"-" xml:"warning"`
+	Code     string   `json:"code" xml:"code"`
+	Message  string   `json:"message" xml:"message"`
+	Blocking bool     `json:"blocking" xml:"blocking"`
+}
+
+// ItemCheck is what the database says about whether an item can still be
+// rented in a region.
+type ItemCheck struct {
+	Licensed bool
+	Offered  bool
+	Priced   bool
+}
+
+// ReviewedLine is a cart line revalidated as of now.
+type ReviewedLine struct {
+	Line
+	// Price is nil when the line cannot be rented.
+	Price    *int64
+	Warnings []Warning
+}
+
+// Rentable reports whether nothing keeps the line from being checked out.
+func (l ReviewedLine) Rentable() bool {
+	for _, w := range l.Warnings {
+		if w.Blocking {
+			return false
+		}
+	}
+	return true
+}
+
+// Review is a cart as it stands now. Quote prices its rentable lines only.
+type Review struct {
+	Lines []ReviewedLine
+	Quote pricing.Quote
+}
+
+// CheckoutReady reports whether the cart has lines and all of them can be
+// rented.
+func (r Review) CheckoutReady() bool {
+	for _, l := range r.Lines {
+		if !l.Rentable() {
+			return false
+		}
+	}
+	return len(r.Lines) > 0
+}
+
+// ReviewCart revalidates lines against their movies' rental windows,
+// licensing in region, the offers and the price table as of now, and prices
+// the lines that can still be rented for userID, or for a guest when
+// userID is 0.
+func ReviewCart(ctx context.Context, repo Repository, pricer Pricer, userID int, region string, lines []Line, now time.Time) (Review, error) {
+	items := make([]Item, len(lines))
+	for i, l := range lines {
+		items[i] = Item{MovieID: l.Movie.MovieID, Format: l.Format, RentalDays: l.RentalDays}
+	}
+	checks, err := repo.CheckItems(ctx, items, region)
+	if err != nil {
+		return Review{}, err
+	}
+
+	review := Review{Lines: make([]ReviewedLine, len(lines))}
+	var rentable []Line
+	for i, l := range lines {
+		review.Lines[i] = ReviewedLine{Line: l, Warnings: lineWarnings(l, checks[i], now)}
+		if review.Lines[i].Rentable() {
+			rentable = append(rentable, l)
+		}
+	}
+
+	review.Quote, err = pricer.Price(ctx, NewPricingRequest(userID, region, rentable))
+	if err != nil {
+		return Review{}, err
+	}
+	priced := review.Quote.Lines
+	for i := range review.Lines {
+		l := &review.Lines[i]
+		if !l.Rentable() {
+			continue
+		}
+		amount := priced[0].Amount
+		priced = priced[1:]
+		l.Price = &amount
+		if l.AddedPrice != nil && *l.AddedPrice != amount {
+			l.Warnings = append(l.Warnings, Warning{
+				Code:    WarningPriceChanged,
+				Message: fmt.Sprintf("price changed from %d to %d since the movie was added", *l.AddedPrice, amount),
+			})
+		}
+	}
+	return review, nil
+}
+
+// lineWarnings returns the blocking warnings of l.
+func lineWarnings(l Line, check ItemCheck, now time.Time) []Warning {
+	warnings := []Warning{}
+	block := func(code, message string) {
+		warnings = append(warnings, Warning{Code: code, Message: message, Blocking: true})
+	}
+
+	m := l.Movie
+	if m.AvailableUntil != nil && !now.Before(*m.AvailableUntil) {
+		block(WarningRetired, "movie was retired on "+m.AvailableUntil.Format(time.RFC3339))
+	} else if err := checkAvailable(m, now); err != nil {
+		block(CodeMovieUnavailable, err.Error())
+	}
+	if !check.Licensed {
+		block(CodeNotLicensed, ErrNotLicensed.Error())
+	}
+	if !check.Offered {
+		block(CodeOptionNotOffered, ErrOptionNotOffered.Error())
+	} else if !check.Priced {
+		block(CodePriceUnavailable, pricing.ErrNoPrice.Error())
+	}
+	return warnings
+}
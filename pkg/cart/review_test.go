@@ -0,0 +1,57 @@
+package cart
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/pricing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewCart(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tomorrow := now.AddDate(0, 0, 1)
+	was, same := int64(299), int64(399)
+	lines := []Line{
+		{Movie: movies.Movie{MovieID: 1}, Format: FormatDigital, RentalDays: 2, AddedPrice: &was},
+		{Movie: movies.Movie{MovieID: 2}, Format: FormatDigital, RentalDays: 2, AddedPrice: &same},
+		{Movie: movies.Movie{MovieID: 3, AvailableFrom: &tomorrow}, Format: FormatDigital, RentalDays: 2},
+		{Movie: movies.Movie{MovieID: 4}, Format: FormatDVD, RentalDays: 7},
+		{Movie: movies.Movie{MovieID: 5}, Format: FormatDigital, RentalDays: 2},
+	}
+	repo := &mockRepository{
+		CheckItemsFunc: func(items []Item, region string) ([]ItemCheck, error) {
+			assert.Equal(t, "US", region)
+			assert.Equal(t, Item{MovieID: 4, Format: FormatDVD, RentalDays: 7}, items[3])
+			ok := ItemCheck{Licensed: true, Offered: true, Priced: true}
+			return []ItemCheck{ok, ok, ok, {Licensed: true}, {Offered: true, Priced: true}}, nil
+		},
+	}
+
+	review, err := ReviewCart(context.Background(), repo, pricing.NewPricer(mockPriceRepository{}), 1, "US", lines, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(798), review.Quote.Subtotal)
+	assert.False(t, review.CheckoutReady())
+
+	l := review.Lines
+	assert.True(t, l[0].Rentable())
+	assert.Equal(t, []Warning{{Code: WarningPriceChanged, Message: "price changed from 299 to 399 since the movie was added"}}, l[0].Warnings)
+	assert.Equal(t, []Warning{}, l[1].Warnings)
+	assert.Equal(t, int64(399), *l[1].Price)
+	assert.Equal(t, CodeMovieUnavailable, l[2].Warnings[0].Code)
+	assert.Equal(t, CodeOptionNotOffered, l[3].Warnings[0].Code)
+	assert.Equal(t, CodeNotLicensed, l[4].Warnings[0].Code)
+	for _, blocked := range l[2:] {
+		assert.False(t, blocked.Rentable())
+		assert.Nil(t, blocked.Price)
+	}
+}
+
+func TestReview_CheckoutReady(t *testing.T) {
+	assert.False(t, Review{}.CheckoutReady())
+	assert.True(t, Review{Lines: []ReviewedLine{{Warnings: []Warning{{Code: WarningPriceChanged}}}}}.CheckoutReady())
+}
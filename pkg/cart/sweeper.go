@@ -0,0 +1,67 @@
+package cart
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ExpiryPolicy decides how long an item may sit in a cart.
+type ExpiryPolicy struct {
+	// TTL is how long items are kept after they were added.
+	TTL time.Duration
+	// Formats overrides TTL for items rented in a given format.
+	Formats map[Format]time.Duration
+}
+
+// DefaultExpiryPolicy keeps items for three days.
+var DefaultExpiryPolicy = ExpiryPolicy{TTL: 72 * time.Hour}
+
+// TTLFor returns how long an item rented in format is kept.
+func (p ExpiryPolicy) TTLFor(format Format) time.Duration {
+	if ttl, ok := p.Formats[format]; ok {
+		return ttl
+	}
+	return p.TTL
+}
+
+// Sweeper periodically removes expired items from carts. Each removed item
+// is recorded as a cart_abandoned activity event, which reminder emails are
+// sent from.
+type Sweeper struct {
+	repo     Repository
+	policy   ExpiryPolicy
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewSweeper(repo Repository, policy ExpiryPolicy, interval time.Duration) *Sweeper {
+	return &Sweeper{repo: repo, policy: policy, interval: interval, now: time.Now}
+}
+
+// Run sweeps immediately and then once per interval until ctx is
+// cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sweeper) Sweep(ctx context.Context) {
+	n, err := s.repo.ExpireItems(ctx, s.now(), s.policy)
+	if err != nil {
+		log.Printf("cart: expire items: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("cart: expired %d items", n)
+	}
+}
@@ -0,0 +1,64 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryPolicy_TTLFor(t *testing.T) {
+	policy := ExpiryPolicy{TTL: 72 * time.Hour, Formats: map[Format]time.Duration{FormatDVD: 24 * time.Hour}}
+
+	assert.Equal(t, 24*time.Hour, policy.TTLFor(FormatDVD))
+	assert.Equal(t, 72*time.Hour, policy.TTLFor(FormatDigital))
+	assert.Equal(t, 72*time.Hour, DefaultExpiryPolicy.TTLFor(FormatBluRay))
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	policy := ExpiryPolicy{TTL: time.Hour}
+	calls := 0
+	repo := &mockRepository{
+		ExpireItemsFunc: func(at time.Time, p ExpiryPolicy) (int64, error) {
+			calls++
+			assert.Equal(t, now, at)
+			assert.Equal(t, policy, p)
+			if calls > 1 {
+				return 0, errors.New("db error")
+			}
+			return 3, nil
+		},
+	}
+	s := NewSweeper(repo, policy, time.Minute)
+	s.now = func() time.Time { return now }
+
+	s.Sweep(context.Background())
+	s.Sweep(context.Background())
+	assert.Equal(t, 2, calls)
+}
+
+func TestSweeper_RunStopsWithContext(t *testing.T) {
+	swept := make(chan struct{}, 1)
+	repo := &mockRepository{
+		ExpireItemsFunc: func(time.Time, ExpiryPolicy) (int64, error) {
+			select {
+			case swept <- struct{}{}:
+			default:
+			}
+			return 0, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewSweeper(repo, DefaultExpiryPolicy, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	<-swept
+	cancel()
+	<-done
+}
@@ -0,0 +1,126 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Invalidator drops cached copies of a movie, such as
+// movies.CachedMovieRepository.
+type Invalidator interface {
+	Invalidate(id string)
+}
+
+func ScanDuplicatesHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		minScore, ok := minScoreParam(c)
+		if !ok {
+			return
+		}
+
+		duplicates, err := ScanDuplicates(c.Request.Context(), repo, minScore, time.Now())
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusOK, ScanResult{Duplicates: len(duplicates)})
+	}
+}
+
+func ListDuplicatesHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		minScore, ok := minScoreParam(c)
+		if !ok {
+			return
+		}
+
+		duplicates, err := repo.ListDuplicates(c.Request.Context(), minScore)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if duplicates == nil {
+			duplicates = []Duplicate{}
+		}
+
+		api.List(c, duplicates, len(duplicates))
+	}
+}
+
+// MergeHandler folds one movie into another and evicts both from cache.
+func MergeHandler(repo Repository, cache Invalidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MergeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "survivor_id and duplicate_id must be different positive integers")
+			return
+		}
+
+		err := repo.Merge(c.Request.Context(), req.SurvivorID, req.DuplicateID)
+		switch {
+		case errors.Is(err, ErrMovieNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+			return
+		case err != nil:
+			api.InternalError(c, err)
+			return
+		}
+		cache.Invalidate(strconv.Itoa(req.SurvivorID))
+		cache.Invalidate(strconv.Itoa(req.DuplicateID))
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func minScoreParam(c *gin.Context) (float64, bool) {
+	raw := c.Query("min_score")
+	if raw == "" {
+		return DefaultMinScore, true
+	}
+	minScore, err := strconv.ParseFloat(raw, 64)
+	if err != nil || minScore < 0 || minScore > 1 {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "min_score must be between 0 and 1")
+		return 0, false
+	}
+	return minScore, true
+}
+
+// QualityReportHandler checks the catalog against rules, or against those
+// named by the comma-separated rule query parameter. CSV clients receive one
+// line per issue.
+func QualityReportHandler(repo Repository, rules []Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		selected, err := SelectRules(rules, splitList(c.Query("rule")))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		catalog, err := repo.ListMovieDetails(c.Request.Context())
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		report := RunRules(catalog, selected)
+		api.OKList(c, report, report.Issues)
+	}
+}
+
+func splitList(raw string) []string {
+	var list []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
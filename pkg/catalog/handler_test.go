@@ -0,0 +1,206 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/movies"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	ListCandidatesFunc    func() ([]Candidate, error)
+	ReplaceDuplicatesFunc func(duplicates []Duplicate, detectedAt time.Time) error
+	ListDuplicatesFunc    func(minScore float64) ([]Duplicate, error)
+	MergeFunc             func(survivorID, duplicateID int) error
+	ListMovieDetailsFunc  func() ([]movies.Movie, error)
+}
+
+func (m *mockRepository) ListCandidates(_ context.Context) ([]Candidate, error) {
+	return m.ListCandidatesFunc()
+}
+func (m *mockRepository) ReplaceDuplicates(_ context.Context, duplicates []Duplicate, detectedAt time.Time) error {
+	return m.ReplaceDuplicatesFunc(duplicates, detectedAt)
+}
+func (m *mockRepository) ListDuplicates(_ context.Context, minScore float64) ([]Duplicate, error) {
+	return m.ListDuplicatesFunc(minScore)
+}
+func (m *mockRepository) Merge(_ context.Context, survivorID, duplicateID int) error {
+	return m.MergeFunc(survivorID, duplicateID)
+}
+func (m *mockRepository) ListMovieDetails(_ context.Context) ([]movies.Movie, error) {
+	return m.ListMovieDetailsFunc()
+}
+
+type invalidated []string
+
+func (i *invalidated) Invalidate(id string) { *i = append(*i, id) }
+
+func setupRouter(repo Repository, cache Invalidator) *gin.Engine {
+	router := gin.Default()
+	router.POST("/v2/admin/catalog/duplicates/scan", ScanDuplicatesHandler(repo))
+	router.GET("/v2/admin/catalog/duplicates", ListDuplicatesHandler(repo))
+	router.POST("/v2/admin/catalog/merge", MergeHandler(repo, cache))
+	router.GET("/v2/admin/catalog/quality", QualityReportHandler(repo, DefaultRules(time.Now)))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestScanDuplicatesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var stored []Duplicate
+	repo := &mockRepository{
+		ListCandidatesFunc: func() ([]Candidate, error) {
+			return []Candidate{
+				{MovieID: 1, Title: "Heat", Year: 1995},
+				{MovieID: 2, Title: "Heat", Year: 1995},
+				{MovieID: 3, Title: "Heat", Year: 2013},
+			}, nil
+		},
+		ReplaceDuplicatesFunc: func(duplicates []Duplicate, detectedAt time.Time) error {
+			stored = duplicates
+			return nil
+		},
+	}
+
+	recorder := serve(setupRouter(repo, &invalidated{}), "POST", "/v2/admin/catalog/duplicates/scan", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"duplicates":1},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+	assert.Len(t, stored, 1)
+	assert.Equal(t, 2, stored[0].DuplicateID)
+}
+
+func TestScanDuplicatesHandler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListCandidatesFunc: func() ([]Candidate, error) {
+			return nil, errors.New("db error")
+		},
+	}
+
+	recorder := serve(setupRouter(repo, &invalidated{}), "POST", "/v2/admin/catalog/duplicates/scan", "")
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestListDuplicatesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListDuplicatesFunc: func(minScore float64) ([]Duplicate, error) {
+			assert.Equal(t, 0.95, minScore)
+			return nil, nil
+		},
+	}
+	router := setupRouter(repo, &invalidated{})
+
+	recorder := serve(router, "GET", "/v2/admin/catalog/duplicates?min_score=0.95", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"api_version":"v2","count":0}}`, recorder.Body.String())
+
+	recorder = serve(router, "GET", "/v2/admin/catalog/duplicates?min_score=2", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestMergeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		MergeFunc: func(survivorID, duplicateID int) error {
+			assert.Equal(t, 1, survivorID)
+			assert.Equal(t, 3, duplicateID)
+			return nil
+		},
+	}
+	cache := &invalidated{}
+
+	recorder := serve(setupRouter(repo, cache), "POST", "/v2/admin/catalog/merge", `{"survivor_id":1,"duplicate_id":3}`)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, invalidated{"1", "3"}, *cache)
+}
+
+func TestMergeHandler_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{}, &invalidated{})
+
+	for _, body := range []string{`{}`, `{"survivor_id":1}`, `{"survivor_id":1,"duplicate_id":1}`} {
+		recorder := serve(router, "POST", "/v2/admin/catalog/merge", body)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+}
+
+func TestMergeHandler_MovieNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		MergeFunc: func(survivorID, duplicateID int) error {
+			return ErrMovieNotFound
+		},
+	}
+	cache := &invalidated{}
+
+	recorder := serve(setupRouter(repo, cache), "POST", "/v2/admin/catalog/merge", `{"survivor_id":1,"duplicate_id":99}`)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Empty(t, *cache)
+}
+
+func TestQualityReportHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListMovieDetailsFunc: func() ([]movies.Movie, error) {
+			return testCatalog(), nil
+		},
+	}
+
+	recorder := serve(setupRouter(repo, &invalidated{}), "GET", "/v2/admin/catalog/quality?rule=empty_actors", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{
+		"data": {
+			"movies": 3,
+			"counts": [{"rule":"empty_actors","count":1}],
+			"issues": [{"rule":"empty_actors","movie_id":3,"title":"Heat","detail":"actors is empty"}]
+		},
+		"meta": {"api_version":"v2"}
+	}`, recorder.Body.String())
+}
+
+func TestQualityReportHandler_CSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListMovieDetailsFunc: func() ([]movies.Movie, error) {
+			return testCatalog(), nil
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "/v2/admin/catalog/quality?rule=missing_plot,empty_actors", nil)
+	req.Header.Set("Accept", "text/csv")
+	recorder := httptest.NewRecorder()
+	setupRouter(repo, &invalidated{}).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "rule,movie_id,title,detail\nmissing_plot,2,Matrix,plot is empty\nempty_actors,3,Heat,actors is empty\n",
+		recorder.Body.String())
+}
+
+func TestQualityReportHandler_UnknownRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := serve(setupRouter(&mockRepository{}, &invalidated{}), "GET", "/v2/admin/catalog/quality?rule=nope", "")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
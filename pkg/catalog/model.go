@@ -0,0 +1,39 @@
+// Package catalog holds maintenance tools for the movie catalog.
+package catalog
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Candidate is the part of a movie that duplicate detection looks at.
+type Candidate struct {
+	MovieID int
+	Title   string
+	Year    int
+	ImdbID  string
+}
+
+// Duplicate is a pair of movies that probably describe the same title.
+// MovieID is always the lower of the two ids.
+type Duplicate struct {
+	XMLName        xml.Name  `json:"-" xml:"duplicate"`
+	MovieID        int       `json:"movie_id" xml:"movie_id"`
+	Title          string    `json:"title" xml:"title"`
+	DuplicateID    int       `json:"duplicate_id" xml:"duplicate_id"`
+	DuplicateTitle string    `json:"duplicate_title" xml:"duplicate_title"`
+	Year           int       `json:"year" xml:"year"`
+	Score          float64   `json:"score" xml:"score"`
+	DetectedAt     time.Time `json:"detected_at" xml:"detected_at"`
+}
+
+// MergeRequest folds DuplicateID into SurvivorID.
+type MergeRequest struct {
+	SurvivorID  int `json:"survivor_id" binding:"required,gt=0"`
+	DuplicateID int `json:"duplicate_id" binding:"required,gt=0,nefield=SurvivorID"`
+}
+
+type ScanResult struct {
+	XMLName    xml.Name `json:"-" xml:"scan"`
+	Duplicates int      `json:"duplicates" xml:"duplicates"`
+}
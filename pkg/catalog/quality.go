@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"movie-rental/pkg/movies"
+)
+
+// Rule is one data-quality check over the catalog. Rules see every movie at
+// once so that they can compare movies with each other.
+type Rule interface {
+	// Name identifies the rule in reports and in the rule query parameter.
+	Name() string
+	Check(catalog []movies.Movie) []Issue
+}
+
+// Issue is one problem a rule found with one movie.
+type Issue struct {
+	XMLName xml.Name `json:"-" xml:"issue"`
+	Rule    string   `json:"rule" xml:"rule"`
+	MovieID int      `json:"movie_id" xml:"movie_id"`
+	Title   string   `json:"title" xml:"title"`
+	Detail  string   `json:"detail" xml:"detail"`
+}
+
+type RuleCount struct {
+	XMLName xml.Name `json:"-" xml:"rule"`
+	Rule    string   `json:"rule" xml:"name"`
+	Count   int      `json:"count" xml:"count"`
+}
+
+type QualityReport struct {
+	XMLName xml.Name    `json:"-" xml:"quality_report"`
+	Movies  int         `json:"movies" xml:"movies"`
+	Counts  []RuleCount `json:"counts" xml:"counts>rule"`
+	Issues  []Issue     `json:"issues" xml:"issues>issue"`
+}
+
+// earliestFilmYear is when the first surviving motion picture was made.
+const earliestFilmYear = 1888
+
+// announcedYears is how far ahead of now a release year is still plausible.
+const announcedYears = 5
+
+var imdbIDPattern = regexp.MustCompile(`^tt\d{7,8}$`)
+
+// DefaultRules are the checks run when a report names none. now tells the
+// year check what the current year is.
+func DefaultRules(now func() time.Time) []Rule {
+	return []Rule{
+		MovieRule("missing_plot", func(m movies.Movie) string {
+			if strings.TrimSpace(m.Plot) == "" {
+				return "plot is empty"
+			}
+			return ""
+		}),
+		MovieRule("malformed_imdbid", func(m movies.Movie) string {
+			if m.ImdbID == "" {
+				return "imdbid is missing"
+			}
+			if !imdbIDPattern.MatchString(m.ImdbID) {
+				return fmt.Sprintf("imdbid %q is not of the form tt1234567", m.ImdbID)
+			}
+			return ""
+		}),
+		MovieRule("implausible_year", func(m movies.Movie) string {
+			latest := now().Year() + announcedYears
+			if m.Year < earliestFilmYear || m.Year > latest {
+				return fmt.Sprintf("year %d is outside %d-%d", m.Year, earliestFilmYear, latest)
+			}
+			return ""
+		}),
+		MovieRule("empty_actors", func(m movies.Movie) string {
+			if strings.TrimSpace(m.Actors) == "" {
+				return "actors is empty"
+			}
+			return ""
+		}),
+		duplicateTitleRule{},
+	}
+}
+
+// MovieRule adapts a check of a single movie into a Rule. check returns why
+// the movie fails, or "" when it passes.
+func MovieRule(name string, check func(movies.Movie) string) Rule {
+	return movieRule{name: name, check: check}
+}
+
+type movieRule struct {
+	name  string
+	check func(movies.Movie) string
+}
+
+func (r movieRule) Name() string { return r.name }
+
+func (r movieRule) Check(catalog []movies.Movie) []Issue {
+	var issues []Issue
+	for _, m := range catalog {
+		if detail := r.check(m); detail != "" {
+			issues = append(issues, Issue{Rule: r.name, MovieID: m.MovieID, Title: m.Title, Detail: detail})
+		}
+	}
+	return issues
+}
+
+// duplicateTitleRule flags every movie that shares its normalized title and
+// year with an earlier one.
+type duplicateTitleRule struct{}
+
+func (duplicateTitleRule) Name() string { return "duplicate_title" }
+
+func (r duplicateTitleRule) Check(catalog []movies.Movie) []Issue {
+	type key struct {
+		title string
+		year  int
+	}
+	first := make(map[key]int)
+	var issues []Issue
+	for _, m := range catalog {
+		k := key{normalizeTitle(m.Title), m.Year}
+		if k.title == "" {
+			continue
+		}
+		if id, ok := first[k]; ok {
+			issues = append(issues, Issue{
+				Rule:    r.Name(),
+				MovieID: m.MovieID,
+				Title:   m.Title,
+				Detail:  fmt.Sprintf("same title and year as movie %d", id),
+			})
+			continue
+		}
+		first[k] = m.MovieID
+	}
+	return issues
+}
+
+// RunRules checks catalog against rules. Every rule appears in the counts,
+// including those that found nothing.
+func RunRules(catalog []movies.Movie, rules []Rule) QualityReport {
+	report := QualityReport{Movies: len(catalog), Counts: []RuleCount{}, Issues: []Issue{}}
+	for _, rule := range rules {
+		issues := rule.Check(catalog)
+		report.Counts = append(report.Counts, RuleCount{Rule: rule.Name(), Count: len(issues)})
+		report.Issues = append(report.Issues, issues...)
+	}
+	return report
+}
+
+// SelectRules returns the rules named in names, or all of them when names is
+// empty.
+func SelectRules(rules []Rule, names []string) ([]Rule, error) {
+	if len(names) == 0 {
+		return rules, nil
+	}
+	var selected []Rule
+	for _, name := range names {
+		found := false
+		for _, rule := range rules {
+			if rule.Name() == name {
+				selected = append(selected, rule)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	return selected, nil
+}
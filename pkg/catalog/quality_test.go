@@ -0,0 +1,71 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"movie-rental/pkg/movies"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCatalog() []movies.Movie {
+	return []movies.Movie{
+		{MovieID: 1, Title: "The Matrix", Year: 1999, Plot: "A hacker learns the truth.", ImdbID: "tt0133093", Actors: "Keanu Reeves"},
+		{MovieID: 2, Title: "Matrix", Year: 1999, Plot: " ", ImdbID: "", Actors: "Keanu Reeves"},
+		{MovieID: 3, Title: "Heat", Year: 1195, Plot: "Cops and robbers.", ImdbID: "0113277", Actors: ""},
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	report := RunRules(testCatalog(), DefaultRules(func() time.Time { return now }))
+
+	assert.Equal(t, 3, report.Movies)
+	assert.Equal(t, []RuleCount{
+		{Rule: "missing_plot", Count: 1},
+		{Rule: "malformed_imdbid", Count: 2},
+		{Rule: "implausible_year", Count: 1},
+		{Rule: "empty_actors", Count: 1},
+		{Rule: "duplicate_title", Count: 1},
+	}, report.Counts)
+	assert.Equal(t, []Issue{
+		{Rule: "missing_plot", MovieID: 2, Title: "Matrix", Detail: "plot is empty"},
+		{Rule: "malformed_imdbid", MovieID: 2, Title: "Matrix", Detail: "imdbid is missing"},
+		{Rule: "malformed_imdbid", MovieID: 3, Title: "Heat", Detail: `imdbid "0113277" is not of the form tt1234567`},
+		{Rule: "implausible_year", MovieID: 3, Title: "Heat", Detail: "year 1195 is outside 1888-2031"},
+		{Rule: "empty_actors", MovieID: 3, Title: "Heat", Detail: "actors is empty"},
+		{Rule: "duplicate_title", MovieID: 2, Title: "Matrix", Detail: "same title and year as movie 1"},
+	}, report.Issues)
+}
+
+func TestRunRules_CustomRule(t *testing.T) {
+	noGenre := MovieRule("missing_genre", func(m movies.Movie) string {
+		if m.Genre == "" {
+			return "genre is empty"
+		}
+		return ""
+	})
+
+	report := RunRules(testCatalog()[:1], []Rule{noGenre})
+
+	assert.Equal(t, []RuleCount{{Rule: "missing_genre", Count: 1}}, report.Counts)
+	assert.Len(t, report.Issues, 1)
+}
+
+func TestSelectRules(t *testing.T) {
+	rules := DefaultRules(time.Now)
+
+	selected, err := SelectRules(rules, nil)
+	assert.NoError(t, err)
+	assert.Len(t, selected, len(rules))
+
+	selected, err = SelectRules(rules, []string{"empty_actors", "missing_plot"})
+	assert.NoError(t, err)
+	assert.Equal(t, "empty_actors", selected[0].Name())
+	assert.Equal(t, "missing_plot", selected[1].Name())
+
+	_, err = SelectRules(rules, []string{"nope"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,231 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"movie-rental/pkg/movies"
+
+	"github.com/lib/pq"
+)
+
+var ErrMovieNotFound = errors.New("movie not found")
+
+// movieReferences lists every table with a movie_id column, together with
+// the other columns of its unique key. A merge repoints their rows to the
+// surviving movie and drops those that would collide with its own.
+// movie_prices is left out: it follows movie_offers through its foreign key.
+var movieReferences = []struct {
+	table string
+	keys  []string
+}{
+	{"cart", []string{"user_id"}},
+	{"guest_carts", []string{"guest_id"}},
+	{"wishlist", []string{"user_id"}},
+	{"movie_tags", []string{"tag_id", "user_id"}},
+	{"shelf_movies", []string{"shelf_id"}},
+	{"movie_territories", []string{"region"}},
+	{"movie_offers", []string{"format", "rental_days"}},
+	{"trending_scores", []string{"time_window"}},
+	{"gifts", nil},
+	{"rentals", nil},
+	{"activity_events", nil},
+}
+
+type Repository interface {
+	ListCandidates(ctx context.Context) ([]Candidate, error)
+	// ReplaceDuplicates stores the result of a scan in place of the last one.
+	ReplaceDuplicates(ctx context.Context, duplicates []Duplicate, detectedAt time.Time) error
+	ListDuplicates(ctx context.Context, minScore float64) ([]Duplicate, error)
+	// Merge moves everything that references duplicateID to survivorID,
+	// fills the survivor's empty details from the duplicate, and deletes the
+	// duplicate, all in one transaction.
+	Merge(ctx context.Context, survivorID, duplicateID int) error
+	// ListMovieDetails returns every movie with NULL columns read as zero
+	// values, for the quality report.
+	ListMovieDetails(ctx context.Context) ([]movies.Movie, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListCandidates(ctx context.Context) ([]Candidate, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT movie_id, title, COALESCE(year, 0), COALESCE(imdbid, '') FROM movies ORDER BY movie_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	for rows.Next() {
+		var c Candidate
+		if err := rows.Scan(&c.MovieID, &c.Title, &c.Year, &c.ImdbID); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+func (r *repository) ListMovieDetails(ctx context.Context) ([]movies.Movie, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT movie_id, title, COALESCE(year, 0), COALESCE(plot, ''), COALESCE(genre, ''),
+			COALESCE(imdbid, ''), COALESCE(actors, '')
+		FROM movies ORDER BY movie_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []movies.Movie
+	for rows.Next() {
+		var m movies.Movie
+		if err := rows.Scan(&m.MovieID, &m.Title, &m.Year, &m.Plot, &m.Genre, &m.ImdbID, &m.Actors); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+func (r *repository) ReplaceDuplicates(ctx context.Context, duplicates []Duplicate, detectedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM duplicate_candidates"); err != nil {
+		return err
+	}
+	movieIDs := make([]int64, len(duplicates))
+	duplicateIDs := make([]int64, len(duplicates))
+	scores := make([]float64, len(duplicates))
+	for i, d := range duplicates {
+		movieIDs[i], duplicateIDs[i], scores[i] = int64(d.MovieID), int64(d.DuplicateID), d.Score
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO duplicate_candidates (movie_id, duplicate_id, score, detected_at)
+		SELECT movie_id, duplicate_id, score, $4
+		FROM unnest($1::int[], $2::int[], $3::float8[]) AS t(movie_id, duplicate_id, score)`,
+		pq.Array(movieIDs), pq.Array(duplicateIDs), pq.Array(scores), detectedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) ListDuplicates(ctx context.Context, minScore float64) ([]Duplicate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT dc.movie_id, m.title, dc.duplicate_id, d.title, COALESCE(m.year, 0), dc.score, dc.detected_at
+		FROM duplicate_candidates dc
+		JOIN movies m ON m.movie_id = dc.movie_id
+		JOIN movies d ON d.movie_id = dc.duplicate_id
+		WHERE dc.score >= $1
+		ORDER BY dc.score DESC, dc.movie_id, dc.duplicate_id`, minScore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []Duplicate
+	for rows.Next() {
+		var d Duplicate
+		if err := rows.Scan(&d.MovieID, &d.Title, &d.DuplicateID, &d.DuplicateTitle, &d.Year, &d.Score, &d.DetectedAt); err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, d)
+	}
+	return duplicates, rows.Err()
+}
+
+func (r *repository) Merge(ctx context.Context, survivorID, duplicateID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT movie_id, COALESCE(plot, ''), COALESCE(genre, ''), COALESCE(imdbid, ''), COALESCE(actors, '')
+		FROM movies WHERE movie_id IN ($1, $2)
+		ORDER BY movie_id
+		FOR UPDATE`, survivorID, duplicateID)
+	if err != nil {
+		return err
+	}
+	var found int
+	var plot, genre, imdbID, actors string
+	for rows.Next() {
+		var id int
+		var p, g, i, a string
+		if err := rows.Scan(&id, &p, &g, &i, &a); err != nil {
+			rows.Close()
+			return err
+		}
+		found++
+		if id == duplicateID {
+			plot, genre, imdbID, actors = p, g, i, a
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if found != 2 {
+		return ErrMovieNotFound
+	}
+
+	for _, ref := range movieReferences {
+		if _, err := tx.ExecContext(ctx, repointQuery(ref.table, ref.keys), survivorID, duplicateID); err != nil {
+			return err
+		}
+		if len(ref.keys) > 0 {
+			// Whatever is left collided with a row of the survivor.
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+ref.table+" WHERE movie_id = $1", duplicateID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The duplicate goes first so that its imdbid is free to move across.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movies WHERE movie_id = $1", duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE movies SET
+			plot = COALESCE(NULLIF(plot, ''), NULLIF($2, '')),
+			genre = COALESCE(NULLIF(genre, ''), NULLIF($3, '')),
+			imdbid = COALESCE(NULLIF(imdbid, ''), NULLIF($4, '')),
+			actors = COALESCE(NULLIF(actors, ''), NULLIF($5, ''))
+		WHERE movie_id = $1`, survivorID, plot, genre, imdbID, actors); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// repointQuery moves rows of table from movie $2 to movie $1, skipping rows
+// whose unique key the survivor already holds.
+func repointQuery(table string, keys []string) string {
+	query := fmt.Sprintf("UPDATE %s t SET movie_id = $1 WHERE t.movie_id = $2", table)
+	if len(keys) == 0 {
+		return query
+	}
+	match := make([]string, len(keys))
+	for i, k := range keys {
+		match[i] = fmt.Sprintf("s.%[1]s = t.%[1]s", k)
+	}
+	return query + fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM %s s WHERE s.movie_id = $1 AND %s)",
+		table, strings.Join(match, " AND "))
+}
@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListCandidates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT movie_id, title, COALESCE\(year, 0\), COALESCE\(imdbid, ''\) FROM movies`).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "title", "year", "imdbid"}).
+			AddRow(1, "The Matrix", 1999, "tt0133093").
+			AddRow(2, "Matrix", 1999, ""))
+
+	repo := NewRepository(db)
+	candidates, err := repo.ListCandidates(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{
+		{MovieID: 1, Title: "The Matrix", Year: 1999, ImdbID: "tt0133093"},
+		{MovieID: 2, Title: "Matrix", Year: 1999},
+	}, candidates)
+}
+
+func TestReplaceDuplicates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM duplicate_candidates`).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`INSERT INTO duplicate_candidates \(movie_id, duplicate_id, score, detected_at\) SELECT (.+) FROM unnest`).
+		WithArgs(pq.Array([]int64{1, 4}), pq.Array([]int64{3, 5}), pq.Array([]float64{1, 0.9}), now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.ReplaceDuplicates(context.Background(), []Duplicate{
+		{MovieID: 1, DuplicateID: 3, Score: 1},
+		{MovieID: 4, DuplicateID: 5, Score: 0.9},
+	}, now)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDuplicates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT (.+) FROM duplicate_candidates dc JOIN movies m (.+) WHERE dc.score >= \$1`).
+		WithArgs(0.9).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "title", "duplicate_id", "title", "year", "score", "detected_at"}).
+			AddRow(1, "The Matrix", 3, "Matrix", 1999, 1.0, now))
+
+	repo := NewRepository(db)
+	duplicates, err := repo.ListDuplicates(context.Background(), 0.9)
+	assert.NoError(t, err)
+	assert.Equal(t, []Duplicate{{MovieID: 1, Title: "The Matrix", DuplicateID: 3, DuplicateTitle: "Matrix", Year: 1999, Score: 1, DetectedAt: now}}, duplicates)
+}
+
+func expectLockMovies(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectQuery(`SELECT movie_id, (.+) FROM movies WHERE movie_id IN \(\$1, \$2\) ORDER BY movie_id FOR UPDATE`).
+		WithArgs(1, 3).
+		WillReturnRows(rows)
+}
+
+func newLockRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"movie_id", "plot", "genre", "imdbid", "actors"})
+}
+
+func TestMerge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockMovies(mock, newLockRows().
+		AddRow(1, "", "Sci-Fi", "", "Keanu Reeves").
+		AddRow(3, "A hacker learns the truth.", "Action", "tt0133093", ""))
+	mock.ExpectExec(`UPDATE cart t SET movie_id = \$1 WHERE t.movie_id = \$2 AND NOT EXISTS \(SELECT 1 FROM cart s WHERE s.movie_id = \$1 AND s.user_id = t.user_id\)`).
+		WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM cart WHERE movie_id = \$1`).
+		WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE guest_carts t`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM guest_carts`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE wishlist t`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM wishlist`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE movie_tags t SET movie_id = \$1 WHERE t.movie_id = \$2 AND NOT EXISTS \(SELECT 1 FROM movie_tags s WHERE s.movie_id = \$1 AND s.tag_id = t.tag_id AND s.user_id = t.user_id\)`).
+		WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM movie_tags`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE shelf_movies t`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM shelf_movies`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE movie_territories t`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM movie_territories`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE movie_offers t`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM movie_offers`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE trending_scores t`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM trending_scores`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE gifts t SET movie_id = \$1 WHERE t.movie_id = \$2$`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE rentals t SET movie_id = \$1 WHERE t.movie_id = \$2$`).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE activity_events t SET movie_id = \$1 WHERE t.movie_id = \$2$`).
+		WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 7))
+	mock.ExpectExec(`DELETE FROM movies WHERE movie_id = \$1`).
+		WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE movies SET plot = COALESCE\(NULLIF\(plot, ''\), NULLIF\(\$2, ''\)\)`).
+		WithArgs(1, "A hacker learns the truth.", "Action", "tt0133093", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.Merge(context.Background(), 1, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMerge_MovieNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockMovies(mock, newLockRows().AddRow(1, "", "", "", ""))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.Merge(context.Background(), 1, 3)
+	assert.ErrorIs(t, err, ErrMovieNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMerge_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockMovies(mock, newLockRows().AddRow(1, "", "", "", "").AddRow(3, "", "", "", ""))
+	mock.ExpectExec(`UPDATE cart t`).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.Merge(context.Background(), 1, 3)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListMovieDetails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT movie_id, title, COALESCE\(year, 0\), COALESCE\(plot, ''\), (.+) FROM movies ORDER BY movie_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors"}).
+			AddRow(1, "The Matrix", 1999, "", "Sci-Fi", "tt0133093", ""))
+
+	repo := NewRepository(db)
+	list, err := repo.ListMovieDetails(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "Sci-Fi", list[0].Genre)
+}
@@ -0,0 +1,20 @@
+package catalog
+
+import (
+	"context"
+	"time"
+)
+
+// ScanDuplicates runs duplicate detection over the whole catalog and stores
+// the pairs scoring at least minScore, replacing the previous scan.
+func ScanDuplicates(ctx context.Context, repo Repository, minScore float64, now time.Time) ([]Duplicate, error) {
+	candidates, err := repo.ListCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := FindDuplicates(candidates, minScore, now)
+	if err := repo.ReplaceDuplicates(ctx, duplicates, now); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultMinScore is the lowest score reported as a probable duplicate.
+const DefaultMinScore = 0.85
+
+// conflictingImdbPenalty scales the score of two titles that carry
+// different IMDb ids: they may still be a bad import, but are more likely
+// distinct films that share a name, such as a remake.
+const conflictingImdbPenalty = 0.8
+
+// FindDuplicates returns the pairs of candidates released in the same year
+// whose Score is at least minScore, best matches first.
+func FindDuplicates(candidates []Candidate, minScore float64, now time.Time) []Duplicate {
+	byYear := make(map[int][]Candidate)
+	for _, c := range candidates {
+		byYear[c.Year] = append(byYear[c.Year], c)
+	}
+
+	var found []Duplicate
+	for year, group := range byYear {
+		for i := range group {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i], group[j]
+				if a.MovieID > b.MovieID {
+					a, b = b, a
+				}
+				score := Score(a, b)
+				if score < minScore {
+					continue
+				}
+				found = append(found, Duplicate{
+					MovieID:        a.MovieID,
+					Title:          a.Title,
+					DuplicateID:    b.MovieID,
+					DuplicateTitle: b.Title,
+					Year:           year,
+					Score:          score,
+					DetectedAt:     now,
+				})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Score != found[j].Score {
+			return found[i].Score > found[j].Score
+		}
+		if found[i].MovieID != found[j].MovieID {
+			return found[i].MovieID < found[j].MovieID
+		}
+		return found[i].DuplicateID < found[j].DuplicateID
+	})
+	return found
+}
+
+// Score rates how likely a and b are the same movie, from 0 to 1. It is the
+// similarity of their normalized titles, penalized when both have an IMDb id
+// and the ids differ. Movies from different years never match.
+func Score(a, b Candidate) float64 {
+	if a.Year != b.Year {
+		return 0
+	}
+	if a.ImdbID != "" && a.ImdbID == b.ImdbID {
+		return 1
+	}
+	ta, tb := normalizeTitle(a.Title), normalizeTitle(b.Title)
+	if ta == "" || tb == "" {
+		return 0
+	}
+	score := titleSimilarity(ta, tb)
+	if a.ImdbID != "" && b.ImdbID != "" {
+		score *= conflictingImdbPenalty
+	}
+	return math.Round(score*100) / 100
+}
+
+// normalizeTitle lower-cases a title, drops punctuation and a leading
+// article, and collapses whitespace, so "The Matrix" and "matrix, the"
+// compare equal.
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) > 1 && isArticle(fields[0]) {
+		fields = fields[1:]
+	} else if len(fields) > 1 && isArticle(fields[len(fields)-1]) {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
+
+func isArticle(word string) bool {
+	return word == "the" || word == "a" || word == "an"
+}
+
+// titleSimilarity is one minus the edit distance between a and b relative
+// to the longer of the two.
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,49 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	assert.Equal(t, "matrix", normalizeTitle("The Matrix"))
+	assert.Equal(t, "matrix", normalizeTitle("Matrix, The"))
+	assert.Equal(t, "alien", normalizeTitle("  ALIEN! "))
+	assert.Equal(t, "the", normalizeTitle("The"))
+}
+
+func TestScore(t *testing.T) {
+	matrix := Candidate{MovieID: 1, Title: "The Matrix", Year: 1999}
+
+	assert.Equal(t, 1.0, Score(matrix, Candidate{MovieID: 2, Title: "Matrix, The", Year: 1999}))
+	assert.Equal(t, 0.0, Score(matrix, Candidate{MovieID: 2, Title: "The Matrix", Year: 2021}))
+	assert.Equal(t, 1.0, Score(
+		Candidate{Title: "Heat", Year: 1995, ImdbID: "tt0113277"},
+		Candidate{Title: "Heat (Director's Cut)", Year: 1995, ImdbID: "tt0113277"}))
+	assert.Equal(t, 0.8, Score(
+		Candidate{Title: "Crash", Year: 2004, ImdbID: "tt0375679"},
+		Candidate{Title: "Crash", Year: 2004, ImdbID: "tt0115964"}))
+	assert.InDelta(t, 0.83, Score(matrix, Candidate{Title: "The Matrx", Year: 1999}), 0.01)
+	assert.Less(t, Score(matrix, Candidate{Title: "Memento", Year: 1999}), 0.5)
+}
+
+func TestFindDuplicates(t *testing.T) {
+	now := time.Now()
+	candidates := []Candidate{
+		{MovieID: 3, Title: "The Matrix", Year: 1999},
+		{MovieID: 1, Title: "Matrix", Year: 1999, ImdbID: "tt0133093"},
+		{MovieID: 2, Title: "The Matrix", Year: 2003},
+		{MovieID: 4, Title: "Toy Story", Year: 1995},
+		{MovieID: 5, Title: "Toy Story!", Year: 1995},
+		{MovieID: 6, Title: "Memento", Year: 1999},
+	}
+
+	found := FindDuplicates(candidates, DefaultMinScore, now)
+
+	assert.Equal(t, []Duplicate{
+		{MovieID: 1, Title: "Matrix", DuplicateID: 3, DuplicateTitle: "The Matrix", Year: 1999, Score: 1, DetectedAt: now},
+		{MovieID: 4, Title: "Toy Story", DuplicateID: 5, DuplicateTitle: "Toy Story!", Year: 1995, Score: 1, DetectedAt: now},
+	}, found)
+}
@@ -0,0 +1,62 @@
+package gifts
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeAlreadyClaimed = "gift_already_claimed"
+	CodeNotRecipient   = "gift_not_yours"
+)
+
+// ListReceivedHandler responds with the gifts addressed to or claimed by
+// the user, newest first.
+func ListReceivedHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+
+		gifts, err := repo.Received(c.Request.Context(), userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.List(c, NewGiftResponses(gifts), len(gifts))
+	}
+}
+
+// ClaimHandler starts the rental of a gift for the user claiming it.
+func ClaimHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ClaimRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "code and a positive user_id are required")
+			return
+		}
+
+		gift, err := repo.Claim(c.Request.Context(), req.Code, req.UserID, time.Now())
+		switch {
+		case errors.Is(err, ErrGiftNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case errors.Is(err, ErrAlreadyClaimed):
+			api.Error(c, http.StatusConflict, CodeAlreadyClaimed, err.Error())
+		case errors.Is(err, ErrNotRecipient):
+			api.Error(c, http.StatusForbidden, CodeNotRecipient, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			api.OK(c, http.StatusOK, NewGiftResponse(gift))
+		}
+	}
+}
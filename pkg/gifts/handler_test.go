@@ -0,0 +1,105 @@
+package gifts
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/movies"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	ReceivedFunc func(userID int) ([]Gift, error)
+	ClaimFunc    func(code string, userID int) (Gift, error)
+}
+
+func (m *mockRepository) Received(_ context.Context, userID int) ([]Gift, error) {
+	return m.ReceivedFunc(userID)
+}
+func (m *mockRepository) Claim(_ context.Context, code string, userID int, now time.Time) (Gift, error) {
+	return m.ClaimFunc(code, userID)
+}
+
+func setupRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/gifts/:user_id", ListReceivedHandler(repo))
+	router.POST("/v2/gifts/claim", ClaimHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestListReceivedHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := &mockRepository{
+		ReceivedFunc: func(userID int) ([]Gift, error) {
+			assert.Equal(t, 5, userID)
+			return []Gift{{GiftID: 11, ClaimCode: "CODE", SenderID: 1, RecipientID: 5, Message: "Enjoy",
+				Movie: movies.Movie{MovieID: 2, Title: "Movie 2"}, Format: "digital", RentalDays: 2, CreatedAt: created}}, nil
+		},
+	}
+
+	recorder := serve(setupRouter(repo), "GET", "/v2/gifts/5", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"claim_code":"CODE"`)
+	assert.Contains(t, recorder.Body.String(), `"recipient_user_id":5`)
+	assert.Contains(t, recorder.Body.String(), `"claimed_at":null,"ends_at":null`)
+	assert.Contains(t, recorder.Body.String(), `"count":1`)
+}
+
+func TestClaimHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	claimed := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := &mockRepository{
+		ClaimFunc: func(code string, userID int) (Gift, error) {
+			assert.Equal(t, "CODE", code)
+			assert.Equal(t, 5, userID)
+			return Gift{GiftID: 11, ClaimCode: code, RentalDays: 2, ClaimedBy: userID, ClaimedAt: &claimed}, nil
+		},
+	}
+
+	recorder := serve(setupRouter(repo), "POST", "/v2/gifts/claim", `{"code":"CODE","user_id":5}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"ends_at":"2026-10-21T12:00:00Z"`)
+}
+
+func TestClaimHandler_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrGiftNotFound, http.StatusNotFound, "not_found"},
+		{ErrAlreadyClaimed, http.StatusConflict, CodeAlreadyClaimed},
+		{ErrNotRecipient, http.StatusForbidden, CodeNotRecipient},
+	}
+	for _, tc := range cases {
+		repo := &mockRepository{
+			ClaimFunc: func(code string, userID int) (Gift, error) { return Gift{}, tc.err },
+		}
+
+		recorder := serve(setupRouter(repo), "POST", "/v2/gifts/claim", `{"code":"CODE","user_id":5}`)
+
+		assert.Equal(t, tc.status, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"`+tc.code+`"`)
+	}
+
+	recorder := serve(setupRouter(&mockRepository{}), "POST", "/v2/gifts/claim", `{"code":"CODE"}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
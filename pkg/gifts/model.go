@@ -0,0 +1,95 @@
+package gifts
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+)
+
+var (
+	ErrGiftNotFound   = errors.New("gift not found")
+	ErrAlreadyClaimed = errors.New("gift has already been claimed")
+	ErrNotRecipient   = errors.New("gift is for someone else")
+)
+
+// Gift is a rental bought for someone else. The rental belongs to the
+// recipient and starts when they claim it with ClaimCode.
+type Gift struct {
+	GiftID    int
+	ClaimCode string
+	SenderID  int
+	// RecipientID is 0 for gifts addressed by RecipientEmail, which anyone
+	// holding the code may claim.
+	RecipientID    int
+	RecipientEmail string
+	Message        string
+	Movie          movies.Movie
+	Format         cart.Format
+	RentalDays     int
+	CreatedAt      time.Time
+	// ClaimedBy and ClaimedAt are zero until the gift is claimed.
+	ClaimedBy int
+	ClaimedAt *time.Time
+}
+
+// EndsAt returns when the gifted rental ends, or nil if it has not been
+// claimed.
+func (g Gift) EndsAt() *time.Time {
+	if g.ClaimedAt == nil {
+		return nil
+	}
+	end := g.ClaimedAt.AddDate(0, 0, g.RentalDays)
+	return &end
+}
+
+// ClaimRequest is the request body for claiming a gift.
+type ClaimRequest struct {
+	Code   string `json:"code" binding:"required,max=32"`
+	UserID int    `json:"user_id" binding:"required,gt=0"`
+}
+
+type GiftResponse struct {
+	XMLName        xml.Name             `json:"-" xml:"gift"`
+	GiftID         int                  `json:"gift_id" xml:"gift_id"`
+	ClaimCode      string               `json:"claim_code" xml:"claim_code"`
+	SenderID       int                  `json:"sender_id" xml:"sender_id"`
+	RecipientID    int                  `json:"recipient_user_id,omitempty" xml:"recipient_user_id,omitempty"`
+	RecipientEmail string               `json:"recipient_email,omitempty" xml:"recipient_email,omitempty"`
+	Message        string               `json:"message" xml:"message"`
+	Movie          movies.MovieResponse `json:"movie" xml:"movie"`
+	Format         cart.Format          `json:"format" xml:"format"`
+	RentalDays     int                  `json:"rental_days" xml:"rental_days"`
+	CreatedAt      time.Time            `json:"created_at" xml:"created_at"`
+	ClaimedBy      int                  `json:"claimed_by,omitempty" xml:"claimed_by,omitempty"`
+	ClaimedAt      *time.Time           `json:"claimed_at" xml:"claimed_at,omitempty"`
+	EndsAt         *time.Time           `json:"ends_at" xml:"ends_at,omitempty"`
+}
+
+func NewGiftResponse(g Gift) GiftResponse {
+	return GiftResponse{
+		GiftID:         g.GiftID,
+		ClaimCode:      g.ClaimCode,
+		SenderID:       g.SenderID,
+		RecipientID:    g.RecipientID,
+		RecipientEmail: g.RecipientEmail,
+		Message:        g.Message,
+		Movie:          movies.NewMovieResponse(g.Movie),
+		Format:         g.Format,
+		RentalDays:     g.RentalDays,
+		CreatedAt:      g.CreatedAt,
+		ClaimedBy:      g.ClaimedBy,
+		ClaimedAt:      g.ClaimedAt,
+		EndsAt:         g.EndsAt(),
+	}
+}
+
+func NewGiftResponses(gifts []Gift) []GiftResponse {
+	out := make([]GiftResponse, len(gifts))
+	for i, g := range gifts {
+		out[i] = NewGiftResponse(g)
+	}
+	return out
+}
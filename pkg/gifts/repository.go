@@ -0,0 +1,147 @@
+package gifts
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+)
+
+type Repository interface {
+	// Received returns the gifts addressed to the user or claimed by them,
+	// newest first.
+	Received(ctx context.Context, userID int) ([]Gift, error)
+	// Claim starts the rental of the gift with code for the user as of now.
+	Claim(ctx context.Context, code string, userID int, now time.Time) (Gift, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// claimCodes are short enough to type from an email.
+var claimCodes = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newClaimCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return claimCodes.EncodeToString(b), nil
+}
+
+// Create records item, bought by senderID, as a gift with a new claim
+// code. It takes a Querier so that checkout can create gifts inside its
+// own transaction.
+func Create(ctx context.Context, q cart.Querier, senderID int, item cart.Item, gift cart.Gift) (Gift, error) {
+	g := Gift{
+		SenderID:       senderID,
+		RecipientID:    gift.RecipientID,
+		RecipientEmail: gift.RecipientEmail,
+		Message:        gift.Message,
+		Movie:          movies.Movie{MovieID: item.MovieID},
+		Format:         item.Format,
+		RentalDays:     item.RentalDays,
+	}
+	code, err := newClaimCode()
+	if err != nil {
+		return g, err
+	}
+	g.ClaimCode = code
+
+	var recipientID, recipientEmail interface{}
+	if gift.RecipientID != 0 {
+		recipientID = gift.RecipientID
+	} else {
+		recipientEmail = gift.RecipientEmail
+	}
+	err = q.QueryRowContext(ctx, `
+		INSERT INTO gifts (claim_code, sender_id, recipient_id, recipient_email, movie_id, format, rental_days, message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING gift_id, created_at`,
+		code, senderID, recipientID, recipientEmail, item.MovieID, item.Format, item.RentalDays, gift.Message).
+		Scan(&g.GiftID, &g.CreatedAt)
+	return g, err
+}
+
+const giftColumns = `g.gift_id, g.claim_code, g.sender_id, g.recipient_id, g.recipient_email, g.message,
+	g.format, g.rental_days, g.created_at, g.claimed_by, g.claimed_at`
+
+func scanGift(row interface{ Scan(...interface{}) error }) (Gift, error) {
+	var g Gift
+	var recipientID, claimedBy sql.NullInt64
+	var recipientEmail sql.NullString
+	err := row.Scan(append(movies.ScanFields(&g.Movie), &g.GiftID, &g.ClaimCode, &g.SenderID, &recipientID, &recipientEmail,
+		&g.Message, &g.Format, &g.RentalDays, &g.CreatedAt, &claimedBy, &g.ClaimedAt)...)
+	g.RecipientID, g.RecipientEmail, g.ClaimedBy = int(recipientID.Int64), recipientEmail.String, int(claimedBy.Int64)
+	return g, err
+}
+
+func (r *repository) Received(ctx context.Context, userID int) ([]Gift, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+movies.SelectColumns("m")+`, `+giftColumns+`
+		FROM gifts g
+		JOIN movies m ON g.movie_id = m.movie_id
+		WHERE g.recipient_id = $1 OR g.claimed_by = $1
+		ORDER BY g.created_at DESC, g.gift_id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gifts []Gift
+	for rows.Next() {
+		g, err := scanGift(rows)
+		if err != nil {
+			return nil, err
+		}
+		gifts = append(gifts, g)
+	}
+	return gifts, rows.Err()
+}
+
+// Claim claims the gift and starts its rental in a single statement, so
+// that two claims of the same code cannot both succeed.
+func (r *repository) Claim(ctx context.Context, code string, userID int, now time.Time) (Gift, error) {
+	g, err := scanGift(r.db.QueryRowContext(ctx, `
+		WITH claimed AS (
+			UPDATE gifts SET claimed_by = $2, claimed_at = $3
+			WHERE claim_code = $1 AND claimed_at IS NULL AND (recipient_id IS NULL OR recipient_id = $2)
+			RETURNING *
+		), started AS (
+			UPDATE rentals r SET user_id = $2, starts_at = $3, ends_at = $3 + make_interval(days => r.rental_days)
+			FROM claimed g
+			WHERE r.gift_id = g.gift_id
+		)
+		SELECT `+movies.SelectColumns("m")+`, `+giftColumns+`
+		FROM claimed g
+		JOIN movies m ON g.movie_id = m.movie_id`, code, userID, now))
+	if err != sql.ErrNoRows {
+		return g, err
+	}
+
+	// Find out why nothing was claimed.
+	var claimed bool
+	var recipientID sql.NullInt64
+	err = r.db.QueryRowContext(ctx,
+		"SELECT claimed_at IS NOT NULL, recipient_id FROM gifts WHERE claim_code = $1", code).
+		Scan(&claimed, &recipientID)
+	switch {
+	case err == sql.ErrNoRows:
+		return g, ErrGiftNotFound
+	case err != nil:
+		return g, err
+	case claimed:
+		return g, ErrAlreadyClaimed
+	default:
+		return g, ErrNotRecipient
+	}
+}
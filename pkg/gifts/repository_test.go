@@ -0,0 +1,105 @@
+package gifts
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/cart"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var giftRowColumns = []string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until",
+	"gift_id", "claim_code", "sender_id", "recipient_id", "recipient_email", "message",
+	"format", "rental_days", "created_at", "claimed_by", "claimed_at"}
+
+func TestCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO gifts \(claim_code, sender_id, recipient_id, recipient_email, movie_id, format, rental_days, message\) VALUES (.+) RETURNING gift_id, created_at`).
+		WithArgs(sqlmock.AnyArg(), 1, nil, "friend@example.com", 2, cart.FormatDVD, 7, "Enjoy").
+		WillReturnRows(sqlmock.NewRows([]string{"gift_id", "created_at"}).AddRow(10, created))
+
+	g, err := Create(context.Background(), db, 1, cart.NewItem(2, cart.FormatDVD, 7),
+		cart.Gift{RecipientEmail: "friend@example.com", Message: "Enjoy"})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, g.GiftID)
+	assert.Equal(t, created, g.CreatedAt)
+	assert.Len(t, g.ClaimCode, 16)
+	assert.Equal(t, "friend@example.com", g.RecipientEmail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceived(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	claimed := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT m.movie_id, (.+), g.gift_id, (.+) FROM gifts g JOIN movies m ON g.movie_id = m.movie_id WHERE g.recipient_id = \$1 OR g.claimed_by = \$1 ORDER BY g.created_at DESC`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(giftRowColumns).
+			AddRow(2, "Movie 2", 2021, "", "", "", "", nil, nil, 11, "CODE", 1, 5, nil, "", "digital", 2, claimed, nil, nil).
+			AddRow(3, "Movie 3", 2022, "", "", "", "", nil, nil, 10, "OTHER", 1, nil, "friend@example.com", "Hi", "dvd", 7, claimed, 5, claimed))
+
+	gifts, err := NewRepository(db).Received(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Len(t, gifts, 2)
+	assert.Equal(t, 5, gifts[0].RecipientID)
+	assert.Nil(t, gifts[0].ClaimedAt)
+	assert.Equal(t, "friend@example.com", gifts[1].RecipientEmail)
+	assert.Equal(t, 5, gifts[1].ClaimedBy)
+	assert.Equal(t, claimed.AddDate(0, 0, 7), *gifts[1].EndsAt())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WITH claimed AS \( UPDATE gifts SET claimed_by = \$2, claimed_at = \$3 WHERE claim_code = \$1 AND claimed_at IS NULL AND \(recipient_id IS NULL OR recipient_id = \$2\) RETURNING \* \), `+
+		`started AS \( UPDATE rentals r SET user_id = \$2, starts_at = \$3, ends_at = \$3 \+ make_interval\(days => r.rental_days\) FROM claimed g WHERE r.gift_id = g.gift_id \) `+
+		`SELECT (.+) FROM claimed g JOIN movies m`).
+		WithArgs("CODE", 5, now).
+		WillReturnRows(sqlmock.NewRows(giftRowColumns).
+			AddRow(2, "Movie 2", 2021, "", "", "", "", nil, nil, 11, "CODE", 1, 5, nil, "", "digital", 2, now, 5, now))
+
+	g, err := NewRepository(db).Claim(context.Background(), "CODE", 5, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, g.ClaimedBy)
+	assert.Equal(t, now.AddDate(0, 0, 2), *g.EndsAt())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaim_Refused(t *testing.T) {
+	cases := []struct {
+		rows *sqlmock.Rows
+		want error
+	}{
+		{sqlmock.NewRows([]string{"claimed", "recipient_id"}), ErrGiftNotFound},
+		{sqlmock.NewRows([]string{"claimed", "recipient_id"}).AddRow(true, nil), ErrAlreadyClaimed},
+		{sqlmock.NewRows([]string{"claimed", "recipient_id"}).AddRow(false, 6), ErrNotRecipient},
+	}
+	for _, tc := range cases {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+
+		mock.ExpectQuery(`WITH claimed AS`).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(`SELECT claimed_at IS NOT NULL, recipient_id FROM gifts WHERE claim_code = \$1`).
+			WithArgs("CODE").
+			WillReturnRows(tc.rows)
+
+		_, err = NewRepository(db).Claim(context.Background(), "CODE", 5, time.Now())
+		assert.ErrorIs(t, err, tc.want)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	}
+}
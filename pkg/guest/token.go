@@ -0,0 +1,82 @@
+package guest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// CookieName and Header carry the guest token; the header wins when a
+	// request has both.
+	CookieName = "guest_token"
+	Header     = "X-Guest-Token"
+
+	cookieMaxAge = 30 * 24 * 60 * 60
+)
+
+var ErrInvalidToken = errors.New("invalid guest token")
+
+// Signer issues and verifies guest tokens. A token is a random guest id and
+// an HMAC-SHA256 of it, so guests cannot forge or guess each other's ids.
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+// New returns a token for a new guest along with the guest's id.
+func (s *Signer) New() (token, id string, err error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b)
+	return id + "." + s.sign(id), id, nil
+}
+
+// Verify returns the guest id in token.
+func (s *Signer) Verify(token string) (string, error) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || len(id) != 32 || !hmac.Equal([]byte(sig), []byte(s.sign(id))) {
+		return "", ErrInvalidToken
+	}
+	return id, nil
+}
+
+func (s *Signer) sign(id string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// FromRequest returns the guest token sent with the request, or "".
+func FromRequest(c *gin.Context) string {
+	if token := c.GetHeader(Header); token != "" {
+		return token
+	}
+	token, _ := c.Cookie(CookieName)
+	return token
+}
+
+// Issue hands token to the client in both the cookie and the header.
+func Issue(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, token, cookieMaxAge, "/", "", false, true)
+	c.Header(Header, token)
+}
+
+// Clear tells the client to forget its guest token.
+func Clear(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, "", -1, "/", "", false, true)
+}
@@ -0,0 +1,57 @@
+package guest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+
+	token, id, err := s.New()
+	assert.NoError(t, err)
+	assert.Len(t, id, 32)
+
+	got, err := s.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	other, _, _ := s.New()
+	assert.NotEqual(t, token, other)
+}
+
+func TestSigner_Rejects(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	token, id, _ := s.New()
+	forged, _, _ := NewSigner([]byte("other")).New()
+
+	for _, bad := range []string{
+		"",
+		id,
+		id + ".",
+		"0123456789abcdef0123456789abcdef" + token[32:],
+		forged,
+		token + "x",
+	} {
+		_, err := s.Verify(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	assert.Equal(t, "", FromRequest(c))
+
+	c.Request.AddCookie(&http.Cookie{Name: CookieName, Value: "from-cookie"})
+	assert.Equal(t, "from-cookie", FromRequest(c))
+
+	c.Request.Header.Set(Header, "from-header")
+	assert.Equal(t, "from-header", FromRequest(c))
+}
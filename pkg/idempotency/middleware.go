@@ -0,0 +1,211 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/guest"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Header carries the client's key for a request it may retry.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultTTL is how long keys are kept by default.
+	DefaultTTL = 24 * time.Hour
+
+	maxKeyLength = 255
+	// MaxBodyBytes is the largest request body kept for comparing retries.
+	MaxBodyBytes = 1 << 20
+)
+
+const (
+	CodeKeyReused    = "idempotency_key_reused"
+	CodeKeyInUse     = "idempotency_key_in_use"
+	CodeBodyTooLarge = "request_body_too_large"
+)
+
+// Middleware makes mutating requests that carry an Idempotency-Key safe to
+// retry. The first request with a key is handled as usual and its response
+// is kept for ttl; later requests with the same key and the same method,
+// path and body receive that response again without being handled. Keys
+// belong to the route and the caller, so different callers and endpoints
+// cannot see each other's responses; requests that name no caller are
+// handled without a key, as nothing would tell callers' keys apart. Reusing
+// a key for a different request is refused, as are retries that arrive
+// while the first request is still being handled. Server errors and panics
+// are not kept, so that the request can be retried.
+func Middleware(store Store, ttl time.Duration, tokens *guest.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" || !mutating(c.Request.Method) || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			api.Error(c, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "request body must be at most 1 MiB")
+			return
+		} else if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "could not read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		who := caller(c, tokens, body)
+		if who == "" {
+			c.Next()
+			return
+		}
+		hash := requestHash(c.Request, body)
+		key = scopedKey(c.Request.Method, c.FullPath(), who, key)
+
+		// The key is given up or completed even if the client goes away.
+		ctx := context.WithoutCancel(c.Request.Context())
+		rec, err := store.Reserve(ctx, key, hash, time.Now(), ttl)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if rec != nil {
+			replay(c, rec, hash)
+			return
+		}
+
+		w := &recorder{ResponseWriter: c.Writer}
+		c.Writer = w
+		completed := false
+		defer func() {
+			// A panicking handler leaves the key to be retried.
+			if !completed {
+				if err := store.Release(ctx, key); err != nil {
+					_ = c.Error(err)
+				}
+			}
+		}()
+		c.Next()
+
+		if w.Status() < http.StatusInternalServerError {
+			err = store.Complete(ctx, key, Response{
+				Status: w.Status(),
+				Header: contentHeaders(w.Header()),
+				Body:   w.body.Bytes(),
+			})
+			if err != nil {
+				_ = c.Error(err)
+			}
+			completed = true
+		}
+	}
+}
+
+func replay(c *gin.Context, rec *Record, hash string) {
+	switch {
+	case rec.RequestHash != hash:
+		api.Error(c, http.StatusUnprocessableEntity, CodeKeyReused, "Idempotency-Key was already used for a different request")
+	case rec.Status == 0:
+		api.Error(c, http.StatusConflict, CodeKeyInUse, "a request with this Idempotency-Key is still being handled")
+	default:
+		for name, values := range contentHeaders(rec.Header) {
+			for _, v := range values {
+				c.Writer.Header().Add(name, v)
+			}
+		}
+		c.Header(ReplayedHeader, "true")
+		c.Writer.WriteHeader(rec.Status)
+		_, _ = c.Writer.Write(rec.Body)
+		c.Abort()
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// caller identifies who sent the request: the user named in its path or
+// JSON body, or else the guest whose token it carries. It returns "" when
+// the request names neither.
+func caller(c *gin.Context, tokens *guest.Signer, body []byte) string {
+	if id := c.Param("user_id"); id != "" {
+		return "user:" + id
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) == nil {
+		for name, value := range fields {
+			// v1 bodies spell it UserID.
+			if strings.EqualFold(strings.ReplaceAll(name, "_", ""), "userid") {
+				return "user:" + string(value)
+			}
+		}
+	}
+	if id, err := tokens.Verify(guest.FromRequest(c)); err == nil {
+		return "guest:" + id
+	}
+	return ""
+}
+
+// scopedKey is the key as stored: the client's key within its method,
+// route and caller.
+func scopedKey(method, route, caller, key string) string {
+	h := sha256.New()
+	io.WriteString(h, method+"\n"+route+"\n"+caller+"\n"+key)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentHeaders returns the headers describing the body, the only ones
+// replayed; others, such as Set-Cookie, belong to the first response alone.
+func contentHeaders(header http.Header) http.Header {
+	kept := make(http.Header)
+	for name, values := range header {
+		if strings.HasPrefix(http.CanonicalHeaderKey(name), "Content-") {
+			kept[name] = values
+		}
+	}
+	return kept
+}
+
+// requestHash identifies a request by its method, path, query and body.
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+"\n"+req.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body as it is written.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,257 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/guest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore keeps keys in memory and never expires them.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]*Record{}}
+}
+
+func (m *memoryStore) Reserve(_ context.Context, key, hash string, _ time.Time, _ time.Duration) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rec, ok := m.records[key]; ok {
+		copied := *rec
+		return &copied, nil
+	}
+	m.records[key] = &Record{RequestHash: hash}
+	return nil, nil
+}
+
+func (m *memoryStore) Complete(_ context.Context, key string, resp Response) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec := m.records[key]
+	rec.Status, rec.Header, rec.Body = resp.Status, resp.Header, resp.Body
+	return nil
+}
+
+func (m *memoryStore) Release(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.records[key].Status == 0 {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+func (m *memoryStore) Purge(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+var testTokens = guest.NewSigner([]byte("test-key"))
+
+func setupRouter(store Store, status *int) (*gin.Engine, *int) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware(store, time.Hour, testTokens))
+	handler := func(c *gin.Context) {
+		calls++
+		if *status == http.StatusTeapot {
+			panic("handler failed")
+		}
+		c.Header("Set-Cookie", "session=first")
+		c.Header("Content-Language", "en")
+		c.JSON(*status, gin.H{"call": calls})
+	}
+	router.POST("/cart", handler)
+	router.GET("/cart", handler)
+	router.POST("/cart/:user_id", handler)
+	router.POST("/guest/cart", handler)
+	return router, &calls
+}
+
+func serve(router *gin.Engine, method, key, body string) *httptest.ResponseRecorder {
+	return servePath(router, method, "/cart", key, body)
+}
+
+func servePath(router *gin.Engine, method, path, key, body string) *httptest.ResponseRecorder {
+	return serveAs(router, method, path, "", key, body)
+}
+
+func serveAs(router *gin.Engine, method, path, guestToken, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if guestToken != "" {
+		req.Header.Set(guest.Header, guestToken)
+	}
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMiddleware_ReplaysResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusCreated
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	first := serve(router, "POST", "abc", `{"user_id":1,"movie_id":2}`)
+	second := serve(router, "POST", "abc", `{"user_id":1,"movie_id":2}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", second.Header().Get("Content-Type"))
+	assert.Equal(t, "en", second.Header().Get("Content-Language"))
+	assert.Empty(t, second.Header().Get("Set-Cookie"))
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_RejectsReuse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusCreated
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	serve(router, "POST", "abc", `{"user_id":1,"movie_id":2}`)
+	recorder := serve(router, "POST", "abc", `{"user_id":1,"movie_id":3}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeKeyReused)
+}
+
+func TestMiddleware_InFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryStore()
+	store.records[scopedKey("POST", "/cart", "user:1", "abc")] = &Record{RequestHash: requestHash(httptest.NewRequest("POST", "/cart", nil), []byte(`{"user_id":1}`))}
+	status := http.StatusCreated
+	router, calls := setupRouter(store, &status)
+
+	recorder := serve(router, "POST", "abc", `{"user_id":1}`)
+
+	assert.Equal(t, 0, *calls)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeKeyInUse)
+}
+
+func TestMiddleware_ServerErrorsAreRetried(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusInternalServerError
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	serve(router, "POST", "abc", `{"user_id":1}`)
+	status = http.StatusConflict
+	serve(router, "POST", "abc", `{"user_id":1}`)
+	recorder := serve(router, "POST", "abc", `{"user_id":1}`)
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, "true", recorder.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_PassesThrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusOK
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	serve(router, "POST", "", `{}`)
+	serve(router, "POST", "", `{}`)
+	serve(router, "GET", "abc", "")
+	serve(router, "GET", "abc", "")
+
+	assert.Equal(t, 4, *calls)
+}
+
+func TestMiddleware_KeyTooLong(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusOK
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	recorder := serve(router, "POST", strings.Repeat("k", 256), `{}`)
+
+	assert.Equal(t, 0, *calls)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestMiddleware_ScopesKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusCreated
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	servePath(router, "POST", "/cart/1", "abc", `{}`)
+	other := servePath(router, "POST", "/cart/2", "abc", `{}`)
+	route := servePath(router, "POST", "/cart", "abc", `{"user_id":1}`)
+
+	assert.Equal(t, 3, *calls)
+	assert.Empty(t, other.Header().Get(ReplayedHeader))
+	assert.Empty(t, route.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_PanicReleasesKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryStore()
+	status := http.StatusTeapot
+	router, calls := setupRouter(store, &status)
+
+	assert.Panics(t, func() { serve(router, "POST", "abc", `{"user_id":1}`) })
+	assert.Empty(t, store.records)
+	status = http.StatusCreated
+	recorder := serve(router, "POST", "abc", `{"user_id":1}`)
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+}
+
+func TestMiddleware_BodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusOK
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	recorder := serve(router, "POST", "abc", strings.Repeat("x", MaxBodyBytes+1))
+
+	assert.Equal(t, 0, *calls)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeBodyTooLarge)
+}
+
+func TestMiddleware_ScopesKeysToGuests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusCreated
+	router, calls := setupRouter(newMemoryStore(), &status)
+	first, _, _ := testTokens.New()
+	second, _, _ := testTokens.New()
+
+	serveAs(router, "POST", "/guest/cart", first, "abc", `{"movie_id":2}`)
+	other := serveAs(router, "POST", "/guest/cart", second, "abc", `{"movie_id":2}`)
+	retry := serveAs(router, "POST", "/guest/cart", first, "abc", `{"movie_id":2}`)
+
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, other.Header().Get(ReplayedHeader))
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_NoCallerIsNotReplayed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := http.StatusCreated
+	router, calls := setupRouter(newMemoryStore(), &status)
+
+	serveAs(router, "POST", "/guest/cart", "", "abc", `{"movie_id":2}`)
+	recorder := serveAs(router, "POST", "/guest/cart", "forged", "abc", `{"movie_id":2}`)
+
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, recorder.Header().Get(ReplayedHeader))
+}
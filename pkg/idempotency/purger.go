@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Purger periodically deletes expired keys along with their stored
+// responses.
+type Purger struct {
+	store    Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewPurger(store Store, interval time.Duration) *Purger {
+	return &Purger{store: store, interval: interval, now: time.Now}
+}
+
+// Run purges immediately and then once per interval until ctx is
+// cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) Purge(ctx context.Context) {
+	n, err := p.store.Purge(ctx, p.now())
+	if err != nil {
+		log.Printf("idempotency: purge keys: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("idempotency: purged %d keys", n)
+	}
+}
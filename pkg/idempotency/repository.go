@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// lockTimeout is how long a request may hold its key without completing
+// before the key is considered abandoned, say by a crashed server, and a
+// retry may claim it.
+const lockTimeout = time.Minute
+
+// Record is what is stored under a key.
+type Record struct {
+	RequestHash string
+	// Status is 0 while the first request is still being handled.
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Response is a response to store for replay.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type Store interface {
+	// Reserve claims key for a request with hash until now+ttl. If someone
+	// else holds the key, it returns their record instead and claims
+	// nothing. Expired keys are claimed anew.
+	Reserve(ctx context.Context, key, hash string, now time.Time, ttl time.Duration) (*Record, error)
+	// Complete stores the response to the request holding key.
+	Complete(ctx context.Context, key string, resp Response) error
+	// Release gives key up so that the request can be retried.
+	Release(ctx context.Context, key string) error
+	// Purge deletes the keys that expired before now and returns how many.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) Store {
+	return &store{db: db}
+}
+
+func (s *store) Reserve(ctx context.Context, key, hash string, now time.Time, ttl time.Duration) (*Record, error) {
+	for attempt := 0; ; attempt++ {
+		var claimed string
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO idempotency_keys (key, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status = NULL, headers = NULL, body = NULL,
+				created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= $3
+				OR (idempotency_keys.status IS NULL AND idempotency_keys.created_at <= $5)
+			RETURNING key`, key, hash, now, now.Add(ttl), now.Add(-lockTimeout)).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		rec, err := s.find(ctx, key)
+		// The holder may have released the key in between; try once more
+		// to claim it.
+		if err == sql.ErrNoRows && attempt == 0 {
+			continue
+		}
+		return rec, err
+	}
+}
+
+func (s *store) find(ctx context.Context, key string) (*Record, error) {
+	var rec Record
+	var status sql.NullInt64
+	var header []byte
+	err := s.db.QueryRowContext(ctx,
+		"SELECT request_hash, status, headers, body FROM idempotency_keys WHERE key = $1", key).
+		Scan(&rec.RequestHash, &status, &header, &rec.Body)
+	if err != nil {
+		return nil, err
+	}
+	rec.Status = int(status.Int64)
+	if header != nil {
+		if err := json.Unmarshal(header, &rec.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &rec, nil
+}
+
+func (s *store) Complete(ctx context.Context, key string, resp Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status = $2, headers = $3, body = $4 WHERE key = $1",
+		key, resp.Status, header, resp.Body)
+	return err
+}
+
+func (s *store) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND status IS NULL", key)
+	return err
+}
+
+func (s *store) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserve_Claims(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO idempotency_keys \(key, request_hash, created_at, expires_at\) VALUES (.+) ON CONFLICT \(key\) DO UPDATE (.+) RETURNING key`).
+		WithArgs("abc", "hash", now, now.Add(time.Hour), now.Add(-lockTimeout)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("abc"))
+
+	rec, err := NewStore(db).Reserve(context.Background(), "abc", "hash", now, time.Hour)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserve_ReturnsExisting(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT request_hash, status, headers, body FROM idempotency_keys WHERE key = \$1`).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "status", "headers", "body"}).
+			AddRow("hash", 201, []byte(`{"Content-Type":["application/json"]}`), []byte(`{}`)))
+
+	rec, err := NewStore(db).Reserve(context.Background(), "abc", "hash", time.Now(), time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, &Record{
+		RequestHash: "hash",
+		Status:      201,
+		Header:      http.Header{"Content-Type": {"application/json"}},
+		Body:        []byte(`{}`),
+	}, rec)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserve_RetriesReleasedKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT request_hash`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("abc"))
+
+	rec, err := NewStore(db).Reserve(context.Background(), "abc", "hash", time.Now(), time.Hour)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComplete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE idempotency_keys SET status = \$2, headers = \$3, body = \$4 WHERE key = \$1`).
+		WithArgs("abc", 201, []byte(`{"Location":["/cart/1"]}`), []byte(`{}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewStore(db).Complete(context.Background(), "abc", Response{
+		Status: 201,
+		Header: http.Header{"Location": {"/cart/1"}},
+		Body:   []byte(`{}`),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseAndPurge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE key = \$1 AND status IS NULL`).
+		WithArgs("abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE expires_at <= \$1`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	store := NewStore(db)
+	assert.NoError(t, store.Release(context.Background(), "abc"))
+	n, err := store.Purge(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,201 @@
+package movies
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats is a snapshot of the cache counters.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// CachedMovieRepository is a MovieRepository that serves GetMovieByID from an
+// in-process LRU cache, falling back to the wrapped repository on a miss.
+type CachedMovieRepository struct {
+	next     MovieRepository
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	// calls holds the in-flight lookup for each id. Invalidating an id takes
+	// its lookup out, which tells the lookup not to cache what it read.
+	calls map[string]*call
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type cacheEntry struct {
+	id        string
+	movie     *Movie
+	expiresAt time.Time
+}
+
+// call is an in-flight lookup that concurrent misses for the same id wait on.
+type call struct {
+	done  chan struct{}
+	movie *Movie
+	err   error
+}
+
+func NewCachedMovieRepository(next MovieRepository, capacity int, ttl time.Duration) *CachedMovieRepository {
+	return &CachedMovieRepository{
+		next:     next,
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		calls:    make(map[string]*call),
+	}
+}
+
+func (r *CachedMovieRepository) ListMovies(ctx context.Context, filter MovieFilter, fields []string) ([]Movie, error) {
+	return r.next.ListMovies(ctx, filter, fields)
+}
+
+func (r *CachedMovieRepository) ListSimilarMovies(ctx context.Context, id, region string, limit int) ([]Movie, error) {
+	return r.next.ListSimilarMovies(ctx, id, region, limit)
+}
+
+func (r *CachedMovieRepository) ListNewReleases(ctx context.Context, withinDays int, region string) ([]Movie, error) {
+	return r.next.ListNewReleases(ctx, withinDays, region)
+}
+
+func (r *CachedMovieRepository) ListComingSoon(ctx context.Context, region string) ([]Movie, error) {
+	return r.next.ListComingSoon(ctx, region)
+}
+
+func (r *CachedMovieRepository) ListLicenses(ctx context.Context, movieID string) ([]License, error) {
+	return r.next.ListLicenses(ctx, movieID)
+}
+
+func (r *CachedMovieRepository) SetLicense(ctx context.Context, movieID string, l License) error {
+	defer r.Invalidate(movieID)
+	return r.next.SetLicense(ctx, movieID, l)
+}
+
+func (r *CachedMovieRepository) DeleteLicense(ctx context.Context, movieID, region string) error {
+	defer r.Invalidate(movieID)
+	return r.next.DeleteLicense(ctx, movieID, region)
+}
+
+func (r *CachedMovieRepository) GetMovieByID(ctx context.Context, id string) (*Movie, error) {
+	r.mu.Lock()
+	if el, ok := r.entries[id]; ok {
+		entry := el.Value.(*cacheEntry)
+		if r.now().Before(entry.expiresAt) {
+			r.order.MoveToFront(el)
+			r.mu.Unlock()
+			atomic.AddUint64(&r.hits, 1)
+			return copyMovie(entry.movie), nil
+		}
+		r.removeElement(el)
+	}
+	atomic.AddUint64(&r.misses, 1)
+
+	if c, ok := r.calls[id]; ok {
+		r.mu.Unlock()
+		select {
+		case <-c.done:
+			return copyMovie(c.movie), c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c := &call{done: make(chan struct{})}
+	r.calls[id] = c
+	r.mu.Unlock()
+
+	// The lookup is detached from the caller's context so that one cancelled
+	// request does not fail every other request waiting on the same id.
+	c.movie, c.err = r.next.GetMovieByID(context.WithoutCancel(ctx), id)
+
+	r.mu.Lock()
+	// A lookup that was invalidated while in flight may have read the old
+	// row, so only a lookup still registered for id is cached.
+	if r.calls[id] == c {
+		delete(r.calls, id)
+		if c.err == nil && c.movie != nil {
+			r.store(id, c.movie)
+		}
+	}
+	r.mu.Unlock()
+	close(c.done)
+
+	return copyMovie(c.movie), c.err
+}
+
+// Invalidate drops the cached entry for id. It must be called after any write
+// to the movie so that readers do not see stale data until the TTL expires.
+func (r *CachedMovieRepository) Invalidate(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Later misses must not wait on a lookup that may have read the old row.
+	delete(r.calls, id)
+	if el, ok := r.entries[id]; ok {
+		r.removeElement(el)
+	}
+}
+
+// Purge drops every cached entry.
+func (r *CachedMovieRepository) Purge() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = make(map[string]*call)
+	r.entries = make(map[string]*list.Element)
+	r.order.Init()
+}
+
+func (r *CachedMovieRepository) Stats() CacheStats {
+	r.mu.Lock()
+	size := r.order.Len()
+	r.mu.Unlock()
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&r.hits),
+		Misses:    atomic.LoadUint64(&r.misses),
+		Evictions: atomic.LoadUint64(&r.evictions),
+		Size:      size,
+	}
+}
+
+func (r *CachedMovieRepository) store(id string, m *Movie) {
+	entry := &cacheEntry{id: id, movie: m, expiresAt: r.now().Add(r.ttl)}
+	if el, ok := r.entries[id]; ok {
+		el.Value = entry
+		r.order.MoveToFront(el)
+		return
+	}
+	r.entries[id] = r.order.PushFront(entry)
+	for r.capacity > 0 && r.order.Len() > r.capacity {
+		r.removeElement(r.order.Back())
+		atomic.AddUint64(&r.evictions, 1)
+	}
+}
+
+func (r *CachedMovieRepository) removeElement(el *list.Element) {
+	r.order.Remove(el)
+	delete(r.entries, el.Value.(*cacheEntry).id)
+}
+
+// copyMovie keeps callers from mutating the cached value.
+func copyMovie(m *Movie) *Movie {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.Licenses = append([]License(nil), m.Licenses...)
+	return &c
+}
@@ -0,0 +1,214 @@
+package movies
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func countingRepository(calls *int32) *mockMovieRepository {
+	return &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			atomic.AddInt32(calls, 1)
+			return &Movie{MovieID: 1, Title: "Movie " + id}, nil
+		},
+	}
+}
+
+func TestCachedMovieRepository_Hit(t *testing.T) {
+	var calls int32
+	repo := NewCachedMovieRepository(countingRepository(&calls), 10, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		movie, err := repo.GetMovieByID(context.Background(), "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Movie 1", movie.Title)
+	}
+
+	assert.Equal(t, int32(1), calls)
+	stats := repo.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestCachedMovieRepository_Expiry(t *testing.T) {
+	var calls int32
+	repo := NewCachedMovieRepository(countingRepository(&calls), 10, time.Minute)
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+
+	_, _ = repo.GetMovieByID(context.Background(), "1")
+	now = now.Add(2 * time.Minute)
+	_, _ = repo.GetMovieByID(context.Background(), "1")
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCachedMovieRepository_EvictsLeastRecentlyUsed(t *testing.T) {
+	var calls int32
+	repo := NewCachedMovieRepository(countingRepository(&calls), 2, time.Minute)
+	ctx := context.Background()
+
+	_, _ = repo.GetMovieByID(ctx, "1")
+	_, _ = repo.GetMovieByID(ctx, "2")
+	_, _ = repo.GetMovieByID(ctx, "1")
+	_, _ = repo.GetMovieByID(ctx, "3")
+	assert.Equal(t, int32(3), calls)
+
+	_, _ = repo.GetMovieByID(ctx, "1")
+	assert.Equal(t, int32(3), calls)
+	_, _ = repo.GetMovieByID(ctx, "2")
+	assert.Equal(t, int32(4), calls)
+	assert.Equal(t, 2, repo.Stats().Size)
+}
+
+func TestCachedMovieRepository_Invalidate(t *testing.T) {
+	var calls int32
+	repo := NewCachedMovieRepository(countingRepository(&calls), 10, time.Minute)
+
+	_, _ = repo.GetMovieByID(context.Background(), "1")
+	repo.Invalidate("1")
+	_, _ = repo.GetMovieByID(context.Background(), "1")
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCachedMovieRepository_InvalidateDuringFetch(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	next := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+				<-release
+				return &Movie{MovieID: 1, Title: "Old title"}, nil
+			}
+			return &Movie{MovieID: 1, Title: "New title"}, nil
+		},
+	}
+	repo := NewCachedMovieRepository(next, 10, time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		movie, err := repo.GetMovieByID(context.Background(), "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Old title", movie.Title)
+	}()
+	<-started
+	repo.Invalidate("1")
+	close(release)
+	<-done
+
+	movie, err := repo.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "New title", movie.Title)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCachedMovieRepository_InvalidateOtherDuringFetch(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	next := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+				<-release
+			}
+			return &Movie{MovieID: 2, Title: "Movie 2"}, nil
+		},
+	}
+	repo := NewCachedMovieRepository(next, 10, time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := repo.GetMovieByID(context.Background(), "2")
+		assert.NoError(t, err)
+	}()
+	<-started
+	repo.Invalidate("1")
+	close(release)
+	<-done
+
+	_, err := repo.GetMovieByID(context.Background(), "2")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestCachedMovieRepository_CollapsesConcurrentMisses(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	next := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return &Movie{MovieID: 1, Title: "Movie 1"}, nil
+		},
+	}
+	repo := NewCachedMovieRepository(next, 10, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			movie, err := repo.GetMovieByID(context.Background(), "1")
+			assert.NoError(t, err)
+			assert.Equal(t, "Movie 1", movie.Title)
+		}()
+	}
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestCachedMovieRepository_DoesNotCacheMissesOrErrors(t *testing.T) {
+	var calls int32
+	next := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			n := atomic.AddInt32(&calls, 1)
+			if n == 1 {
+				return nil, errors.New("db failure")
+			}
+			return nil, nil
+		},
+	}
+	repo := NewCachedMovieRepository(next, 10, time.Minute)
+
+	_, err := repo.GetMovieByID(context.Background(), "1")
+	assert.Error(t, err)
+	movie, err := repo.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Nil(t, movie)
+	_, _ = repo.GetMovieByID(context.Background(), "1")
+
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestCachedMovieRepository_LicenseWritesInvalidate(t *testing.T) {
+	var calls int32
+	next := countingRepository(&calls)
+	next.SetLicenseFunc = func(movieID string, l License) error { return nil }
+	next.DeleteLicenseFunc = func(movieID, region string) error { return nil }
+	repo := NewCachedMovieRepository(next, 10, time.Minute)
+	ctx := context.Background()
+
+	_, _ = repo.GetMovieByID(ctx, "1")
+	assert.NoError(t, repo.SetLicense(ctx, "1", License{Region: "GB"}))
+	_, _ = repo.GetMovieByID(ctx, "1")
+	assert.NoError(t, repo.DeleteLicense(ctx, "1", "GB"))
+	_, _ = repo.GetMovieByID(ctx, "1")
+
+	assert.Equal(t, int32(3), calls)
+}
@@ -0,0 +1,44 @@
+package movies
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MovieResponse is the v2 representation of a Movie.
+type MovieResponse struct {
+	XMLName xml.Name `json:"-" xml:"movie"`
+	MovieID int      `json:"movie_id" xml:"movie_id"`
+	Title   string   `json:"title" xml:"title"`
+	Year    int      `json:"year" xml:"year"`
+	Plot    string   `json:"plot" xml:"plot"`
+	Genre   string   `json:"genre" xml:"genre"`
+	ImdbID  string   `json:"imdb_id" xml:"imdb_id"`
+	Actors  string   `json:"actors" xml:"actors"`
+
+	AvailableFrom  *time.Time `json:"available_from" xml:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until" xml:"available_until,omitempty"`
+}
+
+func NewMovieResponse(m Movie) MovieResponse {
+	return MovieResponse{
+		MovieID: m.MovieID,
+		Title:   m.Title,
+		Year:    m.Year,
+		Plot:    m.Plot,
+		Genre:   m.Genre,
+		ImdbID:  m.ImdbID,
+		Actors:  m.Actors,
+
+		AvailableFrom:  m.AvailableFrom,
+		AvailableUntil: m.AvailableUntil,
+	}
+}
+
+func NewMovieResponses(ms []Movie) []MovieResponse {
+	out := make([]MovieResponse, len(ms))
+	for i, m := range ms {
+		out[i] = NewMovieResponse(m)
+	}
+	return out
+}
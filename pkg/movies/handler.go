@@ -1,40 +1,165 @@
 package movies
 
 import (
+    "errors"
+    "fmt"
     "net/http"
+    "reflect"
+    "strconv"
+    "strings"
+    "time"
+    "movie-rental/pkg/api"
+    "movie-rental/pkg/region"
     "github.com/gin-gonic/gin"
 )
 
+const similarMoviesLimit = 10
+
+// v1Fields are the fields v1 clients may request; later columns are only
+// exposed through v2.
+var v1Fields = movieColumns[:7]
+
+// supportedIncludes lists the related resources GET /movies/:id can embed.
+var supportedIncludes = []string{"reviews", "similar"}
+
 func ListMoviesHandler(repo MovieRepository) gin.HandlerFunc {
     return func(c *gin.Context) {
         genre := c.Query("genre")
         actor := c.Query("actor")
         year := c.Query("year")
+        fields, err := parseList(c.Query("fields"), v1Fields, "field")
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        userRegion, err := RequestRegion(c)
+        if errors.Is(err, ErrInvalidUserID) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        } else if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
 
-        movies, err := repo.ListMovies(c.Request.Context(), genre, actor, year)
+        movies, err := repo.ListMovies(c.Request.Context(), MovieFilter{Genre: genre, Actor: actor, Year: year, Region: userRegion}, fields)
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
             return
         }
+        if len(fields) == 0 {
+            api.RespondList(c, http.StatusOK, movies, movies)
+            return
+        }
 
-        c.JSON(http.StatusOK, movies)
+        projected := make([]gin.H, len(movies))
+        for i := range movies {
+            projected[i] = projectMovie(&movies[i], fields)
+        }
+        api.RespondList(c, http.StatusOK, projected, projected)
     }
 }
 
 func GetMovieByIDHandler(repo MovieRepository) gin.HandlerFunc {
     return func(c *gin.Context) {
         id := c.Param("id")
+        includes, err := parseList(c.Query("include"), supportedIncludes, "include")
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        userRegion, err := RequestRegion(c)
+        if errors.Is(err, ErrInvalidUserID) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        } else if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
 
         movie, err := repo.GetMovieByID(c.Request.Context(), id)
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
             return
         }
-        if movie == nil {
+        if movie == nil || !movie.LicensedIn(userRegion, time.Now()) {
             c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
             return
         }
+        if len(includes) == 0 {
+            api.Respond(c, http.StatusOK, movie)
+            return
+        }
+
+        resp := projectMovie(movie, v1Fields)
+        for _, include := range includes {
+            switch include {
+            case "similar":
+                similar, err := repo.ListSimilarMovies(c.Request.Context(), id, userRegion, similarMoviesLimit)
+                if err != nil {
+                    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                    return
+                }
+                if similar == nil {
+                    similar = []Movie{}
+                }
+                resp["similar"] = similar
+            case "reviews":
+                resp["reviews"] = noReviews()
+            }
+        }
+
+        api.Respond(c, http.StatusOK, resp)
+    }
+}
+
+// noReviews is what include=reviews embeds until movies can be reviewed.
+func noReviews() []gin.H {
+    return []gin.H{}
+}
+
+var ErrInvalidUserID = errors.New("user_id must be an integer")
+
+// RequestRegion resolves the region of a catalog request, which may name the
+// browsing user with the user_id query parameter. It fails with
+// ErrInvalidUserID if user_id is not an integer.
+func RequestRegion(c *gin.Context) (string, error) {
+    var userID int
+    if raw := c.Query("user_id"); raw != "" {
+        var err error
+        if userID, err = strconv.Atoi(raw); err != nil {
+            return "", ErrInvalidUserID
+        }
+    }
+    return region.Resolve(c, userID)
+}
+
+// parseList splits a comma-separated query value and rejects any entry that
+// is not in allowed.
+func parseList(raw string, allowed []string, kind string) ([]string, error) {
+    if raw == "" {
+        return nil, nil
+    }
+    var list []string
+    for _, v := range strings.Split(raw, ",") {
+        v = strings.TrimSpace(v)
+        if v == "" {
+            continue
+        }
+        if !contains(allowed, v) {
+            return nil, fmt.Errorf("unsupported %s %q", kind, v)
+        }
+        list = append(list, v)
+    }
+    return list, nil
+}
 
-        c.JSON(http.StatusOK, movie)
+// projectMovie renders only the requested fields of m, keyed the same way as
+// the full Movie response. MovieID is always present.
+func projectMovie(m *Movie, fields []string) gin.H {
+    out := gin.H{"MovieID": m.MovieID}
+    for _, f := range fields {
+        key, ptr := movieField(m, f)
+        out[key] = reflect.ValueOf(ptr).Elem().Interface()
     }
+    return out
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package movies
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListLicensesHandler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := strconv.Atoi(id); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie id must be an integer")
+			return
+		}
+
+		licenses, err := repo.ListLicenses(c.Request.Context(), id)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if licenses == nil {
+			licenses = []License{}
+		}
+
+		api.List(c, licenses, len(licenses))
+	}
+}
+
+// SetLicenseHandler creates or replaces a movie's license window in one
+// region.
+func SetLicenseHandler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, territory, ok := licenseParams(c)
+		if !ok {
+			return
+		}
+		var req LicenseRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "licensed_from and licensed_until must be RFC 3339 timestamps")
+			return
+		}
+		if req.LicensedFrom != nil && req.LicensedUntil != nil && !req.LicensedUntil.After(*req.LicensedFrom) {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "licensed_until must be after licensed_from")
+			return
+		}
+
+		license := License{Region: territory, LicensedFrom: req.LicensedFrom, LicensedUntil: req.LicensedUntil}
+		err := repo.SetLicense(c.Request.Context(), id, license)
+		switch {
+		case errors.Is(err, ErrMovieNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			api.OK(c, http.StatusOK, license)
+		}
+	}
+}
+
+func DeleteLicenseHandler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, territory, ok := licenseParams(c)
+		if !ok {
+			return
+		}
+
+		err := repo.DeleteLicense(c.Request.Context(), id, territory)
+		switch {
+		case errors.Is(err, ErrLicenseNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+func licenseParams(c *gin.Context) (string, string, bool) {
+	id := c.Param("id")
+	if _, err := strconv.Atoi(id); err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie id must be an integer")
+		return "", "", false
+	}
+	territory, err := region.Normalize(c.Param("region"))
+	if err != nil || territory == "" {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, region.ErrInvalidRegion.Error())
+		return "", "", false
+	}
+	return id, territory, true
+}
@@ -0,0 +1,121 @@
+package movies
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupLicenseRouter(repo MovieRepository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/admin/movies/:id/territories", ListLicensesHandler(repo))
+	router.PUT("/v2/admin/movies/:id/territories/:region", SetLicenseHandler(repo))
+	router.DELETE("/v2/admin/movies/:id/territories/:region", DeleteLicenseHandler(repo))
+	return router
+}
+
+func serveLicense(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestListLicensesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListLicensesFunc: func(movieID string) ([]License, error) {
+			assert.Equal(t, "1", movieID)
+			return []License{{Region: "US"}}, nil
+		},
+	}
+
+	recorder := serveLicense(setupLicenseRouter(repo), "GET", "/v2/admin/movies/1/territories", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":[{"region":"US","licensed_from":null,"licensed_until":null}],"meta":{"api_version":"v2","count":1}}`,
+		recorder.Body.String())
+}
+
+func TestSetLicenseHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		SetLicenseFunc: func(movieID string, l License) error {
+			assert.Equal(t, "1", movieID)
+			assert.Equal(t, "GB", l.Region)
+			assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), l.LicensedFrom.UTC())
+			assert.Nil(t, l.LicensedUntil)
+			return nil
+		},
+	}
+
+	recorder := serveLicense(setupLicenseRouter(repo), "PUT", "/v2/admin/movies/1/territories/gb",
+		`{"licensed_from":"2026-11-01T00:00:00Z"}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"region":"GB"`)
+}
+
+func TestSetLicenseHandler_Invalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupLicenseRouter(&mockMovieRepository{})
+
+	for _, tc := range []struct{ path, body string }{
+		{"/v2/admin/movies/abc/territories/GB", `{}`},
+		{"/v2/admin/movies/1/territories/GBR", `{}`},
+		{"/v2/admin/movies/1/territories/GB", `{"licensed_from":"tomorrow"}`},
+		{"/v2/admin/movies/1/territories/GB", `{"licensed_from":"2027-01-01T00:00:00Z","licensed_until":"2026-01-01T00:00:00Z"}`},
+	} {
+		recorder := serveLicense(router, "PUT", tc.path, tc.body)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, tc.path+" "+tc.body)
+	}
+}
+
+func TestSetLicenseHandler_MovieNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		SetLicenseFunc: func(movieID string, l License) error {
+			return ErrMovieNotFound
+		},
+	}
+
+	recorder := serveLicense(setupLicenseRouter(repo), "PUT", "/v2/admin/movies/99/territories/GB", `{}`)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestDeleteLicenseHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		DeleteLicenseFunc: func(movieID, region string) error {
+			if region == "GB" {
+				return nil
+			}
+			return ErrLicenseNotFound
+		},
+	}
+	router := setupLicenseRouter(repo)
+
+	assert.Equal(t, http.StatusNoContent, serveLicense(router, "DELETE", "/v2/admin/movies/1/territories/GB", "").Code)
+	assert.Equal(t, http.StatusNotFound, serveLicense(router, "DELETE", "/v2/admin/movies/1/territories/FR", "").Code)
+}
+
+func TestDeleteLicenseHandler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		DeleteLicenseFunc: func(movieID, region string) error {
+			return errors.New("db error")
+		},
+	}
+
+	recorder := serveLicense(setupLicenseRouter(repo), "DELETE", "/v2/admin/movies/1/territories/GB", "")
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
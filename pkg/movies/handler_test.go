@@ -3,25 +3,51 @@ package movies
 import (
 	"errors"
     "context"
+    "encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockMovieRepository struct {
-    ListMoviesFunc   func(genre, actor, year string) ([]Movie, error)
-    GetMovieByIDFunc func(id string) (*Movie, error)
+    ListMoviesFunc        func(filter MovieFilter, fields []string) ([]Movie, error)
+    GetMovieByIDFunc      func(id string) (*Movie, error)
+    ListSimilarMoviesFunc func(id, region string, limit int) ([]Movie, error)
+    ListNewReleasesFunc   func(withinDays int, region string) ([]Movie, error)
+    ListComingSoonFunc    func(region string) ([]Movie, error)
+    ListLicensesFunc      func(movieID string) ([]License, error)
+    SetLicenseFunc        func(movieID string, l License) error
+    DeleteLicenseFunc     func(movieID, region string) error
 }
 
-func (m *mockMovieRepository) ListMovies(_ctx context.Context, genre, actor, year string) ([]Movie, error) {
-    return m.ListMoviesFunc(genre, actor, year)
+func (m *mockMovieRepository) ListMovies(_ctx context.Context, filter MovieFilter, fields []string) ([]Movie, error) {
+    return m.ListMoviesFunc(filter, fields)
 }
 func (m *mockMovieRepository) GetMovieByID(_ctx context.Context, id string) (*Movie, error) {
     return m.GetMovieByIDFunc(id)
 }
+func (m *mockMovieRepository) ListSimilarMovies(_ctx context.Context, id, region string, limit int) ([]Movie, error) {
+    return m.ListSimilarMoviesFunc(id, region, limit)
+}
+func (m *mockMovieRepository) ListNewReleases(_ctx context.Context, withinDays int, region string) ([]Movie, error) {
+    return m.ListNewReleasesFunc(withinDays, region)
+}
+func (m *mockMovieRepository) ListComingSoon(_ctx context.Context, region string) ([]Movie, error) {
+    return m.ListComingSoonFunc(region)
+}
+func (m *mockMovieRepository) ListLicenses(_ctx context.Context, movieID string) ([]License, error) {
+    return m.ListLicensesFunc(movieID)
+}
+func (m *mockMovieRepository) SetLicense(_ctx context.Context, movieID string, l License) error {
+    return m.SetLicenseFunc(movieID, l)
+}
+func (m *mockMovieRepository) DeleteLicense(_ctx context.Context, movieID, region string) error {
+    return m.DeleteLicenseFunc(movieID, region)
+}
 
 func setupRouter(repo MovieRepository) *gin.Engine {
     router := gin.Default()
@@ -33,7 +59,7 @@ func setupRouter(repo MovieRepository) *gin.Engine {
 func TestListMoviesHandler_ListAll(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockMovieRepository{
-        ListMoviesFunc: func(genre, actor, year string) ([]Movie, error) {
+        ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
             return []Movie{
                 {MovieID: 1, Title: "Movie 1"},
                 {MovieID: 2, Title: "Movie 2"},
@@ -52,8 +78,8 @@ func TestListMoviesHandler_ListAll(t *testing.T) {
 func TestListMoviesHandler_FilterByGenre(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockMovieRepository{
-        ListMoviesFunc: func(genre, actor, year string) ([]Movie, error) {
-            assert.Equal(t, "Action", genre)
+        ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+            assert.Equal(t, "Action", filter.Genre)
             return []Movie{{MovieID: 1, Title: "Movie 1", Genre: "Action"}}, nil
         },
     }
@@ -69,7 +95,7 @@ func TestListMoviesHandler_FilterByGenre(t *testing.T) {
 func TestListMoviesHandler_DBError(t *testing.T) {
     gin.SetMode(gin.TestMode)
     repo := &mockMovieRepository{
-        ListMoviesFunc: func(genre, actor, year string) ([]Movie, error) {
+        ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
             return nil, errors.New("db error")
         },
     }
@@ -131,4 +157,160 @@ func TestGetMovieByIDHandler_DBError(t *testing.T) {
 
     assert.Equal(t, http.StatusInternalServerError, recorder.Code)
     assert.Contains(t, recorder.Body.String(), "db failure")
-}
\ No newline at end of file
+}
+
+func TestListMoviesHandler_Fields(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockMovieRepository{
+        ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+            assert.Equal(t, []string{"title", "year"}, fields)
+            return []Movie{{MovieID: 1, Title: "Movie 1", Year: 2020}}, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/movies?fields=title,year", nil)
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusOK, recorder.Code)
+    assert.JSONEq(t, `[{"MovieID":1,"Title":"Movie 1","Year":2020}]`, recorder.Body.String())
+}
+
+func TestListMoviesHandler_UnknownField(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    router := setupRouter(&mockMovieRepository{})
+
+    req, _ := http.NewRequest("GET", "/movies?fields=title,budget", nil)
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusBadRequest, recorder.Code)
+    assert.Contains(t, recorder.Body.String(), "budget")
+}
+
+func TestGetMovieByIDHandler_IncludeSimilar(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockMovieRepository{
+        GetMovieByIDFunc: func(id string) (*Movie, error) {
+            return &Movie{MovieID: 1, Title: "Movie 1", Genre: "Action"}, nil
+        },
+        ListSimilarMoviesFunc: func(id, region string, limit int) ([]Movie, error) {
+            assert.Equal(t, "1", id)
+            return []Movie{{MovieID: 2, Title: "Movie 2", Genre: "Action"}}, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/movies/1?include=similar", nil)
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusOK, recorder.Code)
+
+    var resp struct {
+        Title   string
+        Similar []Movie `json:"similar"`
+    }
+    err := json.NewDecoder(recorder.Body).Decode(&resp)
+    assert.NoError(t, err)
+    assert.Equal(t, "Movie 1", resp.Title)
+    assert.Len(t, resp.Similar, 1)
+    assert.Equal(t, "Movie 2", resp.Similar[0].Title)
+}
+
+func TestGetMovieByIDHandler_IncludeSimilarLicensedElsewhere(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    similar := []Movie{
+        {MovieID: 2, Title: "Movie 2", Genre: "Action"},
+        {MovieID: 3, Title: "Movie 3", Genre: "Action", Licenses: []License{{Region: "FR"}}},
+    }
+    repo := &mockMovieRepository{
+        GetMovieByIDFunc: func(id string) (*Movie, error) {
+            return &Movie{MovieID: 1, Title: "Movie 1", Genre: "Action"}, nil
+        },
+        ListSimilarMoviesFunc: func(id, region string, limit int) ([]Movie, error) {
+            assert.Equal(t, "GB", region)
+            var out []Movie
+            for _, m := range similar {
+                if m.LicensedIn(region, time.Now()) {
+                    out = append(out, m)
+                }
+            }
+            return out, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/movies/1?include=similar", nil)
+    req.Header.Set("X-Region", "GB")
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusOK, recorder.Code)
+    assert.Contains(t, recorder.Body.String(), "Movie 2")
+    assert.NotContains(t, recorder.Body.String(), "Movie 3")
+}
+
+func TestGetMovieByIDHandler_UnsupportedInclude(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    router := setupRouter(&mockMovieRepository{})
+
+    req, _ := http.NewRequest("GET", "/movies/1?include=cast", nil)
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetMovieByIDHandler_IncludeReviews(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockMovieRepository{
+        GetMovieByIDFunc: func(id string) (*Movie, error) {
+            return &Movie{MovieID: 1, Title: "Movie 1"}, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/movies/1?include=reviews", nil)
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusOK, recorder.Code)
+    assert.Contains(t, recorder.Body.String(), `"reviews":[]`)
+}
+
+func TestListMoviesHandler_CSV(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockMovieRepository{
+        ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+            return []Movie{{MovieID: 1, Title: "Movie 1", Year: 2020}}, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/movies?fields=title,year", nil)
+    req.Header.Set("Accept", "text/csv")
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusOK, recorder.Code)
+    assert.Equal(t, "MovieID,Title,Year\n1,Movie 1,2020\n", recorder.Body.String())
+}
+
+func TestGetMovieByIDHandler_NotAcceptable(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := &mockMovieRepository{
+        GetMovieByIDFunc: func(id string) (*Movie, error) {
+            return &Movie{MovieID: 1, Title: "Movie 1"}, nil
+        },
+    }
+    router := setupRouter(repo)
+
+    req, _ := http.NewRequest("GET", "/movies/1", nil)
+    req.Header.Set("Accept", "text/csv")
+    recorder := httptest.NewRecorder()
+    router.ServeHTTP(recorder, req)
+
+    assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
@@ -0,0 +1,159 @@
+package movies
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListMoviesV2Handler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fields, err := parseList(c.Query("fields"), movieColumns, "field")
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, err.Error())
+			return
+		}
+		filter := MovieFilter{
+			Genre: c.Query("genre"),
+			Actor: c.Query("actor"),
+			Year:  c.Query("year"),
+			Tag:   c.Query("tag"),
+		}
+		if filter.Region, err = RequestRegion(c); err != nil {
+			RespondRegionError(c, err)
+			return
+		}
+		movies, err := repo.ListMovies(c.Request.Context(), filter, fields)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if len(fields) == 0 {
+			api.List(c, NewMovieResponses(movies), len(movies))
+			return
+		}
+
+		projected := make([]gin.H, len(movies))
+		for i := range movies {
+			projected[i] = projectMovieResponse(&movies[i], fields)
+		}
+		api.List(c, projected, len(projected))
+	}
+}
+
+func NewReleasesHandler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRegion, err := RequestRegion(c)
+		if err != nil {
+			RespondRegionError(c, err)
+			return
+		}
+		movies, err := repo.ListNewReleases(c.Request.Context(), NewReleaseDays, userRegion)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.List(c, NewMovieResponses(movies), len(movies))
+	}
+}
+
+func ComingSoonHandler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRegion, err := RequestRegion(c)
+		if err != nil {
+			RespondRegionError(c, err)
+			return
+		}
+		movies, err := repo.ListComingSoon(c.Request.Context(), userRegion)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.List(c, NewMovieResponses(movies), len(movies))
+	}
+}
+
+func GetMovieByIDV2Handler(repo MovieRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := strconv.Atoi(id); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie id must be an integer")
+			return
+		}
+		includes, err := parseList(c.Query("include"), supportedIncludes, "include")
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		userRegion, err := RequestRegion(c)
+		if err != nil {
+			RespondRegionError(c, err)
+			return
+		}
+
+		movie, err := repo.GetMovieByID(c.Request.Context(), id)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if movie == nil || !movie.LicensedIn(userRegion, time.Now()) {
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, "movie not found")
+			return
+		}
+
+		if len(includes) == 0 {
+			api.OK(c, http.StatusOK, NewMovieResponse(*movie))
+			return
+		}
+
+		resp := projectMovieResponse(movie, movieColumns)
+		for _, include := range includes {
+			switch include {
+			case "similar":
+				similar, err := repo.ListSimilarMovies(c.Request.Context(), id, userRegion, similarMoviesLimit)
+				if err != nil {
+					api.InternalError(c, err)
+					return
+				}
+				resp["similar"] = NewMovieResponses(similar)
+			case "reviews":
+				resp["reviews"] = noReviews()
+			}
+		}
+
+		api.OK(c, http.StatusOK, resp)
+	}
+}
+
+// projectMovieResponse renders only the requested fields of m, keyed the
+// same way as MovieResponse. movie_id is always present.
+func projectMovieResponse(m *Movie, fields []string) gin.H {
+	out := gin.H{"movie_id": m.MovieID}
+	for _, f := range fields {
+		_, ptr := movieField(m, f)
+		key := f
+		if f == "imdbid" {
+			key = "imdb_id"
+		}
+		out[key] = reflect.ValueOf(ptr).Elem().Interface()
+	}
+	return out
+}
+
+// RespondRegionError responds with the error RequestRegion returned.
+func RespondRegionError(c *gin.Context, err error) {
+	if errors.Is(err, ErrInvalidUserID) {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, err.Error())
+		return
+	}
+	api.InternalError(c, err)
+}
@@ -0,0 +1,326 @@
+package movies
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupV2Router(repo MovieRepository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/movies", ListMoviesV2Handler(repo))
+	router.GET("/v2/movies/new-releases", NewReleasesHandler(repo))
+	router.GET("/v2/movies/coming-soon", ComingSoonHandler(repo))
+	router.GET("/v2/movies/:id", GetMovieByIDV2Handler(repo))
+	return router
+}
+
+func TestListMoviesV2Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+			assert.Equal(t, "Action", filter.Genre)
+			return []Movie{{MovieID: 1, Title: "Movie 1", Year: 2020, Genre: "Action", ImdbID: "tt1234567"}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies?genre=Action", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{
+		"data": [{"movie_id":1,"title":"Movie 1","year":2020,"plot":"","genre":"Action","imdb_id":"tt1234567","actors":"","available_from":null,"available_until":null}],
+		"meta": {"api_version":"v2","count":1}
+	}`, recorder.Body.String())
+}
+
+func TestListMoviesV2Handler_FilterByTag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+			assert.Equal(t, MovieFilter{Tag: "date night"}, filter)
+			return nil, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies?tag=date+night", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestListMoviesV2Handler_EmptyList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+			return nil, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"api_version":"v2","count":0}}`, recorder.Body.String())
+}
+
+func TestListMoviesV2Handler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "db error")
+	assert.Contains(t, recorder.Body.String(), "internal_error")
+}
+
+func TestGetMovieByIDV2Handler_Found(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			return &Movie{MovieID: 1, Title: "Movie 1"}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"movie_id":1`)
+	assert.Contains(t, recorder.Body.String(), `"api_version":"v2"`)
+}
+
+func TestGetMovieByIDV2Handler_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			return nil, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/99", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.JSONEq(t, `{"error":{"code":"not_found","message":"movie not found"}}`, recorder.Body.String())
+}
+
+func TestGetMovieByIDV2Handler_InvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockMovieRepository{})
+
+	req, _ := http.NewRequest("GET", "/v2/movies/abc", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetMovieByIDV2Handler_XML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			return &Movie{MovieID: 1, Title: "Movie 1"}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/1", nil)
+	req.Header.Set("Accept", "application/xml")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<response><movie><movie_id>1</movie_id><title>Movie 1</title>")
+}
+
+func TestNewReleasesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	repo := &mockMovieRepository{
+		ListNewReleasesFunc: func(withinDays int, region string) ([]Movie, error) {
+			assert.Equal(t, NewReleaseDays, withinDays)
+			assert.Equal(t, "GB", region)
+			return []Movie{{MovieID: 1, Title: "Movie 1", AvailableFrom: &from}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/new-releases", nil)
+	req.Header.Set("X-Region", "gb")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"available_from":"2026-10-01T00:00:00Z","available_until":null`)
+}
+
+func TestComingSoonHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListComingSoonFunc: func(region string) ([]Movie, error) {
+			assert.Equal(t, "GB", region)
+			return []Movie{{MovieID: 2, Title: "Movie 2"}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/coming-soon", nil)
+	req.Header.Set("X-Region", "GB")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"count":1`)
+}
+
+func TestComingSoonHandler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListComingSoonFunc: func(region string) ([]Movie, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/coming-soon", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestListMoviesV2Handler_RegionHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+			assert.Equal(t, "GB", filter.Region)
+			return nil, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies", nil)
+	req.Header.Set("X-Region", "gb")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestListMoviesV2Handler_InvalidUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupV2Router(&mockMovieRepository{})
+
+	req, _ := http.NewRequest("GET", "/v2/movies?user_id=abc", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetMovieByIDV2Handler_NotLicensedInRegion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			return &Movie{MovieID: 1, Title: "Movie 1", Licenses: []License{{Region: "US"}}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	for region, status := range map[string]int{"US": http.StatusOK, "FR": http.StatusNotFound, "": http.StatusNotFound} {
+		req, _ := http.NewRequest("GET", "/v2/movies/1", nil)
+		req.Header.Set("X-Region", region)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, status, recorder.Code, region)
+	}
+}
+
+func TestListMoviesV2Handler_Fields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		ListMoviesFunc: func(filter MovieFilter, fields []string) ([]Movie, error) {
+			assert.Equal(t, []string{"title", "imdbid"}, fields)
+			return []Movie{{MovieID: 1, Title: "Movie 1", ImdbID: "tt1234567"}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies?fields=title,imdbid", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{
+		"data": [{"movie_id":1,"title":"Movie 1","imdb_id":"tt1234567"}],
+		"meta": {"api_version":"v2","count":1}
+	}`, recorder.Body.String())
+
+	req, _ = http.NewRequest("GET", "/v2/movies?fields=budget", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"code":"invalid_request"`)
+}
+
+func TestGetMovieByIDV2Handler_IncludeSimilar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockMovieRepository{
+		GetMovieByIDFunc: func(id string) (*Movie, error) {
+			return &Movie{MovieID: 1, Title: "Movie 1", Genre: "Drama"}, nil
+		},
+		ListSimilarMoviesFunc: func(id, region string, limit int) ([]Movie, error) {
+			assert.Equal(t, "1", id)
+			assert.Equal(t, similarMoviesLimit, limit)
+			return []Movie{{MovieID: 2, Title: "Movie 2", Genre: "Drama"}}, nil
+		},
+	}
+	router := setupV2Router(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/1?include=similar", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{
+		"data": {"movie_id":1,"title":"Movie 1","year":0,"plot":"","genre":"Drama","imdb_id":"","actors":"","available_from":null,"available_until":null,
+			"similar": [{"movie_id":2,"title":"Movie 2","year":0,"plot":"","genre":"Drama","imdb_id":"","actors":"","available_from":null,"available_until":null}]},
+		"meta": {"api_version":"v2"}
+	}`, recorder.Body.String())
+
+	req, _ = http.NewRequest("GET", "/v2/movies/1?include=reviews", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"reviews":[]`)
+
+	req, _ = http.NewRequest("GET", "/v2/movies/1?include=cast", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,56 @@
+package movies
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// License allows a movie to be shown and rented in one region within
+// [LicensedFrom, LicensedUntil); a nil bound is open.
+type License struct {
+	XMLName       xml.Name   `json:"-" xml:"license"`
+	Region        string     `json:"region" xml:"region"`
+	LicensedFrom  *time.Time `json:"licensed_from" xml:"licensed_from,omitempty"`
+	LicensedUntil *time.Time `json:"licensed_until" xml:"licensed_until,omitempty"`
+}
+
+// LicenseRequest is the body of PUT /v2/admin/movies/:id/territories/:region.
+type LicenseRequest struct {
+	LicensedFrom  *time.Time `json:"licensed_from"`
+	LicensedUntil *time.Time `json:"licensed_until"`
+}
+
+func (l License) activeAt(t time.Time) bool {
+	if l.LicensedFrom != nil && t.Before(*l.LicensedFrom) {
+		return false
+	}
+	if l.LicensedUntil != nil && !t.Before(*l.LicensedUntil) {
+		return false
+	}
+	return true
+}
+
+// LicensedIn reports whether the movie may be offered in region at t. A movie
+// without licenses is unrestricted; otherwise an unknown ("") region never
+// matches.
+func (m Movie) LicensedIn(region string, t time.Time) bool {
+	if len(m.Licenses) == 0 {
+		return true
+	}
+	for _, l := range m.Licenses {
+		if l.Region == region && l.activeAt(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// LicensedClause is the SQL equivalent of Movie.LicensedIn at NOW(), for the
+// movie id expression movieID and the region bound to placeholder $arg.
+func LicensedClause(movieID string, arg int) string {
+	return fmt.Sprintf("(NOT EXISTS (SELECT 1 FROM movie_territories WHERE movie_id = %[1]s)"+
+		" OR EXISTS (SELECT 1 FROM movie_territories WHERE movie_id = %[1]s AND region = $%[2]d"+
+		" AND (licensed_from IS NULL OR licensed_from <= NOW())"+
+		" AND (licensed_until IS NULL OR licensed_until > NOW())))", movieID, arg)
+}
@@ -1,5 +1,11 @@
 package movies
 
+import "time"
+
+// NewReleaseDays is how long a movie counts as a new release after it
+// becomes available.
+const NewReleaseDays = 30
+
 type Movie struct {
     MovieID int
     Title   string
@@ -8,4 +14,29 @@ type Movie struct {
     Genre   string
     ImdbID  string
     Actors  string
+
+    // The rental window is [AvailableFrom, AvailableUntil); a nil bound is
+    // open. They are left out of v1 responses, which are frozen.
+    AvailableFrom  *time.Time `json:"-" xml:"-"`
+    AvailableUntil *time.Time `json:"-" xml:"-"`
+
+    // Licenses is only loaded by GetMovieByID.
+    Licenses []License `json:"-" xml:"-"`
+}
+
+// AvailableAt reports whether the movie can be rented at t.
+func (m Movie) AvailableAt(t time.Time) bool {
+    if m.AvailableFrom != nil && t.Before(*m.AvailableFrom) {
+        return false
+    }
+    if m.AvailableUntil != nil && !t.Before(*m.AvailableUntil) {
+        return false
+    }
+    return true
+}
+
+// NewReleaseAt reports whether the movie counts as a new release at t.
+func (m Movie) NewReleaseAt(t time.Time) bool {
+    return m.AvailableFrom != nil && !t.Before(*m.AvailableFrom) &&
+        t.Before(m.AvailableFrom.AddDate(0, 0, NewReleaseDays))
 }
\ No newline at end of file
@@ -0,0 +1,42 @@
+package movies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovie_AvailableAt(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, Movie{}.AvailableAt(from))
+	assert.False(t, Movie{AvailableFrom: &from}.AvailableAt(from.Add(-time.Second)))
+	assert.True(t, Movie{AvailableFrom: &from}.AvailableAt(from))
+	assert.True(t, Movie{AvailableUntil: &until}.AvailableAt(until.Add(-time.Second)))
+	assert.False(t, Movie{AvailableUntil: &until}.AvailableAt(until))
+}
+
+func TestMovie_NewReleaseAt(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := Movie{AvailableFrom: &from}
+
+	assert.False(t, Movie{}.NewReleaseAt(from))
+	assert.False(t, m.NewReleaseAt(from.Add(-time.Second)))
+	assert.True(t, m.NewReleaseAt(from))
+	assert.True(t, m.NewReleaseAt(from.AddDate(0, 0, NewReleaseDays).Add(-time.Second)))
+	assert.False(t, m.NewReleaseAt(from.AddDate(0, 0, NewReleaseDays)))
+}
+
+func TestMovie_LicensedIn(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	m := Movie{Licenses: []License{{Region: "US"}, {Region: "GB", LicensedUntil: &expired}}}
+
+	assert.True(t, Movie{}.LicensedIn("", now))
+	assert.True(t, m.LicensedIn("US", now))
+	assert.False(t, m.LicensedIn("GB", now))
+	assert.False(t, m.LicensedIn("FR", now))
+	assert.False(t, m.LicensedIn("", now))
+}
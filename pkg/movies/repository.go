@@ -3,12 +3,45 @@ package movies
 import (
     "context"
     "database/sql"
+    "errors"
     "fmt"
+    "strings"
+
+    "github.com/lib/pq"
+)
+
+var (
+    ErrMovieNotFound   = errors.New("movie not found")
+    ErrLicenseNotFound = errors.New("movie is not licensed in that region")
 )
 
+// movieColumns lists the columns of the movies table in their declared order.
+// They double as the field names accepted by ?fields=.
+var movieColumns = []string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until"}
+
+// MovieFilter narrows ListMovies. Empty fields other than Region are ignored.
+type MovieFilter struct {
+    Genre string
+    Actor string
+    Year  string
+    // Tag matches approved public tags only.
+    Tag   string
+    // Region is always applied: titles licensed only elsewhere are hidden,
+    // and an empty Region hides every title with territory restrictions.
+    Region string
+}
+
 type MovieRepository interface {
-    ListMovies(ctx context.Context, genre, actor, year string) ([]Movie, error)
+    ListMovies(ctx context.Context, filter MovieFilter, fields []string) ([]Movie, error)
     GetMovieByID(ctx context.Context, id string) (*Movie, error)
+    // ListSimilarMovies, ListNewReleases and ListComingSoon leave out
+    // titles not licensed in region, like MovieFilter.Region.
+    ListSimilarMovies(ctx context.Context, id, region string, limit int) ([]Movie, error)
+    ListNewReleases(ctx context.Context, withinDays int, region string) ([]Movie, error)
+    ListComingSoon(ctx context.Context, region string) ([]Movie, error)
+    ListLicenses(ctx context.Context, movieID string) ([]License, error)
+    SetLicense(ctx context.Context, movieID string, l License) error
+    DeleteLicense(ctx context.Context, movieID, region string) error
 }
 
 type movieRepository struct {
@@ -19,27 +52,149 @@ func NewMovieRepository(db *sql.DB) MovieRepository {
     return &movieRepository{db: db}
 }
 
-func (r *movieRepository) ListMovies(ctx context.Context, genre, actor, year string) ([]Movie, error) {
-    query := "SELECT * FROM movies WHERE 1=1"
+func (r *movieRepository) ListMovies(ctx context.Context, filter MovieFilter, fields []string) ([]Movie, error) {
+    columns, err := projectedColumns(fields)
+    if err != nil {
+        return nil, err
+    }
+
+    query := "SELECT " + strings.Join(columns, ", ") + " FROM movies WHERE 1=1"
     var args []interface{}
     idx := 1
 
-    if genre != "" {
+    if filter.Genre != "" {
         query += fmt.Sprintf(" AND genre ILIKE '%%' || $%d || '%%'", idx)
-        args = append(args, genre)
+        args = append(args, filter.Genre)
         idx++
     }
-    if actor != "" {
+    if filter.Actor != "" {
         query += fmt.Sprintf(" AND actors ILIKE '%%' || $%d || '%%'", idx)
-        args = append(args, actor)
+        args = append(args, filter.Actor)
         idx++
     }
-    if year != "" {
+    if filter.Year != "" {
         query += fmt.Sprintf(" AND year = $%d", idx)
-        args = append(args, year)
+        args = append(args, filter.Year)
         idx++
     }
+    if filter.Tag != "" {
+        query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM movie_tags mt JOIN tags t ON t.tag_id = mt.tag_id"+
+            " WHERE mt.movie_id = movies.movie_id AND t.name = LOWER($%d)"+
+            " AND mt.visibility = 'public' AND mt.status = 'approved')", idx)
+        args = append(args, filter.Tag)
+        idx++
+    }
+    query += " AND " + LicensedClause("movies.movie_id", idx)
+    args = append(args, filter.Region)
+
+    return r.queryMovies(ctx, columns, query, args...)
+}
+
+func (r *movieRepository) GetMovieByID(ctx context.Context, id string) (*Movie, error) {
+    var m Movie
+    err := r.db.QueryRowContext(ctx,
+        "SELECT "+SelectColumns("")+" FROM movies WHERE movie_id = $1", id,
+    ).Scan(ScanFields(&m)...)
+
+    if err == sql.ErrNoRows {
+        return nil, nil
+    } else if err != nil {
+        return nil, err
+    }
 
+    m.Licenses, err = r.ListLicenses(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    return &m, nil
+}
+
+func (r *movieRepository) ListLicenses(ctx context.Context, movieID string) ([]License, error) {
+    rows, err := r.db.QueryContext(ctx, `
+        SELECT region, licensed_from, licensed_until FROM movie_territories
+        WHERE movie_id = $1
+        ORDER BY region`, movieID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var licenses []License
+    for rows.Next() {
+        var l License
+        if err := rows.Scan(&l.Region, &l.LicensedFrom, &l.LicensedUntil); err != nil {
+            return nil, err
+        }
+        licenses = append(licenses, l)
+    }
+
+    return licenses, rows.Err()
+}
+
+// SetLicense creates or replaces the license for l.Region.
+func (r *movieRepository) SetLicense(ctx context.Context, movieID string, l License) error {
+    _, err := r.db.ExecContext(ctx, `
+        INSERT INTO movie_territories (movie_id, region, licensed_from, licensed_until)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (movie_id, region)
+        DO UPDATE SET licensed_from = EXCLUDED.licensed_from, licensed_until = EXCLUDED.licensed_until`,
+        movieID, l.Region, l.LicensedFrom, l.LicensedUntil)
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+        return ErrMovieNotFound
+    }
+    return err
+}
+
+func (r *movieRepository) DeleteLicense(ctx context.Context, movieID, region string) error {
+    res, err := r.db.ExecContext(ctx,
+        "DELETE FROM movie_territories WHERE movie_id = $1 AND region = $2", movieID, region)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return ErrLicenseNotFound
+    }
+    return nil
+}
+
+func (r *movieRepository) ListSimilarMovies(ctx context.Context, id, region string, limit int) ([]Movie, error) {
+    return r.queryMovies(ctx, movieColumns, `
+        SELECT `+SelectColumns("m")+` FROM movies m
+        JOIN movies s ON s.movie_id = $1
+        WHERE m.movie_id <> s.movie_id AND m.genre = s.genre
+        AND `+LicensedClause("m.movie_id", 3)+`
+        ORDER BY m.year DESC, m.movie_id
+        LIMIT $2`, id, limit, region)
+}
+
+// ListNewReleases returns movies that became available within the last
+// withinDays days and can still be rented, newest first.
+func (r *movieRepository) ListNewReleases(ctx context.Context, withinDays int, region string) ([]Movie, error) {
+    return r.queryMovies(ctx, movieColumns, `
+        SELECT `+SelectColumns("")+` FROM movies
+        WHERE available_from <= NOW() AND available_from > NOW() - make_interval(days => $1)
+        AND (available_until IS NULL OR available_until > NOW())
+        AND `+LicensedClause("movies.movie_id", 2)+`
+        ORDER BY available_from DESC, movie_id`, withinDays, region)
+}
+
+// ListComingSoon returns movies that are announced but not yet available,
+// soonest first.
+func (r *movieRepository) ListComingSoon(ctx context.Context, region string) ([]Movie, error) {
+    return r.queryMovies(ctx, movieColumns, `
+        SELECT `+SelectColumns("")+` FROM movies
+        WHERE available_from > NOW()
+        AND `+LicensedClause("movies.movie_id", 1)+`
+        ORDER BY available_from, movie_id`, region)
+}
+
+func (r *movieRepository) queryMovies(ctx context.Context, columns []string, query string, args ...interface{}) ([]Movie, error) {
     rows, err := r.db.QueryContext(ctx, query, args...)
     if err != nil {
         return nil, err
@@ -49,26 +204,86 @@ func (r *movieRepository) ListMovies(ctx context.Context, genre, actor, year str
     var movies []Movie
     for rows.Next() {
         var m Movie
-        if err := rows.Scan(&m.MovieID, &m.Title, &m.Year, &m.Plot, &m.Genre, &m.ImdbID, &m.Actors); err != nil {
+        if err := rows.Scan(scanTargets(&m, columns)...); err != nil {
             return nil, err
         }
         movies = append(movies, m)
     }
 
-    return movies, nil
+    return movies, rows.Err()
 }
 
-func (r *movieRepository) GetMovieByID(ctx context.Context, id string) (*Movie, error) {
-    var m Movie
-    err := r.db.QueryRowContext(ctx,
-        "SELECT * FROM movies WHERE movie_id = $1", id,
-    ).Scan(&m.MovieID, &m.Title, &m.Year, &m.Plot, &m.Genre, &m.ImdbID, &m.Actors)
+// SelectColumns returns every movie column, qualified with alias if given.
+func SelectColumns(alias string) string {
+    if alias == "" {
+        return strings.Join(movieColumns, ", ")
+    }
+    return alias + "." + strings.Join(movieColumns, ", "+alias+".")
+}
 
-    if err == sql.ErrNoRows {
-        return nil, nil
-    } else if err != nil {
-        return nil, err
+// projectedColumns returns the columns to select for the requested fields.
+// movie_id is always selected so that projected rows stay addressable.
+func projectedColumns(fields []string) ([]string, error) {
+    if len(fields) == 0 {
+        return movieColumns, nil
     }
+    columns := []string{"movie_id"}
+    for _, f := range fields {
+        if !contains(movieColumns, f) {
+            return nil, fmt.Errorf("unknown field %q", f)
+        }
+        if !contains(columns, f) {
+            columns = append(columns, f)
+        }
+    }
+    return columns, nil
+}
 
-    return &m, nil
+func contains(list []string, s string) bool {
+    for _, v := range list {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
+
+// ScanFields returns the scan destinations for a row selected with
+// SelectColumns.
+func ScanFields(m *Movie) []interface{} {
+    return scanTargets(m, movieColumns)
+}
+
+func scanTargets(m *Movie, columns []string) []interface{} {
+    targets := make([]interface{}, len(columns))
+    for i, c := range columns {
+        _, targets[i] = movieField(m, c)
+    }
+    return targets
+}
+
+// movieField returns the JSON key and a pointer to the Movie field backing
+// the given column.
+func movieField(m *Movie, column string) (string, interface{}) {
+    switch column {
+    case "movie_id":
+        return "MovieID", &m.MovieID
+    case "title":
+        return "Title", &m.Title
+    case "year":
+        return "Year", &m.Year
+    case "plot":
+        return "Plot", &m.Plot
+    case "genre":
+        return "Genre", &m.Genre
+    case "imdbid":
+        return "ImdbID", &m.ImdbID
+    case "actors":
+        return "Actors", &m.Actors
+    case "available_from":
+        return "AvailableFrom", &m.AvailableFrom
+    case "available_until":
+        return "AvailableUntil", &m.AvailableUntil
+    }
+    return "", nil
 }
\ No newline at end of file
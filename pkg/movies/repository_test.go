@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
 func newTestMovieRows() *sqlmock.Rows {
-    return sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors"})
+    return sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until"})
 }
 
 func TestListMovies_All(t *testing.T) {
@@ -19,12 +21,12 @@ func TestListMovies_All(t *testing.T) {
     defer db.Close()
 
     rows := newTestMovieRows().
-        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A").
-        AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor B")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1`).WillReturnRows(rows)
+        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A", nil, nil).
+        AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor B", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1`).WillReturnRows(rows)
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "", "", "")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{}, nil)
     assert.NoError(t, err)
     assert.Len(t, movies, 2)
     assert.Equal(t, "Movie 1", movies[0].Title)
@@ -37,13 +39,13 @@ func TestListMovies_WithGenre(t *testing.T) {
     defer db.Close()
 
     rows := newTestMovieRows().
-        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1 AND genre ILIKE '%' \|\| \$1 \|\| '%'`).
-        WithArgs("Action").
+        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1 AND genre ILIKE '%' \|\| \$1 \|\| '%'`).
+        WithArgs("Action", "").
         WillReturnRows(rows)
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "Action", "", "")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{Genre: "Action"}, nil)
     assert.NoError(t, err)
     assert.Len(t, movies, 1)
     assert.Equal(t, "Action", movies[0].Genre)
@@ -55,13 +57,13 @@ func TestListMovies_WithActor(t *testing.T) {
     defer db.Close()
 
     rows := newTestMovieRows().
-        AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor B")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1 AND actors ILIKE '%' \|\| \$1 \|\| '%'`).
-        WithArgs("Actor B").
+        AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor B", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1 AND actors ILIKE '%' \|\| \$1 \|\| '%'`).
+        WithArgs("Actor B", "").
         WillReturnRows(rows)
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "", "Actor B", "")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{Actor: "Actor B"}, nil)
     assert.NoError(t, err)
     assert.Len(t, movies, 1)
     assert.Equal(t, "Movie 2", movies[0].Title)
@@ -73,13 +75,13 @@ func TestListMovies_WithYear(t *testing.T) {
     defer db.Close()
 
     rows := newTestMovieRows().
-        AddRow(3, "Movie 3", 2022, "Plot 3", "Comedy", "tt1111111", "Actor C")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1 AND year = \$1`).
-        WithArgs("2022").
+        AddRow(3, "Movie 3", 2022, "Plot 3", "Comedy", "tt1111111", "Actor C", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1 AND year = \$1`).
+        WithArgs("2022", "").
         WillReturnRows(rows)
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "", "", "2022")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{Year: "2022"}, nil)
     assert.NoError(t, err)
     assert.Len(t, movies, 1)
     assert.Equal(t, 2022, movies[0].Year)
@@ -91,28 +93,46 @@ func TestListMovies_WithActorAndYear(t *testing.T) {
     defer db.Close()
 
     rows := newTestMovieRows().
-        AddRow(4, "Movie 4", 2023, "Plot 4", "Thriller", "tt2222222", "Actor D")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1 AND actors ILIKE '%' \|\| \$1 \|\| '%' AND year = \$2`).
-        WithArgs("Actor D", "2023").
+        AddRow(4, "Movie 4", 2023, "Plot 4", "Thriller", "tt2222222", "Actor D", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1 AND actors ILIKE '%' \|\| \$1 \|\| '%' AND year = \$2`).
+        WithArgs("Actor D", "2023", "").
         WillReturnRows(rows)
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "", "Actor D", "2023")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{Actor: "Actor D", Year: "2023"}, nil)
     assert.NoError(t, err)
     assert.Len(t, movies, 1)
     assert.Equal(t, "Movie 4", movies[0].Title)
     assert.Equal(t, 2023, movies[0].Year)
 }
 
+func TestListMovies_WithTag(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    rows := newTestMovieRows().
+        AddRow(5, "Movie 5", 1984, "Plot 5", "Horror", "tt3333333", "Actor E", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1 AND EXISTS \(SELECT 1 FROM movie_tags mt JOIN tags t ON t.tag_id = mt.tag_id WHERE mt.movie_id = movies.movie_id AND t.name = LOWER\(\$1\) AND mt.visibility = 'public' AND mt.status = 'approved'\)`).
+        WithArgs("Cult Classic", "").
+        WillReturnRows(rows)
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{Tag: "Cult Classic"}, nil)
+    assert.NoError(t, err)
+    assert.Len(t, movies, 1)
+    assert.Equal(t, "Movie 5", movies[0].Title)
+}
+
 func TestListMovies_DBError(t *testing.T) {
     db, mock, err := sqlmock.New()
     assert.NoError(t, err)
     defer db.Close()
 
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1`).WillReturnError(errors.New("db error"))
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1`).WillReturnError(errors.New("db error"))
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "", "", "")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{}, nil)
     assert.Error(t, err)
     assert.Nil(t, movies)
 }
@@ -123,11 +143,11 @@ func TestListMovies_RowScanError(t *testing.T) {
     defer db.Close()
 
     rows := newTestMovieRows().
-        AddRow("not-an-int", "Title", 2020, "Plot", "Genre", "imdbid", "Actors")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE 1=1`).WillReturnRows(rows)
+        AddRow("not-an-int", "Title", 2020, "Plot", "Genre", "imdbid", "Actors", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1`).WillReturnRows(rows)
 
     repo := NewMovieRepository(db)
-    movies, err := repo.ListMovies(context.Background(), "", "", "")
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{}, nil)
     assert.Error(t, err)
     assert.Nil(t, movies)
 }
@@ -138,16 +158,21 @@ func TestGetMovieByID_Found(t *testing.T) {
     defer db.Close()
 
     row := newTestMovieRows().
-        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A")
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE movie_id = \$1`).
+        AddRow(1, "Movie 1", 2020, "Plot 1", "Action", "tt1234567", "Actor A", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE movie_id = \$1`).
         WithArgs("1").
         WillReturnRows(row)
+    until := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+    mock.ExpectQuery(`SELECT region, licensed_from, licensed_until FROM movie_territories WHERE movie_id = \$1`).
+        WithArgs("1").
+        WillReturnRows(newTestLicenseRows().AddRow("GB", nil, until).AddRow("US", nil, nil))
 
     repo := NewMovieRepository(db)
     movie, err := repo.GetMovieByID(context.Background(), "1")
     assert.NoError(t, err)
     assert.NotNil(t, movie)
     assert.Equal(t, "Movie 1", movie.Title)
+    assert.Equal(t, []License{{Region: "GB", LicensedUntil: &until}, {Region: "US"}}, movie.Licenses)
 }
 
 func TestGetMovieByID_NotFound(t *testing.T) {
@@ -155,7 +180,7 @@ func TestGetMovieByID_NotFound(t *testing.T) {
     assert.NoError(t, err)
     defer db.Close()
 
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE movie_id = \$1`).
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE movie_id = \$1`).
         WithArgs("99").
         WillReturnRows(newTestMovieRows())
 
@@ -170,7 +195,7 @@ func TestGetMovieByID_DBError(t *testing.T) {
     assert.NoError(t, err)
     defer db.Close()
 
-    mock.ExpectQuery(`SELECT \* FROM movies WHERE movie_id = \$1`).
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE movie_id = \$1`).
         WithArgs("1").
         WillReturnError(errors.New("db failure"))
 
@@ -178,4 +203,213 @@ func TestGetMovieByID_DBError(t *testing.T) {
     movie, err := repo.GetMovieByID(context.Background(), "1")
     assert.Error(t, err)
     assert.Nil(t, movie)
-}
\ No newline at end of file
+}
+
+func TestListMovies_WithFields(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    rows := sqlmock.NewRows([]string{"movie_id", "title", "year"}).
+        AddRow(1, "Movie 1", 2020)
+    mock.ExpectQuery(`SELECT movie_id, title, year FROM movies WHERE 1=1`).WillReturnRows(rows)
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{}, []string{"title", "year"})
+    assert.NoError(t, err)
+    assert.Len(t, movies, 1)
+    assert.Equal(t, "Movie 1", movies[0].Title)
+    assert.Equal(t, 2020, movies[0].Year)
+    assert.Empty(t, movies[0].Plot)
+}
+
+func TestListMovies_UnknownField(t *testing.T) {
+    db, _, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListMovies(context.Background(), MovieFilter{}, []string{"title; DROP TABLE movies"})
+    assert.Error(t, err)
+    assert.Nil(t, movies)
+}
+
+func TestListSimilarMovies(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    rows := newTestMovieRows().
+        AddRow(2, "Movie 2", 2021, "Plot 2", "Action", "tt7654321", "Actor B", nil, nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies m JOIN movies s ON s.movie_id = \$1 WHERE m.movie_id <> s.movie_id AND m.genre = s.genre ` +
+        `AND \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = m.movie_id\) OR EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = m.movie_id AND region = \$3`).
+        WithArgs("1", 10, "GB").
+        WillReturnRows(rows)
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListSimilarMovies(context.Background(), "1", "GB", 10)
+    assert.NoError(t, err)
+    assert.Len(t, movies, 1)
+    assert.Equal(t, "Movie 2", movies[0].Title)
+}
+
+func TestListSimilarMovies_DBError(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT (.+) FROM movies m`).WillReturnError(errors.New("db error"))
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListSimilarMovies(context.Background(), "1", "GB", 10)
+    assert.Error(t, err)
+    assert.Nil(t, movies)
+}
+
+func TestGetMovieByID_AvailabilityWindow(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    from := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+    row := newTestMovieRows().
+        AddRow(1, "Movie 1", 2026, "Plot 1", "Action", "tt1234567", "Actor A", from, nil)
+    mock.ExpectQuery(`SELECT movie_id, title, year, plot, genre, imdbid, actors, available_from, available_until FROM movies WHERE movie_id = \$1`).
+        WithArgs("1").
+        WillReturnRows(row)
+    mock.ExpectQuery(`FROM movie_territories`).WillReturnRows(newTestLicenseRows())
+
+    repo := NewMovieRepository(db)
+    movie, err := repo.GetMovieByID(context.Background(), "1")
+    assert.NoError(t, err)
+    assert.Equal(t, from, *movie.AvailableFrom)
+    assert.Nil(t, movie.AvailableUntil)
+}
+
+func TestListNewReleases(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    rows := newTestMovieRows().
+        AddRow(1, "Movie 1", 2026, "Plot 1", "Action", "tt1234567", "Actor A", time.Now().Add(-24*time.Hour), nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE available_from <= NOW\(\) AND available_from > NOW\(\) - make_interval\(days => \$1\) (.+) ` +
+        `AND \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = movies.movie_id\) OR EXISTS (.+) region = \$2`).
+        WithArgs(30, "GB").
+        WillReturnRows(rows)
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListNewReleases(context.Background(), 30, "GB")
+    assert.NoError(t, err)
+    assert.Len(t, movies, 1)
+}
+
+func TestListComingSoon(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    rows := newTestMovieRows().
+        AddRow(1, "Movie 1", 2026, "Plot 1", "Action", "tt1234567", "Actor A", time.Now().Add(24*time.Hour), nil)
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE available_from > NOW\(\) ` +
+        `AND \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = movies.movie_id\) OR EXISTS (.+) region = \$1 (.+) ORDER BY available_from`).
+        WithArgs("GB").
+        WillReturnRows(rows)
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListComingSoon(context.Background(), "GB")
+    assert.NoError(t, err)
+    assert.Len(t, movies, 1)
+}
+
+func TestListComingSoon_DBError(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE available_from > NOW\(\)`).
+        WillReturnError(errors.New("db error"))
+
+    repo := NewMovieRepository(db)
+    movies, err := repo.ListComingSoon(context.Background(), "GB")
+    assert.Error(t, err)
+    assert.Nil(t, movies)
+}
+
+func newTestLicenseRows() *sqlmock.Rows {
+    return sqlmock.NewRows([]string{"region", "licensed_from", "licensed_until"})
+}
+
+func TestListMovies_FiltersByRegion(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`SELECT (.+) FROM movies WHERE 1=1 AND genre ILIKE '%' \|\| \$1 \|\| '%' AND \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = movies.movie_id\) OR EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = movies.movie_id AND region = \$2`).
+        WithArgs("Drama", "GB").
+        WillReturnRows(newTestMovieRows())
+
+    repo := NewMovieRepository(db)
+    _, err = repo.ListMovies(context.Background(), MovieFilter{Genre: "Drama", Region: "GB"}, nil)
+    assert.NoError(t, err)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_LicenseQueryError(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectQuery(`FROM movies WHERE movie_id = \$1`).
+        WillReturnRows(newTestMovieRows().AddRow(1, "Movie 1", 2020, "", "", "", "", nil, nil))
+    mock.ExpectQuery(`FROM movie_territories`).WillReturnError(errors.New("db error"))
+
+    repo := NewMovieRepository(db)
+    movie, err := repo.GetMovieByID(context.Background(), "1")
+    assert.Error(t, err)
+    assert.Nil(t, movie)
+}
+
+func TestSetLicense(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    from := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+    mock.ExpectExec(`INSERT INTO movie_territories \(movie_id, region, licensed_from, licensed_until\) VALUES \(\$1, \$2, \$3, \$4\) ON CONFLICT \(movie_id, region\) DO UPDATE`).
+        WithArgs("1", "GB", &from, nil).
+        WillReturnResult(sqlmock.NewResult(0, 1))
+
+    repo := NewMovieRepository(db)
+    err = repo.SetLicense(context.Background(), "1", License{Region: "GB", LicensedFrom: &from})
+    assert.NoError(t, err)
+}
+
+func TestSetLicense_MovieNotFound(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`INSERT INTO movie_territories`).WillReturnError(&pq.Error{Code: "23503"})
+
+    repo := NewMovieRepository(db)
+    err = repo.SetLicense(context.Background(), "99", License{Region: "GB"})
+    assert.ErrorIs(t, err, ErrMovieNotFound)
+}
+
+func TestDeleteLicense(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`DELETE FROM movie_territories WHERE movie_id = \$1 AND region = \$2`).
+        WithArgs("1", "GB").
+        WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(`DELETE FROM movie_territories`).
+        WithArgs("1", "FR").
+        WillReturnResult(sqlmock.NewResult(0, 0))
+
+    repo := NewMovieRepository(db)
+    assert.NoError(t, repo.DeleteLicense(context.Background(), "1", "GB"))
+    assert.ErrorIs(t, repo.DeleteLicense(context.Background(), "1", "FR"), ErrLicenseNotFound)
+}
@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"context"
+	"time"
+)
+
+// Enforcer checks requests against the limits of the user's membership
+// tier. Checks run before the change they guard, so concurrent requests
+// can overshoot a limit; checkout checks again.
+type Enforcer struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewEnforcer(repo Repository, now func() time.Time) *Enforcer {
+	return &Enforcer{repo: repo, now: now}
+}
+
+// CheckAdd returns a *Violation if adding items to the user's cart would
+// break a limit of their tier. Items already in the cart are ignored, as
+// adding them again fails anyway.
+func (e *Enforcer) CheckAdd(ctx context.Context, userID int, items ...Item) error {
+	limits, held, adding, err := e.prepare(ctx, userID, items)
+	if err != nil || len(adding) == 0 {
+		return err
+	}
+	return limits.Check(held, adding)
+}
+
+// CheckEach checks items as if they were added one after another, and
+// returns a *Violation for each item that would break a limit on top of the
+// items before it that did not. The error is nil for the others.
+func (e *Enforcer) CheckEach(ctx context.Context, userID int, items ...Item) ([]error, error) {
+	limits, held, adding, err := e.prepare(ctx, userID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	byMovie := make(map[int]Holding, len(adding))
+	for _, h := range adding {
+		byMovie[h.MovieID] = h
+	}
+	errs := make([]error, len(items))
+	for i, item := range items {
+		h, ok := byMovie[item.MovieID]
+		if !ok {
+			continue
+		}
+		// A movie listed twice is only added once.
+		delete(byMovie, item.MovieID)
+		if err := limits.Check(held, []Holding{h}); err != nil {
+			errs[i] = err
+			continue
+		}
+		held = append(held, h)
+	}
+	return errs, nil
+}
+
+// prepare returns the user's limits and holdings, and what the items not
+// yet in the cart would add to them. adding is empty when the tier has no
+// limits.
+func (e *Enforcer) prepare(ctx context.Context, userID int, items []Item) (limits Limits, held, adding []Holding, err error) {
+	limits, err = e.repo.Limits(ctx, userID)
+	if err != nil || limits.unlimited() {
+		return limits, nil, nil, err
+	}
+
+	now := e.now()
+	held, err = e.repo.Holdings(ctx, userID, now)
+	if err != nil {
+		return limits, nil, nil, err
+	}
+	inCart := make(map[int]bool, len(held))
+	for _, h := range held {
+		if h.InCart {
+			inCart[h.MovieID] = true
+		}
+	}
+	var fresh []Item
+	for _, item := range items {
+		if !inCart[item.MovieID] {
+			fresh = append(fresh, item)
+		}
+	}
+	if len(fresh) == 0 {
+		return limits, held, nil, nil
+	}
+
+	adding, err = e.repo.Describe(ctx, fresh, now)
+	if err != nil {
+		return limits, nil, nil, err
+	}
+	for i := range adding {
+		adding[i].InCart = true
+	}
+	return limits, held, adding, nil
+}
+
+// CheckCheckout returns a *Violation if renting everything in the user's
+// cart would break a limit of their tier on top of their active rentals.
+// The cart itself is not limited here, as checking out empties it.
+func (e *Enforcer) CheckCheckout(ctx context.Context, userID int) error {
+	limits, err := e.repo.Limits(ctx, userID)
+	if err != nil || limits.unlimited() {
+		return err
+	}
+	held, err := e.repo.Holdings(ctx, userID, e.now())
+	if err != nil {
+		return err
+	}
+
+	var rented, renting []Holding
+	for _, h := range held {
+		if h.InCart {
+			h.InCart = false
+			renting = append(renting, h)
+		} else {
+			rented = append(rented, h)
+		}
+	}
+	return limits.Check(rented, renting)
+}
+
+// MaxCartItems returns how many items the user's cart may hold, or 0 for
+// no limit.
+func (e *Enforcer) MaxCartItems(ctx context.Context, userID int) (int, error) {
+	limits, err := e.repo.Limits(ctx, userID)
+	return limits.MaxCartItems, err
+}
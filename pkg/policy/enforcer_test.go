@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	Repository
+	limits   Limits
+	holdings []Holding
+	describe func(items []Item) []Holding
+	err      error
+}
+
+func (m *mockRepository) Limits(ctx context.Context, userID int) (Limits, error) {
+	return m.limits, m.err
+}
+func (m *mockRepository) Holdings(ctx context.Context, userID int, now time.Time) ([]Holding, error) {
+	return m.holdings, nil
+}
+func (m *mockRepository) Describe(ctx context.Context, items []Item, now time.Time) ([]Holding, error) {
+	return m.describe(items), nil
+}
+
+func describeAll(items []Item) []Holding {
+	out := make([]Holding, len(items))
+	for i, item := range items {
+		out[i] = Holding{MovieID: item.MovieID, Physical: Physical(item.Format)}
+	}
+	return out
+}
+
+func TestEnforcer_CheckAdd(t *testing.T) {
+	repo := &mockRepository{
+		limits:   Limits{Tier: "standard", MaxCartItems: 2, MaxPhysicalRentals: 1},
+		holdings: []Holding{{MovieID: 1, InCart: true, Physical: true}},
+		describe: describeAll,
+	}
+	e := NewEnforcer(repo, time.Now)
+	ctx := context.Background()
+
+	assert.NoError(t, e.CheckAdd(ctx, 1, Item{MovieID: 2, Format: "digital"}))
+	assert.Equal(t, &Violation{Rule: RuleMaxPhysicalRentals, Tier: "standard", Limit: 1},
+		e.CheckAdd(ctx, 1, Item{MovieID: 2, Format: "dvd"}))
+	assert.Equal(t, &Violation{Rule: RuleMaxCartItems, Tier: "standard", Limit: 2},
+		e.CheckAdd(ctx, 1, Item{MovieID: 2, Format: "digital"}, Item{MovieID: 3, Format: "digital"}))
+	// Movie 1 is already in the cart, so adding it again adds nothing.
+	assert.NoError(t, e.CheckAdd(ctx, 1, Item{MovieID: 1, Format: "dvd"}))
+}
+
+func TestEnforcer_GiftsDoNotCountAsRentals(t *testing.T) {
+	repo := &mockRepository{
+		limits: Limits{Tier: "standard", MaxCartItems: 2, MaxPhysicalRentals: 1, MaxNewReleases: 1},
+		holdings: []Holding{
+			{MovieID: 1, InCart: true, Gift: true, Physical: true, NewRelease: true},
+			{MovieID: 2, InCart: true, Physical: true},
+		},
+		describe: describeAll,
+	}
+	e := NewEnforcer(repo, time.Now)
+	ctx := context.Background()
+
+	// The gifted disc leaves room for the buyer's own.
+	assert.NoError(t, e.CheckCheckout(ctx, 1))
+	// It still takes up room in the cart.
+	assert.Equal(t, &Violation{Rule: RuleMaxCartItems, Tier: "standard", Limit: 2},
+		e.CheckAdd(ctx, 1, Item{MovieID: 3, Format: "digital"}))
+}
+
+func TestEnforcer_Unlimited(t *testing.T) {
+	repo := &mockRepository{limits: Limits{Tier: "staff"}}
+	e := NewEnforcer(repo, time.Now)
+
+	assert.NoError(t, e.CheckAdd(context.Background(), 1, Item{MovieID: 2}))
+	max, err := e.MaxCartItems(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, max)
+
+	repo.err = errors.New("db error")
+	assert.Error(t, e.CheckAdd(context.Background(), 1, Item{MovieID: 2}))
+}
+
+func TestEnforcer_CheckEach(t *testing.T) {
+	repo := &mockRepository{
+		limits:   Limits{Tier: "standard", MaxCartItems: 3, MaxPhysicalRentals: 1},
+		holdings: []Holding{{MovieID: 1, InCart: true}},
+		describe: describeAll,
+	}
+	e := NewEnforcer(repo, time.Now)
+
+	errs, err := e.CheckEach(context.Background(), 1,
+		Item{MovieID: 2, Format: "dvd"},
+		Item{MovieID: 3, Format: "bluray"},
+		Item{MovieID: 1, Format: "digital"},
+		Item{MovieID: 4, Format: "digital"},
+		Item{MovieID: 5, Format: "digital"})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{
+		nil,
+		&Violation{Rule: RuleMaxPhysicalRentals, Tier: "standard", Limit: 1},
+		nil,
+		nil,
+		&Violation{Rule: RuleMaxCartItems, Tier: "standard", Limit: 3},
+	}, errs)
+}
+
+func TestEnforcer_CheckCheckout(t *testing.T) {
+	repo := &mockRepository{
+		limits: Limits{Tier: "standard", MaxCartItems: 1, MaxPhysicalRentals: 2},
+		holdings: []Holding{
+			{MovieID: 1, Physical: true},
+			{MovieID: 2, InCart: true},
+			{MovieID: 3, InCart: true},
+		},
+	}
+	e := NewEnforcer(repo, time.Now)
+
+	// The cart is over its limit, but checking out empties it.
+	assert.NoError(t, e.CheckCheckout(context.Background(), 1))
+
+	repo.holdings = append(repo.holdings, Holding{MovieID: 4, InCart: true, Physical: true},
+		Holding{MovieID: 5, InCart: true, Physical: true})
+	assert.Equal(t, &Violation{Rule: RuleMaxPhysicalRentals, Tier: "standard", Limit: 2},
+		e.CheckCheckout(context.Background(), 1))
+}
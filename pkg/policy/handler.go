@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CodeLimitExceeded is the error code of a request that would break a limit
+// of the user's tier. The error details name the rule.
+const CodeLimitExceeded = "limit_exceeded"
+
+// RespondError responds with the limit err reports, and reports whether
+// err was a *Violation.
+func RespondError(c *gin.Context, err error) bool {
+	detail, ok := ErrorDetail(err)
+	if ok {
+		api.ErrorWithDetails(c, http.StatusUnprocessableEntity, detail.Code, detail.Message, detail.Details)
+	}
+	return ok
+}
+
+// ErrorDetail describes the limit err reports, for responses that report
+// several errors, and reports whether err was a *Violation.
+func ErrorDetail(err error) (api.ErrorDetail, bool) {
+	var v *Violation
+	if !errors.As(err, &v) {
+		return api.ErrorDetail{}, false
+	}
+	return api.ErrorDetail{Code: CodeLimitExceeded, Message: v.Error(), Details: v}, true
+}
+
+func ListTiersHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tiers, err := repo.ListTiers(c.Request.Context())
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if tiers == nil {
+			tiers = []Limits{}
+		}
+
+		api.List(c, tiers, len(tiers))
+	}
+}
+
+// SetTierHandler creates a tier or replaces its limits.
+func SetTierHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LimitsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "limits must be zero or positive integers")
+			return
+		}
+		tier := c.Param("tier")
+		if len(tier) > 32 {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "tier names are at most 32 characters")
+			return
+		}
+
+		l := Limits{
+			Tier:               tier,
+			MaxCartItems:       req.MaxCartItems,
+			MaxPhysicalRentals: req.MaxPhysicalRentals,
+			MaxNewReleases:     req.MaxNewReleases,
+		}
+		if err := repo.SetTier(c.Request.Context(), l); err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusOK, l)
+	}
+}
+
+// SetMembershipHandler puts a user on a tier.
+func SetMembershipHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+		var req MembershipRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "tier is required")
+			return
+		}
+
+		err = repo.SetMembership(c.Request.Context(), userID, req.Tier)
+		switch {
+		case errors.Is(err, ErrTierNotFound):
+			api.Error(c, http.StatusUnprocessableEntity, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
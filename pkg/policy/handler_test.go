@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminRepository struct {
+	Repository
+	tiers       []Limits
+	set         *Limits
+	memberships map[int]string
+}
+
+func (r *adminRepository) ListTiers(ctx context.Context) ([]Limits, error) {
+	return r.tiers, nil
+}
+func (r *adminRepository) SetTier(ctx context.Context, l Limits) error {
+	r.set = &l
+	return nil
+}
+func (r *adminRepository) SetMembership(ctx context.Context, userID int, tier string) error {
+	if tier != DefaultTier {
+		return ErrTierNotFound
+	}
+	r.memberships[userID] = tier
+	return nil
+}
+
+func setupRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/admin/tiers", ListTiersHandler(repo))
+	router.PUT("/v2/admin/tiers/:tier", SetTierHandler(repo))
+	router.PUT("/v2/admin/memberships/:user_id", SetMembershipHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestListTiersHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&adminRepository{tiers: []Limits{{Tier: "standard", MaxCartItems: 5}}})
+
+	recorder := serve(router, "GET", "/v2/admin/tiers", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":[{"tier":"standard","max_cart_items":5,"max_physical_rentals":0,"max_new_releases":0}],
+		"meta":{"api_version":"v2","count":1}}`, recorder.Body.String())
+}
+
+func TestSetTierHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &adminRepository{}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/tiers/premium", `{"max_cart_items":20,"max_new_releases":5}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, &Limits{Tier: "premium", MaxCartItems: 20, MaxNewReleases: 5}, repo.set)
+
+	recorder = serve(router, "PUT", "/v2/admin/tiers/premium", `{"max_cart_items":-1}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestSetMembershipHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &adminRepository{memberships: map[int]string{}}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/memberships/1", `{"tier":"standard"}`)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "standard", repo.memberships[1])
+
+	recorder = serve(router, "PUT", "/v2/admin/memberships/1", `{"tier":"gold"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+}
@@ -0,0 +1,134 @@
+// Package policy enforces the business limits of each membership tier,
+// such as how many items a cart may hold.
+package policy
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// Rule names a limit of a membership tier.
+type Rule string
+
+const (
+	// RuleMaxCartItems caps the items in a cart.
+	RuleMaxCartItems Rule = "max_cart_items"
+	// RuleMaxPhysicalRentals caps the disc rentals a user has out at once,
+	// counting those in their cart.
+	RuleMaxPhysicalRentals Rule = "max_physical_rentals"
+	// RuleMaxNewReleases caps the new releases a user has at once, in their
+	// cart or rented.
+	RuleMaxNewReleases Rule = "max_new_releases"
+)
+
+// DefaultTier is the tier of users without a membership.
+const DefaultTier = "standard"
+
+var ErrTierNotFound = errors.New("membership tier not found")
+
+// Limits are the limits of a membership tier. Zero means no limit.
+type Limits struct {
+	XMLName            xml.Name `json:"-" xml:"membership_tier"`
+	Tier               string   `json:"tier" xml:"tier"`
+	MaxCartItems       int      `json:"max_cart_items" xml:"max_cart_items"`
+	MaxPhysicalRentals int      `json:"max_physical_rentals" xml:"max_physical_rentals"`
+	MaxNewReleases     int      `json:"max_new_releases" xml:"max_new_releases"`
+}
+
+// LimitsRequest sets the limits of a tier. Zero means no limit.
+type LimitsRequest struct {
+	MaxCartItems       int `json:"max_cart_items" binding:"gte=0"`
+	MaxPhysicalRentals int `json:"max_physical_rentals" binding:"gte=0"`
+	MaxNewReleases     int `json:"max_new_releases" binding:"gte=0"`
+}
+
+// MembershipRequest puts a user on a tier.
+type MembershipRequest struct {
+	Tier string `json:"tier" binding:"required,max=32"`
+}
+
+// Item is a movie a user wants to put in their cart, rented in Format.
+type Item struct {
+	MovieID int
+	Format  string
+}
+
+// Holding is a movie that counts towards a user's limits: one in their
+// cart, one they are renting, or one they are about to add.
+type Holding struct {
+	MovieID int
+	// InCart is false for rentals.
+	InCart bool
+	// Gift is set for cart items bought for someone else. They count
+	// towards the cart, but not towards the buyer's rentals.
+	Gift       bool
+	Physical   bool
+	NewRelease bool
+}
+
+// Physical reports whether movies rented in format are shipped on disc.
+func Physical(format string) bool {
+	return format == "dvd" || format == "bluray"
+}
+
+// Violation is the error returned when a request would break a limit. It
+// is also the details of the error response.
+type Violation struct {
+	Rule  Rule   `json:"rule"`
+	Tier  string `json:"tier"`
+	Limit int    `json:"limit"`
+}
+
+func (v *Violation) Error() string {
+	var what string
+	switch v.Rule {
+	case RuleMaxCartItems:
+		what = "items in the cart"
+	case RuleMaxPhysicalRentals:
+		what = "physical rentals at once"
+	case RuleMaxNewReleases:
+		what = "new releases at once"
+	}
+	return fmt.Sprintf("the %s tier allows at most %d %s", v.Tier, v.Limit, what)
+}
+
+func (l Limits) unlimited() bool {
+	return l.MaxCartItems == 0 && l.MaxPhysicalRentals == 0 && l.MaxNewReleases == 0
+}
+
+// Check returns a *Violation for the first limit that adding would break
+// on top of held. Only the rules adding counts towards are checked, so a
+// user already over a limit, say after moving to a lower tier, can still
+// add what does not make it worse.
+func (l Limits) Check(held, adding []Holding) error {
+	rules := []struct {
+		rule   Rule
+		limit  int
+		counts func(Holding) bool
+	}{
+		{RuleMaxCartItems, l.MaxCartItems, func(h Holding) bool { return h.InCart }},
+		{RuleMaxPhysicalRentals, l.MaxPhysicalRentals, func(h Holding) bool { return h.Physical && !h.Gift }},
+		{RuleMaxNewReleases, l.MaxNewReleases, func(h Holding) bool { return h.NewRelease && !h.Gift }},
+	}
+	for _, r := range rules {
+		if r.limit == 0 {
+			continue
+		}
+		added := count(adding, r.counts)
+		if added > 0 && count(held, r.counts)+added > r.limit {
+			return &Violation{Rule: r.rule, Tier: l.Tier, Limit: r.limit}
+		}
+	}
+	return nil
+}
+
+func count(holdings []Holding, counts func(Holding) bool) int {
+	n := 0
+	for _, h := range holdings {
+		if counts(h) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_Check(t *testing.T) {
+	l := Limits{Tier: "standard", MaxCartItems: 2, MaxPhysicalRentals: 1, MaxNewReleases: 1}
+	digital := Holding{InCart: true}
+	disc := Holding{InCart: true, Physical: true}
+	rentedDisc := Holding{Physical: true}
+	newRelease := Holding{InCart: true, NewRelease: true}
+
+	assert.NoError(t, l.Check(nil, []Holding{digital, digital}))
+	assert.NoError(t, l.Check([]Holding{digital}, []Holding{disc}))
+
+	err := l.Check([]Holding{digital, digital}, []Holding{digital})
+	assert.Equal(t, &Violation{Rule: RuleMaxCartItems, Tier: "standard", Limit: 2}, err)
+	assert.EqualError(t, err, "the standard tier allows at most 2 items in the cart")
+
+	err = l.Check([]Holding{rentedDisc}, []Holding{disc})
+	assert.Equal(t, &Violation{Rule: RuleMaxPhysicalRentals, Tier: "standard", Limit: 1}, err)
+
+	err = l.Check([]Holding{newRelease}, []Holding{newRelease})
+	assert.Equal(t, &Violation{Rule: RuleMaxNewReleases, Tier: "standard", Limit: 1}, err)
+}
+
+func TestLimits_Check_OnlyRulesAddingCountsTowards(t *testing.T) {
+	l := Limits{Tier: "basic", MaxCartItems: 5, MaxPhysicalRentals: 1}
+	overPhysical := []Holding{{Physical: true}, {Physical: true}}
+
+	assert.NoError(t, l.Check(overPhysical, []Holding{{InCart: true}}))
+	assert.Error(t, l.Check(overPhysical, []Holding{{InCart: true, Physical: true}}))
+	assert.NoError(t, Limits{}.Check(overPhysical, overPhysical))
+}
@@ -0,0 +1,171 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"movie-rental/pkg/movies"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// Limits returns the limits of the user's tier. A tier missing from
+	// membership_tiers has no limits.
+	Limits(ctx context.Context, userID int) (Limits, error)
+	// Holdings returns everything that counts towards the user's limits
+	// as of now.
+	Holdings(ctx context.Context, userID int, now time.Time) ([]Holding, error)
+	// Describe returns the holdings that items would become as of now.
+	// Unknown movies are left out.
+	Describe(ctx context.Context, items []Item, now time.Time) ([]Holding, error)
+
+	ListTiers(ctx context.Context) ([]Limits, error)
+	// SetTier creates the tier or replaces its limits.
+	SetTier(ctx context.Context, l Limits) error
+	SetMembership(ctx context.Context, userID int, tier string) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+const limitsColumns = `tier, COALESCE(max_cart_items, 0), COALESCE(max_physical_rentals, 0),
+	COALESCE(max_new_releases, 0)`
+
+func scanLimits(row interface{ Scan(...interface{}) error }) (Limits, error) {
+	var l Limits
+	err := row.Scan(&l.Tier, &l.MaxCartItems, &l.MaxPhysicalRentals, &l.MaxNewReleases)
+	return l, err
+}
+
+func (r *repository) Limits(ctx context.Context, userID int) (Limits, error) {
+	var tier string
+	err := r.db.QueryRowContext(ctx, "SELECT tier FROM memberships WHERE user_id = $1", userID).Scan(&tier)
+	if err == sql.ErrNoRows {
+		tier = DefaultTier
+	} else if err != nil {
+		return Limits{}, err
+	}
+
+	l, err := scanLimits(r.db.QueryRowContext(ctx,
+		"SELECT "+limitsColumns+" FROM membership_tiers WHERE tier = $1", tier))
+	if err == sql.ErrNoRows {
+		return Limits{Tier: tier}, nil
+	}
+	return l, err
+}
+
+func (r *repository) Holdings(ctx context.Context, userID int, now time.Time) ([]Holding, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.movie_id, c.format, m.available_from, TRUE,
+			c.gift_recipient_id IS NOT NULL OR c.gift_recipient_email IS NOT NULL
+		FROM cart c
+		JOIN movies m ON c.movie_id = m.movie_id
+		WHERE c.user_id = $1
+		UNION ALL
+		SELECT r.movie_id, r.format, m.available_from, FALSE, FALSE
+		FROM rentals r
+		JOIN movies m ON r.movie_id = m.movie_id
+		WHERE r.user_id = $1 AND r.ends_at > $2`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []Holding
+	for rows.Next() {
+		var m movies.Movie
+		var format string
+		var inCart, gift bool
+		if err := rows.Scan(&m.MovieID, &format, &m.AvailableFrom, &inCart, &gift); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, Holding{
+			MovieID:    m.MovieID,
+			InCart:     inCart,
+			Gift:       gift,
+			Physical:   Physical(format),
+			NewRelease: m.NewReleaseAt(now),
+		})
+	}
+	return holdings, rows.Err()
+}
+
+func (r *repository) Describe(ctx context.Context, items []Item, now time.Time) ([]Holding, error) {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = int64(item.MovieID)
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT movie_id, available_from FROM movies WHERE movie_id = ANY($1::int[])", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	newRelease := make(map[int]bool, len(items))
+	for rows.Next() {
+		var m movies.Movie
+		if err := rows.Scan(&m.MovieID, &m.AvailableFrom); err != nil {
+			return nil, err
+		}
+		newRelease[m.MovieID] = m.NewReleaseAt(now)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var holdings []Holding
+	for _, item := range items {
+		if nr, ok := newRelease[item.MovieID]; ok {
+			holdings = append(holdings, Holding{MovieID: item.MovieID, Physical: Physical(item.Format), NewRelease: nr})
+		}
+	}
+	return holdings, nil
+}
+
+func (r *repository) ListTiers(ctx context.Context) ([]Limits, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+limitsColumns+" FROM membership_tiers ORDER BY tier")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []Limits
+	for rows.Next() {
+		l, err := scanLimits(rows)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, l)
+	}
+	return tiers, rows.Err()
+}
+
+func (r *repository) SetTier(ctx context.Context, l Limits) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO membership_tiers (tier, max_cart_items, max_physical_rentals, max_new_releases)
+		VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), NULLIF($4, 0))
+		ON CONFLICT (tier) DO UPDATE SET max_cart_items = EXCLUDED.max_cart_items,
+			max_physical_rentals = EXCLUDED.max_physical_rentals, max_new_releases = EXCLUDED.max_new_releases`,
+		l.Tier, l.MaxCartItems, l.MaxPhysicalRentals, l.MaxNewReleases)
+	return err
+}
+
+func (r *repository) SetMembership(ctx context.Context, userID int, tier string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO memberships (user_id, tier) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET tier = EXCLUDED.tier`, userID, tier)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrTierNotFound
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT tier FROM memberships WHERE user_id = \$1`).
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"tier"}))
+	mock.ExpectQuery(`SELECT tier, (.+) FROM membership_tiers WHERE tier = \$1`).
+		WithArgs(DefaultTier).
+		WillReturnRows(sqlmock.NewRows([]string{"tier", "max_cart_items", "max_physical_rentals", "max_new_releases"}).
+			AddRow("standard", 5, 3, 2))
+	mock.ExpectQuery(`SELECT tier FROM memberships`).
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"tier"}).AddRow("gone"))
+	mock.ExpectQuery(`FROM membership_tiers`).
+		WithArgs("gone").WillReturnRows(sqlmock.NewRows([]string{"tier", "a", "b", "c"}))
+
+	repo := NewRepository(db)
+	l, err := repo.Limits(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{Tier: "standard", MaxCartItems: 5, MaxPhysicalRentals: 3, MaxNewReleases: 2}, l)
+
+	l, err = repo.Limits(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{Tier: "gone"}, l)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHoldings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT c.movie_id, c.format, m.available_from, TRUE, c.gift_recipient_id IS NOT NULL OR c.gift_recipient_email IS NOT NULL FROM cart c JOIN movies m ON c.movie_id = m.movie_id WHERE c.user_id = \$1 `+
+		`UNION ALL SELECT r.movie_id, r.format, m.available_from, FALSE, FALSE FROM rentals r JOIN movies m ON r.movie_id = m.movie_id WHERE r.user_id = \$1 AND r.ends_at > \$2`).
+		WithArgs(1, now).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "format", "available_from", "in_cart", "gift"}).
+			AddRow(2, "bluray", nil, true, false).
+			AddRow(3, "digital", now.AddDate(0, 0, -3), true, false).
+			AddRow(4, "dvd", nil, false, false).
+			AddRow(5, "dvd", nil, true, true))
+
+	holdings, err := NewRepository(db).Holdings(context.Background(), 1, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []Holding{
+		{MovieID: 2, InCart: true, Physical: true},
+		{MovieID: 3, InCart: true, NewRelease: true},
+		{MovieID: 4, Physical: true},
+		{MovieID: 5, InCart: true, Gift: true, Physical: true},
+	}, holdings)
+}
+
+func TestDescribe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT movie_id, available_from FROM movies WHERE movie_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(pq.Array([]int64{2, 404})).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "available_from"}).AddRow(2, now))
+
+	holdings, err := NewRepository(db).Describe(context.Background(),
+		[]Item{{MovieID: 2, Format: "dvd"}, {MovieID: 404, Format: "digital"}}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []Holding{{MovieID: 2, Physical: true, NewRelease: true}}, holdings)
+}
+
+func TestSetTier(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO membership_tiers (.+) VALUES \(\$1, NULLIF\(\$2, 0\), NULLIF\(\$3, 0\), NULLIF\(\$4, 0\)\) ON CONFLICT \(tier\) DO UPDATE`).
+		WithArgs("premium", 20, 0, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewRepository(db).SetTier(context.Background(), Limits{Tier: "premium", MaxCartItems: 20, MaxNewReleases: 5})
+	assert.NoError(t, err)
+}
+
+func TestSetMembership_UnknownTier(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO memberships \(user_id, tier\) VALUES \(\$1, \$2\) ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs(1, "gold").WillReturnError(&pq.Error{Code: "23503"})
+
+	err = NewRepository(db).SetMembership(context.Background(), 1, "gold")
+	assert.ErrorIs(t, err, ErrTierNotFound)
+}
@@ -0,0 +1,52 @@
+package pricing
+
+import "errors"
+
+// Currency is the currency of every amount. Amounts are integer minor
+// units, so 399 is 3.99.
+const Currency = "USD"
+
+// ErrNoPrice is returned when an item has no entry in the price table.
+var ErrNoPrice = errors.New("movie has no price in that format and rental duration")
+
+// Item is one cart line to price.
+type Item struct {
+	MovieID    int
+	Format     string
+	RentalDays int
+}
+
+// Request asks for a quote for a user's cart. Region selects the tax rate
+// and may be "" when it is unknown.
+type Request struct {
+	UserID int
+	Region string
+	Items  []Item
+}
+
+// Line is a priced item.
+type Line struct {
+	Item
+	Amount int64
+}
+
+// Discount is a reduction of the subtotal, such as a promotion.
+type Discount struct {
+	Code        string `json:"code" xml:"code"`
+	Description string `json:"description" xml:"description"`
+	Amount      int64  `json:"amount" xml:"amount"`
+}
+
+// Quote is a fully priced cart. Lines are in the order of the request.
+type Quote struct {
+	Currency  string
+	Lines     []Line
+	Subtotal  int64
+	Discounts []Discount
+	// DiscountTotal never exceeds Subtotal.
+	DiscountTotal int64
+	// TaxRate is in basis points; Tax is charged on the discounted subtotal.
+	TaxRate int
+	Tax     int64
+	Total   int64
+}
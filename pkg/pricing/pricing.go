@@ -0,0 +1,73 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Discounter contributes discounts to a quote.
+type Discounter interface {
+	// Discounts returns the discounts that apply to req, whose lines have
+	// already been priced.
+	Discounts(ctx context.Context, req Request, lines []Line) ([]Discount, error)
+}
+
+// Pricer prices carts from the price table.
+type Pricer struct {
+	repo        Repository
+	discounters []Discounter
+}
+
+func NewPricer(repo Repository, discounters ...Discounter) *Pricer {
+	return &Pricer{repo: repo, discounters: discounters}
+}
+
+// Price returns a quote for req. It fails with ErrNoPrice if any item is
+// missing from the price table.
+func (p *Pricer) Price(ctx context.Context, req Request) (Quote, error) {
+	q := Quote{Currency: Currency, Lines: make([]Line, len(req.Items)), Discounts: []Discount{}}
+
+	if len(req.Items) > 0 {
+		prices, err := p.repo.Prices(ctx, req.Items)
+		if err != nil {
+			return Quote{}, err
+		}
+		for i, item := range req.Items {
+			amount, ok := prices[item]
+			if !ok {
+				return Quote{}, fmt.Errorf("%w: movie %d, %s, %d days", ErrNoPrice, item.MovieID, item.Format, item.RentalDays)
+			}
+			q.Lines[i] = Line{Item: item, Amount: amount}
+			q.Subtotal += amount
+		}
+	}
+
+	for _, d := range p.discounters {
+		discounts, err := d.Discounts(ctx, req, q.Lines)
+		if err != nil {
+			return Quote{}, err
+		}
+		for _, discount := range discounts {
+			q.Discounts = append(q.Discounts, discount)
+			q.DiscountTotal += discount.Amount
+		}
+	}
+	if q.DiscountTotal > q.Subtotal {
+		q.DiscountTotal = q.Subtotal
+	}
+
+	rate, err := p.repo.TaxRate(ctx, req.Region)
+	if err != nil {
+		return Quote{}, err
+	}
+	q.TaxRate = rate
+	q.Tax = Tax(q.Subtotal-q.DiscountTotal, rate)
+	q.Total = q.Subtotal - q.DiscountTotal + q.Tax
+	return q, nil
+}
+
+// Tax returns rate basis points of amount, rounded half up to a whole
+// minor unit.
+func Tax(amount int64, rate int) int64 {
+	return (amount*int64(rate) + 5000) / 10000
+}
@@ -0,0 +1,128 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	PricesFunc  func(ctx context.Context, items []Item) (map[Item]int64, error)
+	TaxRateFunc func(ctx context.Context, region string) (int, error)
+}
+
+func (m *mockRepository) Prices(ctx context.Context, items []Item) (map[Item]int64, error) {
+	return m.PricesFunc(ctx, items)
+}
+func (m *mockRepository) TaxRate(ctx context.Context, region string) (int, error) {
+	return m.TaxRateFunc(ctx, region)
+}
+
+type discounterFunc func(ctx context.Context, req Request, lines []Line) ([]Discount, error)
+
+func (f discounterFunc) Discounts(ctx context.Context, req Request, lines []Line) ([]Discount, error) {
+	return f(ctx, req, lines)
+}
+
+var (
+	rental = Item{MovieID: 1, Format: "digital", RentalDays: 2}
+	disc   = Item{MovieID: 2, Format: "bluray", RentalDays: 7}
+)
+
+func newMockRepository(rate int) *mockRepository {
+	return &mockRepository{
+		PricesFunc: func(ctx context.Context, items []Item) (map[Item]int64, error) {
+			return map[Item]int64{rental: 399, disc: 1299}, nil
+		},
+		TaxRateFunc: func(ctx context.Context, region string) (int, error) {
+			return rate, nil
+		},
+	}
+}
+
+func TestPrice(t *testing.T) {
+	p := NewPricer(newMockRepository(2000))
+
+	q, err := p.Price(context.Background(), Request{UserID: 1, Region: "GB", Items: []Item{disc, rental}})
+	assert.NoError(t, err)
+	assert.Equal(t, Currency, q.Currency)
+	assert.Equal(t, []Line{{Item: disc, Amount: 1299}, {Item: rental, Amount: 399}}, q.Lines)
+	assert.Equal(t, int64(1698), q.Subtotal)
+	assert.Equal(t, 2000, q.TaxRate)
+	assert.Equal(t, int64(340), q.Tax)
+	assert.Equal(t, int64(2038), q.Total)
+	assert.Empty(t, q.Discounts)
+}
+
+func TestPrice_EmptyCart(t *testing.T) {
+	repo := newMockRepository(0)
+	repo.PricesFunc = func(ctx context.Context, items []Item) (map[Item]int64, error) {
+		t.Fatal("empty carts need no prices")
+		return nil, nil
+	}
+
+	q, err := NewPricer(repo).Price(context.Background(), Request{UserID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, Quote{Currency: Currency, Lines: []Line{}, Discounts: []Discount{}}, q)
+}
+
+func TestPrice_Discounts(t *testing.T) {
+	tenOff := discounterFunc(func(ctx context.Context, req Request, lines []Line) ([]Discount, error) {
+		assert.Len(t, lines, 1)
+		return []Discount{{Code: "TEN", Amount: 100}}, nil
+	})
+	p := NewPricer(newMockRepository(1000), tenOff)
+
+	q, err := p.Price(context.Background(), Request{Items: []Item{rental}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), q.DiscountTotal)
+	assert.Equal(t, int64(30), q.Tax)
+	assert.Equal(t, int64(329), q.Total)
+}
+
+func TestPrice_DiscountsCappedAtSubtotal(t *testing.T) {
+	big := discounterFunc(func(ctx context.Context, req Request, lines []Line) ([]Discount, error) {
+		return []Discount{{Code: "A", Amount: 300}, {Code: "B", Amount: 300}}, nil
+	})
+	p := NewPricer(newMockRepository(2000), big)
+
+	q, err := p.Price(context.Background(), Request{Items: []Item{rental}})
+	assert.NoError(t, err)
+	assert.Len(t, q.Discounts, 2)
+	assert.Equal(t, int64(399), q.DiscountTotal)
+	assert.Equal(t, int64(0), q.Tax)
+	assert.Equal(t, int64(0), q.Total)
+}
+
+func TestPrice_NoPrice(t *testing.T) {
+	p := NewPricer(newMockRepository(0))
+
+	_, err := p.Price(context.Background(), Request{Items: []Item{{MovieID: 3, Format: "dvd", RentalDays: 2}}})
+	assert.ErrorIs(t, err, ErrNoPrice)
+	assert.Contains(t, err.Error(), "movie 3")
+}
+
+func TestPrice_Errors(t *testing.T) {
+	repo := newMockRepository(0)
+	repo.TaxRateFunc = func(ctx context.Context, region string) (int, error) {
+		return 0, errors.New("db error")
+	}
+	_, err := NewPricer(repo).Price(context.Background(), Request{Items: []Item{rental}})
+	assert.EqualError(t, err, "db error")
+
+	failing := discounterFunc(func(ctx context.Context, req Request, lines []Line) ([]Discount, error) {
+		return nil, errors.New("promo error")
+	})
+	_, err = NewPricer(newMockRepository(0), failing).Price(context.Background(), Request{Items: []Item{rental}})
+	assert.EqualError(t, err, "promo error")
+}
+
+func TestTax(t *testing.T) {
+	assert.Equal(t, int64(0), Tax(0, 2000))
+	assert.Equal(t, int64(80), Tax(399, 2000))
+	assert.Equal(t, int64(1), Tax(5, 1000))
+	assert.Equal(t, int64(0), Tax(4, 1000))
+	assert.Equal(t, int64(0), Tax(399, 0))
+}
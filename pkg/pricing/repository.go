@@ -0,0 +1,63 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// Prices returns the amount of each item that has a price. Items
+	// without one are absent from the map.
+	Prices(ctx context.Context, items []Item) (map[Item]int64, error)
+	// TaxRate returns the tax rate for region in basis points, or 0 if the
+	// region is unknown or untaxed.
+	TaxRate(ctx context.Context, region string) (int, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Prices(ctx context.Context, items []Item) (map[Item]int64, error) {
+	movieIDs := make([]int64, len(items))
+	for i, item := range items {
+		movieIDs[i] = int64(item.MovieID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT movie_id, format, rental_days, amount FROM movie_prices
+		WHERE movie_id = ANY($1::int[])`, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[Item]int64)
+	for rows.Next() {
+		var item Item
+		var amount int64
+		if err := rows.Scan(&item.MovieID, &item.Format, &item.RentalDays, &amount); err != nil {
+			return nil, err
+		}
+		prices[item] = amount
+	}
+	return prices, rows.Err()
+}
+
+func (r *repository) TaxRate(ctx context.Context, region string) (int, error) {
+	if region == "" {
+		return 0, nil
+	}
+	var rate int
+	err := r.db.QueryRowContext(ctx, "SELECT rate_bps FROM tax_rates WHERE region = $1", region).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return rate, err
+}
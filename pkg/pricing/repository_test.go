@@ -0,0 +1,70 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrices(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT movie_id, format, rental_days, amount FROM movie_prices WHERE movie_id = ANY\(\$1::int\[\]\)`).
+		WithArgs("{1,2}").
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "format", "rental_days", "amount"}).
+			AddRow(1, "digital", 2, 399).
+			AddRow(1, "digital", 7, 599).
+			AddRow(2, "dvd", 7, 899))
+
+	repo := NewRepository(db)
+	prices, err := repo.Prices(context.Background(), []Item{{MovieID: 1, Format: "digital", RentalDays: 2}, {MovieID: 2, Format: "dvd", RentalDays: 7}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(399), prices[Item{MovieID: 1, Format: "digital", RentalDays: 2}])
+	assert.Equal(t, int64(899), prices[Item{MovieID: 2, Format: "dvd", RentalDays: 7}])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrices_DBError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM movie_prices`).WillReturnError(errors.New("db error"))
+
+	repo := NewRepository(db)
+	prices, err := repo.Prices(context.Background(), []Item{{MovieID: 1}})
+	assert.Error(t, err)
+	assert.Nil(t, prices)
+}
+
+func TestTaxRate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT rate_bps FROM tax_rates WHERE region = \$1`).
+		WithArgs("GB").
+		WillReturnRows(sqlmock.NewRows([]string{"rate_bps"}).AddRow(2000))
+	mock.ExpectQuery(`SELECT rate_bps FROM tax_rates WHERE region = \$1`).
+		WithArgs("FR").
+		WillReturnRows(sqlmock.NewRows([]string{"rate_bps"}))
+
+	repo := NewRepository(db)
+	rate, err := repo.TaxRate(context.Background(), "GB")
+	assert.NoError(t, err)
+	assert.Equal(t, 2000, rate)
+
+	rate, err = repo.TaxRate(context.Background(), "FR")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, rate)
+
+	rate, err = repo.TaxRate(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, rate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
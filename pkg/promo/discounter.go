@@ -0,0 +1,61 @@
+package promo
+
+import (
+	"context"
+	"time"
+
+	"movie-rental/pkg/pricing"
+)
+
+// Discounter prices the promo code applied to a user's cart. It satisfies
+// pricing.Discounter. A code that has stopped applying since, for example
+// because it expired or the cart changed, is skipped rather than reported.
+type Discounter struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewDiscounter(repo Repository, now func() time.Time) *Discounter {
+	return &Discounter{repo: repo, now: now}
+}
+
+func (d *Discounter) Discounts(ctx context.Context, req pricing.Request, lines []pricing.Line) ([]pricing.Discount, error) {
+	if req.UserID <= 0 {
+		return nil, nil
+	}
+	p, err := d.repo.CartPromo(ctx, req.UserID)
+	if err != nil || p == nil {
+		return nil, err
+	}
+
+	amount, err := Evaluate(ctx, d.repo, *p, req.UserID, lines, d.now())
+	if _, ineligible := reasonCode(err); ineligible {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, nil
+	}
+	return []pricing.Discount{{Code: p.Code, Description: p.Description, Amount: amount}}, nil
+}
+
+// Evaluate returns the discount p gives on the user's priced lines, or the
+// reason it does not apply.
+func Evaluate(ctx context.Context, repo Repository, p Promo, userID int, lines []pricing.Line, now time.Time) (int64, error) {
+	movieIDs := make([]int, len(lines))
+	for i, l := range lines {
+		movieIDs[i] = l.MovieID
+	}
+	f, err := repo.Facts(ctx, p.Code, userID, movieIDs)
+	if err != nil {
+		return 0, err
+	}
+	f.Now = now
+	f.Lines = lines
+
+	if err := p.Check(f); err != nil {
+		return 0, err
+	}
+	return p.Discount(f), nil
+}
@@ -0,0 +1,199 @@
+package promo
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/pricing"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeDuplicateCode = "duplicate_code"
+	// CodeUsageLimit is also used when a code runs out of uses at checkout.
+	CodeUsageLimit = "promo_usage_limit"
+)
+
+// reasonCodes are the error codes for the reasons a promo code does not
+// apply to a cart.
+var reasonCodes = map[error]string{
+	ErrNotStarted:      "promo_not_started",
+	ErrExpired:         "promo_expired",
+	ErrUsageLimit:      CodeUsageLimit,
+	ErrNotNewCustomer:  "promo_new_customers_only",
+	ErrBelowMinimum:    "promo_below_minimum",
+	ErrNoEligibleItems: "promo_no_eligible_items",
+}
+
+// ApplyPromoHandler applies a promo code to a user's cart and responds with
+// the cart revalidated and priced with it. A code that does not apply to the cart as it is
+// now is refused with 422 and a code naming the reason.
+func ApplyPromoHandler(repo Repository, carts cart.Repository, pricer cart.Pricer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := userIDParam(c)
+		if !ok {
+			return
+		}
+		var req ApplyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "code is required")
+			return
+		}
+
+		p, err := repo.GetPromo(c.Request.Context(), NormalizeCode(req.Code))
+		if errors.Is(err, ErrPromoNotFound) {
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+			return
+		} else if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		lines, err := carts.GetCartItems(c.Param("user_id"))
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		userRegion, err := region.Resolve(c, userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		review, ok := reviewCart(c, carts, pricer, userID, userRegion, lines)
+		if !ok {
+			return
+		}
+		if _, err := Evaluate(c.Request.Context(), repo, *p, userID, review.Quote.Lines, time.Now()); err != nil {
+			if code, ok := reasonCode(err); ok {
+				api.Error(c, http.StatusUnprocessableEntity, code, err.Error())
+			} else {
+				api.InternalError(c, err)
+			}
+			return
+		}
+
+		if err := repo.ApplyToCart(c.Request.Context(), userID, p.Code); err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		review, ok = reviewCart(c, carts, pricer, userID, userRegion, lines)
+		if !ok {
+			return
+		}
+		api.OK(c, http.StatusOK, cart.NewCartResponse(userID, review))
+	}
+}
+
+func RemovePromoHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := userIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := repo.RemoveFromCart(c.Request.Context(), userID); err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func CreatePromoHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PromoRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "code and a kind of percentage, fixed or buy_get are required")
+			return
+		}
+		if msg := validate(req); msg != "" {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, msg)
+			return
+		}
+
+		p := req.Promo()
+		if err := repo.CreatePromo(c.Request.Context(), p); err != nil {
+			if errors.Is(err, ErrDuplicateCode) {
+				api.Error(c, http.StatusConflict, CodeDuplicateCode, err.Error())
+			} else {
+				api.InternalError(c, err)
+			}
+			return
+		}
+
+		api.OK(c, http.StatusCreated, p)
+	}
+}
+
+func ListPromosHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		promos, err := repo.ListPromos(c.Request.Context())
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if promos == nil {
+			promos = []Promo{}
+		}
+
+		api.List(c, promos, len(promos))
+	}
+}
+
+// validate checks that req configures its kind of rule, returning why not.
+func validate(req PromoRequest) string {
+	switch {
+	case req.Kind == KindPercentage && req.Percent == 0:
+		return "percentage promo codes need a percent between 1 and 100"
+	case req.Kind == KindFixed && req.Amount == 0:
+		return "fixed promo codes need a positive amount"
+	case req.Kind == KindBuyGet && req.Get == 0:
+		return "buy_get promo codes need a positive get"
+	case req.StartsAt != nil && req.ExpiresAt != nil && !req.ExpiresAt.After(*req.StartsAt):
+		return "expires_at must be after starts_at"
+	}
+	return ""
+}
+
+// reasonCode returns the error code for err if it is a reason a promo code
+// does not apply.
+func reasonCode(err error) (string, bool) {
+	for reason, code := range reasonCodes {
+		if errors.Is(err, reason) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// reviewCart revalidates and prices the user's cart lines. Promo codes
+// apply to the lines that can still be rented.
+func reviewCart(c *gin.Context, carts cart.Repository, pricer cart.Pricer, userID int, userRegion string, lines []cart.Line) (cart.Review, bool) {
+	review, err := cart.ReviewCart(c.Request.Context(), carts, pricer, userID, userRegion, lines, time.Now())
+	if errors.Is(err, pricing.ErrNoPrice) {
+		api.Error(c, http.StatusConflict, cart.CodePriceUnavailable, err.Error())
+		return review, false
+	} else if err != nil {
+		api.InternalError(c, err)
+		return review, false
+	}
+	return review, true
+}
+
+func userIDParam(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,257 @@
+package promo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/pricing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	CreatePromoFunc func(p Promo) error
+	ListPromosFunc  func() ([]Promo, error)
+	promos          map[string]Promo
+	applied         map[int]string
+	facts           Facts
+}
+
+func newMockRepository(promos ...Promo) *mockRepository {
+	m := &mockRepository{promos: make(map[string]Promo), applied: make(map[int]string), facts: Facts{NewCustomer: true}}
+	for _, p := range promos {
+		m.promos[p.Code] = p
+	}
+	return m
+}
+
+func (m *mockRepository) CreatePromo(_ context.Context, p Promo) error {
+	return m.CreatePromoFunc(p)
+}
+func (m *mockRepository) ListPromos(_ context.Context) ([]Promo, error) {
+	return m.ListPromosFunc()
+}
+func (m *mockRepository) GetPromo(_ context.Context, code string) (*Promo, error) {
+	p, ok := m.promos[code]
+	if !ok {
+		return nil, ErrPromoNotFound
+	}
+	return &p, nil
+}
+func (m *mockRepository) CartPromo(ctx context.Context, userID int) (*Promo, error) {
+	code, ok := m.applied[userID]
+	if !ok {
+		return nil, nil
+	}
+	return m.GetPromo(ctx, code)
+}
+func (m *mockRepository) ApplyToCart(_ context.Context, userID int, code string) error {
+	m.applied[userID] = code
+	return nil
+}
+func (m *mockRepository) RemoveFromCart(_ context.Context, userID int) error {
+	delete(m.applied, userID)
+	return nil
+}
+func (m *mockRepository) Facts(_ context.Context, code string, userID int, movieIDs []int) (Facts, error) {
+	f := m.facts
+	f.Genres = map[int]string{1: "Horror", 2: "Drama"}
+	return f, nil
+}
+
+// mockCarts holds one movie of each genre, both priced at 500.
+type mockCarts struct{ cart.Repository }
+
+func (mockCarts) GetCartItems(userID string) ([]cart.Line, error) {
+	return []cart.Line{
+		{Movie: movies.Movie{MovieID: 1, Genre: "Horror"}, Format: cart.FormatDigital, RentalDays: 2},
+		{Movie: movies.Movie{MovieID: 2, Genre: "Drama"}, Format: cart.FormatDigital, RentalDays: 2},
+	}, nil
+}
+
+func (mockCarts) CheckItems(_ context.Context, items []cart.Item, region string) ([]cart.ItemCheck, error) {
+	checks := make([]cart.ItemCheck, len(items))
+	for i := range checks {
+		checks[i] = cart.ItemCheck{Licensed: true, Offered: true, Priced: true}
+	}
+	return checks, nil
+}
+
+type mockPrices struct{}
+
+func (mockPrices) Prices(_ context.Context, items []pricing.Item) (map[pricing.Item]int64, error) {
+	prices := make(map[pricing.Item]int64)
+	for _, item := range items {
+		prices[item] = 500
+	}
+	return prices, nil
+}
+func (mockPrices) TaxRate(_ context.Context, region string) (int, error) {
+	return 0, nil
+}
+
+func setupRouter(repo *mockRepository) *gin.Engine {
+	pricer := pricing.NewPricer(mockPrices{}, NewDiscounter(repo, time.Now))
+	router := gin.Default()
+	router.POST("/v2/cart/:user_id/promo", ApplyPromoHandler(repo, mockCarts{}, pricer))
+	router.DELETE("/v2/cart/:user_id/promo", RemovePromoHandler(repo))
+	router.GET("/v2/cart/:user_id", cart.ViewCartV2Handler(mockCarts{}, pricer))
+	router.POST("/v2/admin/promos", CreatePromoHandler(repo))
+	router.GET("/v2/admin/promos", ListPromosHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+var horror = Promo{Code: "OCTHORROR", Description: "20% off horror", Kind: KindPercentage, Percent: 20, Genre: "horror"}
+
+func TestApplyPromoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMockRepository(horror)
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/cart/1/promo", `{"code":" octhorror "}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "OCTHORROR", repo.applied[1])
+	assert.Contains(t, recorder.Body.String(),
+		`"subtotal":1000,"discounts":[{"code":"OCTHORROR","description":"20% off horror","amount":100}],"discount_total":100,"tax":0,"total":900`)
+
+	recorder = serve(router, "GET", "/v2/cart/1", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"discount_total":100`)
+}
+
+func TestApplyPromoHandler_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(newMockRepository())
+
+	recorder := serve(router, "POST", "/v2/cart/1/promo", `{"code":"NOPE"}`)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "not_found")
+}
+
+func TestApplyPromoHandler_Ineligible(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	expired := time.Now().Add(-time.Hour)
+	repo := newMockRepository(
+		Promo{Code: "OLD", Kind: KindFixed, Amount: 100, ExpiresAt: &expired},
+		Promo{Code: "WELCOME", Kind: KindPercentage, Percent: 100, NewCustomersOnly: true},
+		Promo{Code: "BIG", Kind: KindFixed, Amount: 100, MinSubtotal: 5000},
+	)
+	repo.facts.NewCustomer = false
+	router := setupRouter(repo)
+
+	for code, reason := range map[string]string{
+		"OLD":     "promo_expired",
+		"WELCOME": "promo_new_customers_only",
+		"BIG":     "promo_below_minimum",
+	} {
+		recorder := serve(router, "POST", "/v2/cart/1/promo", `{"code":"`+code+`"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code, code)
+		assert.Contains(t, recorder.Body.String(), `"code":"`+reason+`"`)
+	}
+	assert.Empty(t, repo.applied)
+}
+
+func TestDiscounter_SkipsPromoThatNoLongerApplies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMockRepository(Promo{Code: "WELCOME", Kind: KindPercentage, Percent: 100, NewCustomersOnly: true})
+	repo.applied[1] = "WELCOME"
+	repo.facts.NewCustomer = false
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/cart/1", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"discounts":[],"discount_total":0`)
+}
+
+func TestRemovePromoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMockRepository(horror)
+	repo.applied[1] = horror.Code
+	router := setupRouter(repo)
+
+	recorder := serve(router, "DELETE", "/v2/cart/1/promo", "")
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, repo.applied)
+}
+
+func TestCreatePromoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMockRepository()
+	repo.CreatePromoFunc = func(p Promo) error {
+		assert.Equal(t, "B2G1", p.Code)
+		assert.Equal(t, KindBuyGet, p.Kind)
+		return nil
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/admin/promos", `{"code":"b2g1","kind":"buy_get","buy":2,"get":1,"max_uses":1000}`)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"code":"B2G1"`)
+}
+
+func TestCreatePromoHandler_Invalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(newMockRepository())
+
+	for _, body := range []string{
+		`{"kind":"fixed","amount":100}`,
+		`{"code":"X","kind":"bogo"}`,
+		`{"code":"X","kind":"percentage","percent":120}`,
+		`{"code":"X","kind":"percentage"}`,
+		`{"code":"X","kind":"fixed"}`,
+		`{"code":"X","kind":"buy_get","buy":2}`,
+		`{"code":"X","kind":"fixed","amount":1,"starts_at":"2026-11-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z"}`,
+	} {
+		recorder := serve(router, "POST", "/v2/admin/promos", body)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+}
+
+func TestCreatePromoHandler_Duplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMockRepository()
+	repo.CreatePromoFunc = func(p Promo) error { return ErrDuplicateCode }
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/admin/promos", `{"code":"X","kind":"fixed","amount":100}`)
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeDuplicateCode)
+}
+
+func TestListPromosHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMockRepository()
+	repo.ListPromosFunc = func() ([]Promo, error) { return nil, errors.New("db error") }
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/admin/promos", "")
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+	repo.ListPromosFunc = func() ([]Promo, error) { return []Promo{horror}, nil }
+	recorder = serve(router, "GET", "/v2/admin/promos", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"count":1`)
+}
@@ -0,0 +1,103 @@
+package promo
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Kind is how a promo code computes its discount.
+type Kind string
+
+const (
+	// KindPercentage takes Percent off the eligible lines.
+	KindPercentage Kind = "percentage"
+	// KindFixed takes Amount off the eligible lines.
+	KindFixed Kind = "fixed"
+	// KindBuyGet makes the cheapest Get of every Buy+Get eligible lines free.
+	KindBuyGet Kind = "buy_get"
+)
+
+var (
+	ErrPromoNotFound = errors.New("promo code not found")
+	ErrDuplicateCode = errors.New("a promo code with this code already exists")
+
+	// Reasons a promo code cannot be applied to a cart.
+	ErrNotStarted      = errors.New("promo code is not active yet")
+	ErrExpired         = errors.New("promo code has expired")
+	ErrUsageLimit      = errors.New("promo code has reached its usage limit")
+	ErrNotNewCustomer  = errors.New("promo code is for new customers only")
+	ErrBelowMinimum    = errors.New("cart subtotal is below the promo code's minimum")
+	ErrNoEligibleItems = errors.New("no items in the cart qualify for the promo code")
+)
+
+// Promo is a promo code and the rule it applies. Amounts are in minor
+// units. Zero MaxUses, MaxUsesPerUser and MaxDiscount mean no limit.
+type Promo struct {
+	XMLName          xml.Name   `json:"-" xml:"promo"`
+	Code             string     `json:"code" xml:"code"`
+	Description      string     `json:"description" xml:"description"`
+	Kind             Kind       `json:"kind" xml:"kind"`
+	Percent          int        `json:"percent" xml:"percent"`
+	Amount           int64      `json:"amount" xml:"amount"`
+	Buy              int        `json:"buy" xml:"buy"`
+	Get              int        `json:"get" xml:"get"`
+	MaxDiscount      int64      `json:"max_discount" xml:"max_discount"`
+	Genre            string     `json:"genre" xml:"genre"`
+	NewCustomersOnly bool       `json:"new_customers_only" xml:"new_customers_only"`
+	MinSubtotal      int64      `json:"min_subtotal" xml:"min_subtotal"`
+	MaxUses          int        `json:"max_uses" xml:"max_uses"`
+	MaxUsesPerUser   int        `json:"max_uses_per_user" xml:"max_uses_per_user"`
+	StartsAt         *time.Time `json:"starts_at" xml:"starts_at,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at" xml:"expires_at,omitempty"`
+}
+
+// PromoRequest creates a promo code.
+type PromoRequest struct {
+	Code             string     `json:"code" binding:"required,max=32"`
+	Description      string     `json:"description"`
+	Kind             Kind       `json:"kind" binding:"required,oneof=percentage fixed buy_get"`
+	Percent          int        `json:"percent" binding:"gte=0,lte=100"`
+	Amount           int64      `json:"amount" binding:"gte=0"`
+	Buy              int        `json:"buy" binding:"gte=0"`
+	Get              int        `json:"get" binding:"gte=0"`
+	MaxDiscount      int64      `json:"max_discount" binding:"gte=0"`
+	Genre            string     `json:"genre" binding:"max=255"`
+	NewCustomersOnly bool       `json:"new_customers_only"`
+	MinSubtotal      int64      `json:"min_subtotal" binding:"gte=0"`
+	MaxUses          int        `json:"max_uses" binding:"gte=0"`
+	MaxUsesPerUser   int        `json:"max_uses_per_user" binding:"gte=0"`
+	StartsAt         *time.Time `json:"starts_at"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+}
+
+func (r PromoRequest) Promo() Promo {
+	return Promo{
+		Code:             NormalizeCode(r.Code),
+		Description:      r.Description,
+		Kind:             r.Kind,
+		Percent:          r.Percent,
+		Amount:           r.Amount,
+		Buy:              r.Buy,
+		Get:              r.Get,
+		MaxDiscount:      r.MaxDiscount,
+		Genre:            r.Genre,
+		NewCustomersOnly: r.NewCustomersOnly,
+		MinSubtotal:      r.MinSubtotal,
+		MaxUses:          r.MaxUses,
+		MaxUsesPerUser:   r.MaxUsesPerUser,
+		StartsAt:         r.StartsAt,
+		ExpiresAt:        r.ExpiresAt,
+	}
+}
+
+// ApplyRequest applies a promo code to a cart.
+type ApplyRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// NormalizeCode makes codes case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,151 @@
+package promo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	CreatePromo(ctx context.Context, p Promo) error
+	ListPromos(ctx context.Context) ([]Promo, error)
+	GetPromo(ctx context.Context, code string) (*Promo, error)
+	// CartPromo returns the promo code applied to the user's cart, or nil
+	// if there is none.
+	CartPromo(ctx context.Context, userID int) (*Promo, error)
+	// ApplyToCart applies code to the user's cart, replacing any other.
+	ApplyToCart(ctx context.Context, userID int, code string) error
+	RemoveFromCart(ctx context.Context, userID int) error
+	// Facts gathers everything but the lines and time that a promo code is
+	// evaluated against.
+	Facts(ctx context.Context, code string, userID int, movieIDs []int) (Facts, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+const promoColumns = `code, description, kind, percent, amount, buy_quantity, get_quantity,
+	COALESCE(max_discount, 0), COALESCE(genre, ''), new_customers_only, min_subtotal,
+	COALESCE(max_uses, 0), COALESCE(max_uses_per_user, 0), starts_at, expires_at`
+
+func scanPromo(row interface{ Scan(...interface{}) error }) (*Promo, error) {
+	var p Promo
+	err := row.Scan(&p.Code, &p.Description, &p.Kind, &p.Percent, &p.Amount, &p.Buy, &p.Get,
+		&p.MaxDiscount, &p.Genre, &p.NewCustomersOnly, &p.MinSubtotal,
+		&p.MaxUses, &p.MaxUsesPerUser, &p.StartsAt, &p.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *repository) CreatePromo(ctx context.Context, p Promo) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO promo_codes (code, description, kind, percent, amount, buy_quantity, get_quantity,
+			max_discount, genre, new_customers_only, min_subtotal, max_uses, max_uses_per_user, starts_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, ''), $10, $11, NULLIF($12, 0), NULLIF($13, 0), $14, $15)`,
+		p.Code, p.Description, p.Kind, p.Percent, p.Amount, p.Buy, p.Get,
+		p.MaxDiscount, p.Genre, p.NewCustomersOnly, p.MinSubtotal, p.MaxUses, p.MaxUsesPerUser, p.StartsAt, p.ExpiresAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateCode
+	}
+	return err
+}
+
+func (r *repository) ListPromos(ctx context.Context) ([]Promo, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+promoColumns+" FROM promo_codes ORDER BY created_at DESC, code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promos []Promo
+	for rows.Next() {
+		p, err := scanPromo(rows)
+		if err != nil {
+			return nil, err
+		}
+		promos = append(promos, *p)
+	}
+	return promos, rows.Err()
+}
+
+func (r *repository) GetPromo(ctx context.Context, code string) (*Promo, error) {
+	p, err := scanPromo(r.db.QueryRowContext(ctx, "SELECT "+promoColumns+" FROM promo_codes WHERE code = $1", code))
+	if err == sql.ErrNoRows {
+		return nil, ErrPromoNotFound
+	}
+	return p, err
+}
+
+func (r *repository) CartPromo(ctx context.Context, userID int) (*Promo, error) {
+	p, err := scanPromo(r.db.QueryRowContext(ctx, "SELECT "+promoColumns+` FROM promo_codes
+		WHERE code = (SELECT code FROM cart_promos WHERE user_id = $1)`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *repository) ApplyToCart(ctx context.Context, userID int, code string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO cart_promos (user_id, code, applied_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET code = EXCLUDED.code, applied_at = EXCLUDED.applied_at`,
+		userID, code, time.Now())
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrPromoNotFound
+	}
+	return err
+}
+
+func (r *repository) RemoveFromCart(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM cart_promos WHERE user_id = $1", userID)
+	return err
+}
+
+func (r *repository) Facts(ctx context.Context, code string, userID int, movieIDs []int) (Facts, error) {
+	f := Facts{Genres: make(map[int]string)}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			NOT EXISTS (SELECT 1 FROM activity_events WHERE user_id = $2 AND kind = 'rental'),
+			(SELECT COUNT(*) FROM promo_redemptions WHERE code = $1),
+			(SELECT COUNT(*) FROM promo_redemptions WHERE code = $1 AND user_id = $2)`,
+		code, userID).Scan(&f.NewCustomer, &f.Uses, &f.UserUses)
+	if err != nil {
+		return Facts{}, err
+	}
+	if len(movieIDs) == 0 {
+		return f, nil
+	}
+
+	ids := make([]int64, len(movieIDs))
+	for i, id := range movieIDs {
+		ids[i] = int64(id)
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT movie_id, COALESCE(genre, '') FROM movies WHERE movie_id = ANY($1::int[])", pq.Array(ids))
+	if err != nil {
+		return Facts{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var genre string
+		if err := rows.Scan(&id, &genre); err != nil {
+			return Facts{}, err
+		}
+		f.Genres[id] = genre
+	}
+	return f, rows.Err()
+}
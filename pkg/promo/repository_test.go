@@ -0,0 +1,108 @@
+package promo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var promoRowColumns = []string{"code", "description", "kind", "percent", "amount", "buy_quantity", "get_quantity",
+	"max_discount", "genre", "new_customers_only", "min_subtotal", "max_uses", "max_uses_per_user", "starts_at", "expires_at"}
+
+func TestCreatePromo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO promo_codes`).
+		WithArgs("OCTHORROR", "20% off horror", KindPercentage, 20, int64(0), 0, 0, int64(0), "Horror", false, int64(0), 0, 0, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO promo_codes`).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	repo := NewRepository(db)
+	p := Promo{Code: "OCTHORROR", Description: "20% off horror", Kind: KindPercentage, Percent: 20, Genre: "Horror"}
+	assert.NoError(t, repo.CreatePromo(context.Background(), p))
+	assert.ErrorIs(t, repo.CreatePromo(context.Background(), p), ErrDuplicateCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPromo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT code, (.+) FROM promo_codes WHERE code = \$1`).
+		WithArgs("FREE").
+		WillReturnRows(sqlmock.NewRows(promoRowColumns).
+			AddRow("FREE", "First rental free", "percentage", 100, 0, 0, 0, 599, "", true, 0, 0, 1, nil, nil))
+	mock.ExpectQuery(`FROM promo_codes WHERE code = \$1`).
+		WithArgs("NOPE").
+		WillReturnRows(sqlmock.NewRows(promoRowColumns))
+
+	repo := NewRepository(db)
+	p, err := repo.GetPromo(context.Background(), "FREE")
+	assert.NoError(t, err)
+	assert.Equal(t, KindPercentage, p.Kind)
+	assert.Equal(t, int64(599), p.MaxDiscount)
+	assert.True(t, p.NewCustomersOnly)
+	assert.Equal(t, 1, p.MaxUsesPerUser)
+
+	_, err = repo.GetPromo(context.Background(), "NOPE")
+	assert.ErrorIs(t, err, ErrPromoNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCartPromo_None(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM promo_codes WHERE code = \(SELECT code FROM cart_promos WHERE user_id = \$1\)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(promoRowColumns))
+
+	repo := NewRepository(db)
+	p, err := repo.CartPromo(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestApplyToCart(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO cart_promos (.+) ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs(1, "FREE", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewRepository(db)
+	assert.NoError(t, repo.ApplyToCart(context.Background(), 1, "FREE"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT NOT EXISTS \(SELECT 1 FROM activity_events WHERE user_id = \$2 AND kind = 'rental'\), (.+) FROM promo_redemptions`).
+		WithArgs("FREE", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"new_customer", "uses", "user_uses"}).AddRow(false, 12, 1))
+	mock.ExpectQuery(`SELECT movie_id, COALESCE\(genre, ''\) FROM movies WHERE movie_id = ANY\(\$1::int\[\]\)`).
+		WithArgs("{2,3}").
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "genre"}).AddRow(2, "Horror").AddRow(3, "Drama"))
+
+	repo := NewRepository(db)
+	f, err := repo.Facts(context.Background(), "FREE", 1, []int{2, 3})
+	assert.NoError(t, err)
+	assert.False(t, f.NewCustomer)
+	assert.Equal(t, 12, f.Uses)
+	assert.Equal(t, 1, f.UserUses)
+	assert.Equal(t, map[int]string{2: "Horror", 3: "Drama"}, f.Genres)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
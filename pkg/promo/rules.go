@@ -0,0 +1,111 @@
+package promo
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"movie-rental/pkg/pricing"
+)
+
+// Facts are what a promo code is evaluated against.
+type Facts struct {
+	Now   time.Time
+	Lines []pricing.Line
+	// Genres maps the movie of each line to its genre.
+	Genres      map[int]string
+	NewCustomer bool
+	// Uses counts past redemptions by everyone, UserUses by this user.
+	Uses     int
+	UserUses int
+}
+
+// Check returns why p cannot be applied, or nil if it can.
+func (p Promo) Check(f Facts) error {
+	switch {
+	case p.StartsAt != nil && f.Now.Before(*p.StartsAt):
+		return ErrNotStarted
+	case p.ExpiresAt != nil && !f.Now.Before(*p.ExpiresAt):
+		return ErrExpired
+	case p.MaxUses > 0 && f.Uses >= p.MaxUses,
+		p.MaxUsesPerUser > 0 && f.UserUses >= p.MaxUsesPerUser:
+		return ErrUsageLimit
+	case p.NewCustomersOnly && !f.NewCustomer:
+		return ErrNotNewCustomer
+	case subtotal(f.Lines) < p.MinSubtotal:
+		return ErrBelowMinimum
+	case len(p.eligible(f)) == 0:
+		return ErrNoEligibleItems
+	}
+	return nil
+}
+
+// Discount returns the amount p takes off the cart described by f. It does
+// not check eligibility.
+func (p Promo) Discount(f Facts) int64 {
+	eligible := p.eligible(f)
+
+	var amount int64
+	switch p.Kind {
+	case KindPercentage:
+		amount = (subtotal(eligible)*int64(p.Percent) + 50) / 100
+	case KindFixed:
+		amount = p.Amount
+	case KindBuyGet:
+		amount = buyGet(eligible, p.Buy, p.Get)
+	}
+
+	if p.MaxDiscount > 0 && amount > p.MaxDiscount {
+		amount = p.MaxDiscount
+	}
+	if limit := subtotal(eligible); amount > limit {
+		amount = limit
+	}
+	return amount
+}
+
+// eligible returns the lines p applies to: those in p.Genre, or all of
+// them.
+func (p Promo) eligible(f Facts) []pricing.Line {
+	if p.Genre == "" {
+		return f.Lines
+	}
+	genre := strings.ToLower(p.Genre)
+	var lines []pricing.Line
+	for _, l := range f.Lines {
+		if strings.Contains(strings.ToLower(f.Genres[l.MovieID]), genre) {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// buyGet makes the cheapest get lines of every buy+get free, most
+// expensive lines first, so "buy 2 get 1" on 5, 4, 3 and 2 frees the 3.
+func buyGet(lines []pricing.Line, buy, get int) int64 {
+	group := buy + get
+	if get == 0 {
+		return 0
+	}
+	amounts := make([]int64, len(lines))
+	for i, l := range lines {
+		amounts[i] = l.Amount
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] > amounts[j] })
+
+	var free int64
+	for start := 0; start+group <= len(amounts); start += group {
+		for _, a := range amounts[start+buy : start+group] {
+			free += a
+		}
+	}
+	return free
+}
+
+func subtotal(lines []pricing.Line) int64 {
+	var total int64
+	for _, l := range lines {
+		total += l.Amount
+	}
+	return total
+}
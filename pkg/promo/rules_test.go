@@ -0,0 +1,88 @@
+package promo
+
+import (
+	"testing"
+	"time"
+
+	"movie-rental/pkg/pricing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func line(movieID int, amount int64) pricing.Line {
+	return pricing.Line{Item: pricing.Item{MovieID: movieID, Format: "digital", RentalDays: 2}, Amount: amount}
+}
+
+func facts(lines ...pricing.Line) Facts {
+	return Facts{
+		Now:         now,
+		Lines:       lines,
+		Genres:      map[int]string{1: "Horror", 2: "Comedy, Horror", 3: "Drama"},
+		NewCustomer: true,
+	}
+}
+
+func TestPromo_Check(t *testing.T) {
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	cart := facts(line(1, 399), line(3, 599))
+	returning := cart
+	returning.NewCustomer = false
+	used := cart
+	used.Uses, used.UserUses = 10, 1
+
+	cases := []struct {
+		name  string
+		promo Promo
+		facts Facts
+		want  error
+	}{
+		{"unrestricted", Promo{}, cart, nil},
+		{"not started", Promo{StartsAt: &after}, cart, ErrNotStarted},
+		{"expired", Promo{ExpiresAt: &before}, cart, ErrExpired},
+		{"expires exactly now", Promo{ExpiresAt: &now}, cart, ErrExpired},
+		{"within window", Promo{StartsAt: &before, ExpiresAt: &after}, cart, nil},
+		{"total limit", Promo{MaxUses: 10}, used, ErrUsageLimit},
+		{"per-user limit", Promo{MaxUsesPerUser: 1}, used, ErrUsageLimit},
+		{"under limits", Promo{MaxUses: 11, MaxUsesPerUser: 2}, used, nil},
+		{"new customers", Promo{NewCustomersOnly: true}, returning, ErrNotNewCustomer},
+		{"minimum subtotal", Promo{MinSubtotal: 999}, cart, ErrBelowMinimum},
+		{"minimum subtotal met", Promo{MinSubtotal: 998}, cart, nil},
+		{"genre", Promo{Genre: "horror"}, cart, nil},
+		{"no items in genre", Promo{Genre: "western"}, cart, ErrNoEligibleItems},
+		{"empty cart", Promo{}, facts(), ErrNoEligibleItems},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, tc.promo.Check(tc.facts), tc.name)
+	}
+}
+
+func TestPromo_DiscountPercentage(t *testing.T) {
+	p := Promo{Kind: KindPercentage, Percent: 20, Genre: "Horror"}
+
+	assert.Equal(t, int64(160), p.Discount(facts(line(1, 399), line(2, 399), line(3, 599))))
+	assert.Equal(t, int64(80), p.Discount(facts(line(1, 399))))
+}
+
+func TestPromo_DiscountFixed(t *testing.T) {
+	p := Promo{Kind: KindFixed, Amount: 500}
+
+	assert.Equal(t, int64(500), p.Discount(facts(line(1, 399), line(3, 599))))
+	assert.Equal(t, int64(399), p.Discount(facts(line(1, 399))), "capped at the eligible subtotal")
+}
+
+func TestPromo_DiscountBuyGet(t *testing.T) {
+	p := Promo{Kind: KindBuyGet, Buy: 2, Get: 1}
+
+	assert.Equal(t, int64(0), p.Discount(facts(line(1, 500), line(2, 400))))
+	assert.Equal(t, int64(300), p.Discount(facts(line(1, 200), line(2, 500), line(3, 300), line(4, 400))))
+	assert.Equal(t, int64(500), p.Discount(facts(line(1, 600), line(2, 500), line(3, 400), line(4, 300), line(5, 200), line(6, 100))))
+}
+
+func TestPromo_DiscountMaxDiscount(t *testing.T) {
+	firstRentalFree := Promo{Kind: KindPercentage, Percent: 100, NewCustomersOnly: true, MaxDiscount: 599}
+
+	assert.Equal(t, int64(399), firstRentalFree.Discount(facts(line(1, 399))))
+	assert.Equal(t, int64(599), firstRentalFree.Discount(facts(line(1, 399), line(3, 599))))
+}
@@ -0,0 +1,90 @@
+// Package region works out which licensing territory a request is made from.
+package region
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header lets clients without a profile name their region.
+const Header = "X-Region"
+
+const profilesKey = "region.profiles"
+
+var ErrInvalidRegion = errors.New("region must be a two-letter country code")
+
+// ProfileRepository looks up the region stored on a user's profile.
+type ProfileRepository interface {
+	// ProfileRegion returns "" when the user has no profile.
+	ProfileRegion(ctx context.Context, userID int) (string, error)
+}
+
+type profileRepository struct {
+	db *sql.DB
+}
+
+func NewProfileRepository(db *sql.DB) ProfileRepository {
+	return &profileRepository{db: db}
+}
+
+func (r *profileRepository) ProfileRegion(ctx context.Context, userID int) (string, error) {
+	var region string
+	err := r.db.QueryRowContext(ctx, "SELECT region FROM user_profiles WHERE user_id = $1", userID).Scan(&region)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return region, err
+}
+
+// Normalize upper-cases an ISO 3166-1 alpha-2 code. The empty string is
+// returned unchanged and means the region is unknown.
+func Normalize(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if len(s) != 2 || s[0] < 'A' || s[0] > 'Z' || s[1] < 'A' || s[1] > 'Z' {
+		return "", ErrInvalidRegion
+	}
+	return s, nil
+}
+
+// Middleware rejects malformed region headers and makes profiles available
+// to Resolve for the rest of the request.
+func Middleware(profiles ProfileRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := Normalize(c.GetHeader(Header)); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, err.Error())
+			c.Abort()
+			return
+		}
+		c.Set(profilesKey, profiles)
+		c.Next()
+	}
+}
+
+// Resolve returns the region of the request made on behalf of userID. The
+// region on the user's profile wins; otherwise the region header is used.
+// userID may be zero for anonymous requests, and the result is "" when no
+// region is known.
+func Resolve(c *gin.Context, userID int) (string, error) {
+	if v, ok := c.Get(profilesKey); ok && userID > 0 {
+		profiles := v.(ProfileRepository)
+		region, err := profiles.ProfileRegion(c.Request.Context(), userID)
+		if err != nil {
+			return "", err
+		}
+		if region != "" {
+			return region, nil
+		}
+	}
+	region, _ := Normalize(c.GetHeader(Header))
+	return region, nil
+}
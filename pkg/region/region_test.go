@@ -0,0 +1,94 @@
+package region
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockProfiles map[int]string
+
+func (m mockProfiles) ProfileRegion(_ context.Context, userID int) (string, error) {
+	if userID == 99 {
+		return "", errors.New("db error")
+	}
+	return m[userID], nil
+}
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{"": "", "gb": "GB", " US ": "US"} {
+		got, err := Normalize(in)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	for _, in := range []string{"G", "GBR", "G1", "ñ"} {
+		_, err := Normalize(in)
+		assert.ErrorIs(t, err, ErrInvalidRegion, in)
+	}
+}
+
+// resolveWith runs Resolve for userID behind Middleware and returns the
+// resolved region as the response body.
+func resolveWith(profiles ProfileRepository, header string, userID int) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(profiles))
+	router.GET("/", func(c *gin.Context) {
+		region, err := Resolve(c, userID)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, region)
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(Header, header)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestResolve(t *testing.T) {
+	profiles := mockProfiles{1: "CA"}
+
+	assert.Equal(t, "CA", resolveWith(profiles, "us", 1).Body.String())
+	assert.Equal(t, "US", resolveWith(profiles, "us", 2).Body.String())
+	assert.Equal(t, "US", resolveWith(profiles, "us", 0).Body.String())
+	assert.Equal(t, "", resolveWith(profiles, "", 2).Body.String())
+	assert.Equal(t, http.StatusInternalServerError, resolveWith(profiles, "", 99).Code)
+}
+
+func TestMiddleware_RejectsMalformedHeader(t *testing.T) {
+	recorder := resolveWith(mockProfiles{}, "United Kingdom", 0)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid_request")
+}
+
+func TestProfileRegion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT region FROM user_profiles WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"region"}).AddRow("GB"))
+	mock.ExpectQuery(`SELECT region FROM user_profiles`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"region"}))
+
+	repo := NewProfileRepository(db)
+	region, err := repo.ProfileRegion(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "GB", region)
+	region, err = repo.ProfileRegion(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", region)
+}
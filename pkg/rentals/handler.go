@@ -0,0 +1,96 @@
+package rentals
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/pricing"
+	"movie-rental/pkg/promo"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeCartEmpty       = "cart_empty"
+	CodeCartNotRentable = "cart_not_rentable"
+	CodeCartChanged     = "cart_changed"
+)
+
+// Limiter enforces the limits of a user's membership tier at checkout. It
+// is satisfied by *policy.Enforcer.
+type Limiter interface {
+	CheckCheckout(ctx context.Context, userID int) error
+}
+
+// CheckoutHandler rents everything in the user's cart as one order, priced
+// for the user's region. When a line can no longer be rented, nothing is
+// rented and the details are the reviewed cart, whose warnings say why.
+func CheckoutHandler(repo Repository, cartRepo cart.Repository, pricer cart.Pricer, limiter Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id must be an integer")
+			return
+		}
+		ctx := c.Request.Context()
+
+		lines, err := cartRepo.GetCartItems(c.Param("user_id"))
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if len(lines) == 0 {
+			api.Error(c, http.StatusConflict, CodeCartEmpty, "cart is empty")
+			return
+		}
+
+		userRegion, err := region.Resolve(c, userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if err := limiter.CheckCheckout(ctx, userID); err != nil {
+			if !policy.RespondError(c, err) {
+				api.InternalError(c, err)
+			}
+			return
+		}
+
+		now := time.Now()
+		review, err := cart.ReviewCart(ctx, cartRepo, pricer, userID, userRegion, lines, now)
+		if errors.Is(err, pricing.ErrNoPrice) {
+			// A price was removed after the lines were checked.
+			api.Error(c, http.StatusConflict, cart.CodePriceUnavailable, err.Error())
+			return
+		} else if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if !review.CheckoutReady() {
+			api.ErrorWithDetails(c, http.StatusConflict, CodeCartNotRentable,
+				"some items in the cart can no longer be rented", cart.NewCartResponse(userID, review))
+			return
+		}
+
+		order, err := repo.Checkout(ctx, userID, userRegion, review, now)
+		if errors.Is(err, ErrCartChanged) {
+			api.Error(c, http.StatusConflict, CodeCartChanged, err.Error())
+			return
+		} else if errors.Is(err, promo.ErrUsageLimit) {
+			api.Error(c, http.StatusUnprocessableEntity, promo.CodeUsageLimit, err.Error())
+			return
+		} else if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusCreated, NewOrderResponse(order))
+	}
+}
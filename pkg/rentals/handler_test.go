@@ -0,0 +1,165 @@
+package rentals
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/policy"
+	"movie-rental/pkg/pricing"
+	"movie-rental/pkg/promo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	CheckoutFunc func(userID int, region string, review cart.Review) (Order, error)
+}
+
+func (m *mockRepository) Checkout(_ context.Context, userID int, region string, review cart.Review, now time.Time) (Order, error) {
+	return m.CheckoutFunc(userID, region, review)
+}
+
+// mockCarts holds lines, whose items are all rentable unless unlicensed.
+type mockCarts struct {
+	cart.Repository
+	lines      []cart.Line
+	unlicensed bool
+}
+
+func (m *mockCarts) GetCartItems(userID string) ([]cart.Line, error) {
+	return m.lines, nil
+}
+
+func (m *mockCarts) CheckItems(_ context.Context, items []cart.Item, region string) ([]cart.ItemCheck, error) {
+	checks := make([]cart.ItemCheck, len(items))
+	for i := range checks {
+		checks[i] = cart.ItemCheck{Licensed: !m.unlicensed, Offered: true, Priced: true}
+	}
+	return checks, nil
+}
+
+type mockPrices struct{}
+
+func (mockPrices) Prices(_ context.Context, items []pricing.Item) (map[pricing.Item]int64, error) {
+	prices := make(map[pricing.Item]int64)
+	for _, item := range items {
+		prices[item] = 399
+	}
+	return prices, nil
+}
+func (mockPrices) TaxRate(_ context.Context, region string) (int, error) {
+	return 0, nil
+}
+
+type mockLimiter struct{ err error }
+
+func (m mockLimiter) CheckCheckout(ctx context.Context, userID int) error { return m.err }
+
+func setupRouter(repo Repository, carts *mockCarts, limiter Limiter) *gin.Engine {
+	router := gin.Default()
+	router.POST("/v2/cart/:user_id/:action", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+	router.POST("/v2/cart/:user_id/checkout", CheckoutHandler(repo, carts, pricing.NewPricer(mockPrices{}), limiter))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func cartLines() []cart.Line {
+	return []cart.Line{
+		{Movie: movies.Movie{MovieID: 2, Title: "Movie 2"}, Format: cart.FormatDigital, RentalDays: 2},
+		{Movie: movies.Movie{MovieID: 3, Title: "Movie 3"}, Format: cart.FormatDVD, RentalDays: 7,
+			Gift: &cart.Gift{RecipientEmail: "friend@example.com"}},
+	}
+}
+
+func TestCheckoutHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ends := created.AddDate(0, 0, 2)
+	repo := &mockRepository{
+		CheckoutFunc: func(userID int, region string, review cart.Review) (Order, error) {
+			assert.Equal(t, 1, userID)
+			assert.Equal(t, "GB", region)
+			assert.Len(t, review.Lines, 2)
+			assert.Equal(t, int64(798), review.Quote.Total)
+			return Order{OrderID: 7, UserID: userID, Region: region, Currency: "USD", Subtotal: 798, Total: 798,
+				Discounts: []pricing.Discount{}, CreatedAt: created,
+				Rentals: []Rental{{RentalID: 8, Movie: review.Lines[0].Movie, Format: "digital", RentalDays: 2,
+					Price: 399, StartsAt: &created, EndsAt: &ends}}}, nil
+		},
+	}
+	router := setupRouter(repo, &mockCarts{lines: cartLines()}, mockLimiter{})
+
+	req, _ := http.NewRequest("POST", "/v2/cart/1/checkout", nil)
+	req.Header.Set("X-Region", "gb")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"order_id":7`)
+	assert.Contains(t, recorder.Body.String(), `"price":399,"starts_at":"2026-10-19T12:00:00Z","ends_at":"2026-10-21T12:00:00Z"`)
+	assert.Contains(t, recorder.Body.String(), `"total":798`)
+}
+
+func TestCheckoutHandler_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	violation := &policy.Violation{Rule: policy.RuleMaxPhysicalRentals, Tier: "standard", Limit: 1}
+	cases := []struct {
+		name    string
+		carts   *mockCarts
+		limiter mockLimiter
+		err     error
+		status  int
+		code    string
+	}{
+		{"empty", &mockCarts{}, mockLimiter{}, nil, http.StatusConflict, CodeCartEmpty},
+		{"not rentable", &mockCarts{lines: cartLines(), unlicensed: true}, mockLimiter{}, nil, http.StatusConflict, CodeCartNotRentable},
+		{"limit", &mockCarts{lines: cartLines()}, mockLimiter{violation}, nil, http.StatusUnprocessableEntity, policy.CodeLimitExceeded},
+		{"changed", &mockCarts{lines: cartLines()}, mockLimiter{}, ErrCartChanged, http.StatusConflict, CodeCartChanged},
+		{"promo used up", &mockCarts{lines: cartLines()}, mockLimiter{}, promo.ErrUsageLimit, http.StatusUnprocessableEntity, promo.CodeUsageLimit},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockRepository{
+				CheckoutFunc: func(userID int, region string, review cart.Review) (Order, error) {
+					if tc.err == nil {
+						t.Fatal("checkout should not be attempted")
+					}
+					return Order{}, tc.err
+				},
+			}
+
+			recorder := serve(setupRouter(repo, tc.carts, tc.limiter), "POST", "/v2/cart/1/checkout", "")
+
+			assert.Equal(t, tc.status, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), `"code":"`+tc.code+`"`)
+		})
+	}
+
+	recorder := serve(setupRouter(&mockRepository{}, &mockCarts{}, mockLimiter{}), "POST", "/v2/cart/abc/checkout", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestCheckoutHandler_NotRentableDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{}, &mockCarts{lines: cartLines(), unlicensed: true}, mockLimiter{})
+
+	recorder := serve(router, "POST", "/v2/cart/1/checkout", "")
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"code":"`+cart.CodeNotLicensed+`"`)
+	assert.Contains(t, recorder.Body.String(), `"checkout_ready":false`)
+}
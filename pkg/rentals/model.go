@@ -0,0 +1,122 @@
+// Package rentals checks carts out into orders of rentals.
+package rentals
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/gifts"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/pricing"
+)
+
+// ErrCartChanged is returned when the cart no longer holds the lines that
+// were reviewed for checkout.
+var ErrCartChanged = errors.New("cart changed during checkout; review it and try again")
+
+// Order is a checked-out cart. Its amounts were stamped at checkout.
+type Order struct {
+	OrderID int
+	UserID  int
+	// Region is "" when it was unknown at checkout.
+	Region        string
+	Rentals       []Rental
+	Currency      string
+	Subtotal      int64
+	Discounts     []pricing.Discount
+	DiscountTotal int64
+	Tax           int64
+	Total         int64
+	CreatedAt     time.Time
+}
+
+// Rental is a line of an order.
+type Rental struct {
+	RentalID   int
+	Movie      movies.Movie
+	Format     cart.Format
+	RentalDays int
+	Price      int64
+	// StartsAt and EndsAt are nil for gifts until they are claimed.
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	// Gift is set for rentals bought for someone else.
+	Gift *gifts.Gift
+}
+
+type OrderResponse struct {
+	XMLName       xml.Name           `json:"-" xml:"order"`
+	OrderID       int                `json:"order_id" xml:"order_id"`
+	UserID        int                `json:"user_id" xml:"user_id"`
+	Region        string             `json:"region,omitempty" xml:"region,omitempty"`
+	Rentals       []RentalResponse   `json:"rentals" xml:"rentals>rental"`
+	Currency      string             `json:"currency" xml:"currency"`
+	Subtotal      int64              `json:"subtotal" xml:"subtotal"`
+	Discounts     []pricing.Discount `json:"discounts" xml:"discounts>discount"`
+	DiscountTotal int64              `json:"discount_total" xml:"discount_total"`
+	Tax           int64              `json:"tax" xml:"tax"`
+	Total         int64              `json:"total" xml:"total"`
+	CreatedAt     time.Time          `json:"created_at" xml:"created_at"`
+}
+
+type RentalResponse struct {
+	XMLName    xml.Name             `json:"-" xml:"rental"`
+	RentalID   int                  `json:"rental_id" xml:"rental_id"`
+	Movie      movies.MovieResponse `json:"movie" xml:"movie"`
+	Format     cart.Format          `json:"format" xml:"format"`
+	RentalDays int                  `json:"rental_days" xml:"rental_days"`
+	Price      int64                `json:"price" xml:"price"`
+	StartsAt   *time.Time           `json:"starts_at" xml:"starts_at,omitempty"`
+	EndsAt     *time.Time           `json:"ends_at" xml:"ends_at,omitempty"`
+	Gift       *GiftResponse        `json:"gift,omitempty" xml:"gift,omitempty"`
+}
+
+// GiftResponse tells the buyer of a gift the code its recipient claims it
+// with.
+type GiftResponse struct {
+	XMLName         xml.Name `json:"-" xml:"gift"`
+	GiftID          int      `json:"gift_id" xml:"gift_id"`
+	ClaimCode       string   `json:"claim_code" xml:"claim_code"`
+	RecipientUserID int      `json:"recipient_user_id,omitempty" xml:"recipient_user_id,omitempty"`
+	RecipientEmail  string   `json:"recipient_email,omitempty" xml:"recipient_email,omitempty"`
+	Message         string   `json:"message" xml:"message"`
+}
+
+func NewOrderResponse(o Order) OrderResponse {
+	rentals := make([]RentalResponse, len(o.Rentals))
+	for i, r := range o.Rentals {
+		rentals[i] = RentalResponse{
+			RentalID:   r.RentalID,
+			Movie:      movies.NewMovieResponse(r.Movie),
+			Format:     r.Format,
+			RentalDays: r.RentalDays,
+			Price:      r.Price,
+			StartsAt:   r.StartsAt,
+			EndsAt:     r.EndsAt,
+		}
+		if g := r.Gift; g != nil {
+			rentals[i].Gift = &GiftResponse{
+				GiftID:          g.GiftID,
+				ClaimCode:       g.ClaimCode,
+				RecipientUserID: g.RecipientID,
+				RecipientEmail:  g.RecipientEmail,
+				Message:         g.Message,
+			}
+		}
+	}
+	return OrderResponse{
+		OrderID:       o.OrderID,
+		UserID:        o.UserID,
+		Region:        o.Region,
+		Rentals:       rentals,
+		Currency:      o.Currency,
+		Subtotal:      o.Subtotal,
+		Discounts:     o.Discounts,
+		DiscountTotal: o.DiscountTotal,
+		Tax:           o.Tax,
+		Total:         o.Total,
+		CreatedAt:     o.CreatedAt,
+	}
+}
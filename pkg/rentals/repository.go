@@ -0,0 +1,236 @@
+package rentals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/gifts"
+	"movie-rental/pkg/pricing"
+	"movie-rental/pkg/promo"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// Checkout turns the user's cart, as reviewed for checkout, into an
+	// order of rentals starting now, in one transaction. It fails with
+	// ErrCartChanged if the cart no longer holds the reviewed lines, and
+	// with promo.ErrUsageLimit if a promo code it redeems is used up.
+	Checkout(ctx context.Context, userID int, region string, review cart.Review, now time.Time) (Order, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Checkout(ctx context.Context, userID int, region string, review cart.Review, now time.Time) (Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Order{}, err
+	}
+	defer tx.Rollback()
+
+	// Emptying the cart first locks its rows, so that a concurrent change
+	// to it either shows up here or waits for the checkout to finish.
+	if err := clearCart(ctx, tx, userID, review.Lines); err != nil {
+		return Order{}, err
+	}
+
+	q := review.Quote
+	order := Order{
+		UserID:        userID,
+		Region:        region,
+		Currency:      q.Currency,
+		Subtotal:      q.Subtotal,
+		Discounts:     q.Discounts,
+		DiscountTotal: q.DiscountTotal,
+		Tax:           q.Tax,
+		Total:         q.Total,
+		CreatedAt:     now,
+	}
+	discounts, err := json.Marshal(q.Discounts)
+	if err != nil {
+		return Order{}, err
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO orders (user_id, region, currency, subtotal, discounts, discount_total, tax, total, created_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9)
+		RETURNING order_id`,
+		userID, region, q.Currency, q.Subtotal, discounts, q.DiscountTotal, q.Tax, q.Total, now).
+		Scan(&order.OrderID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	ids := make([]int64, len(review.Lines))
+	for i, l := range review.Lines {
+		rental, err := addRental(ctx, tx, order.OrderID, userID, l, now)
+		if err != nil {
+			return Order{}, err
+		}
+		order.Rentals = append(order.Rentals, rental)
+		ids[i] = int64(l.Movie.MovieID)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO activity_events (user_id, movie_id, kind, occurred_at)
+		SELECT $1, unnest($2::int[]), 'rental', $3`, userID, pq.Array(ids), now); err != nil {
+		return Order{}, err
+	}
+	if err := redeemPromos(ctx, tx, userID, q.Discounts, now); err != nil {
+		return Order{}, err
+	}
+	return order, tx.Commit()
+}
+
+// clearCart deletes the user's cart, and fails with ErrCartChanged unless it
+// held exactly lines.
+func clearCart(ctx context.Context, tx *sql.Tx, userID int, lines []cart.ReviewedLine) error {
+	rows, err := tx.QueryContext(ctx,
+		"DELETE FROM cart WHERE user_id = $1 RETURNING movie_id, format, rental_days", userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	want := make(map[cart.Item]bool, len(lines))
+	for _, l := range lines {
+		want[cart.Item{MovieID: l.Movie.MovieID, Format: l.Format, RentalDays: l.RentalDays}] = true
+	}
+	n := 0
+	for rows.Next() {
+		var item cart.Item
+		if err := rows.Scan(&item.MovieID, &item.Format, &item.RentalDays); err != nil {
+			return err
+		}
+		if !want[item] {
+			return ErrCartChanged
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if n != len(want) {
+		return ErrCartChanged
+	}
+	return nil
+}
+
+// addRental records l as a rental of the order. The rental starts now,
+// unless l is a gift, which starts when its recipient claims it.
+func addRental(ctx context.Context, tx *sql.Tx, orderID, userID int, l cart.ReviewedLine, now time.Time) (Rental, error) {
+	rental := Rental{
+		Movie:      l.Movie,
+		Format:     l.Format,
+		RentalDays: l.RentalDays,
+		Price:      *l.Price,
+	}
+	item := cart.Item{MovieID: l.Movie.MovieID, Format: l.Format, RentalDays: l.RentalDays}
+
+	var renterID, giftID interface{}
+	if l.Gift != nil {
+		g, err := gifts.Create(ctx, tx, userID, item, *l.Gift)
+		if err != nil {
+			return rental, err
+		}
+		g.Movie = l.Movie
+		rental.Gift = &g
+		giftID = g.GiftID
+	} else {
+		start, end := now, now.AddDate(0, 0, l.RentalDays)
+		rental.StartsAt, rental.EndsAt = &start, &end
+		renterID = userID
+	}
+
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO rentals (order_id, user_id, movie_id, format, rental_days, price, gift_id, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING rental_id`,
+		orderID, renterID, item.MovieID, item.Format, item.RentalDays, rental.Price, giftID, rental.StartsAt, rental.EndsAt).
+		Scan(&rental.RentalID)
+	return rental, err
+}
+
+// redeemPromos counts the promo codes among discounts as redeemed by the
+// user, and takes the code applied to their cart off it. It fails with
+// promo.ErrUsageLimit if a code has been used up since the cart was priced.
+func redeemPromos(ctx context.Context, tx *sql.Tx, userID int, discounts []pricing.Discount, now time.Time) error {
+	var codes []string
+	for _, d := range discounts {
+		if d.Code != "" {
+			codes = append(codes, d.Code)
+		}
+	}
+	if len(codes) > 0 {
+		if err := checkPromoUses(ctx, tx, userID, codes); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO promo_redemptions (code, user_id, redeemed_at)
+			SELECT code, $1, $3 FROM promo_codes WHERE code = ANY($2::text[])`,
+			userID, pq.Array(codes), now); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM cart_promos WHERE user_id = $1", userID)
+	return err
+}
+
+// checkPromoUses locks the codes, so that checkouts redeeming the same code
+// take turns, and fails with promo.ErrUsageLimit if any has no uses left for
+// the user. The redemptions are counted after the lock is taken, so that
+// they include those of the checkout that held it before.
+func checkPromoUses(ctx context.Context, tx *sql.Tx, userID int, codes []string) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT code, COALESCE(max_uses, 0), COALESCE(max_uses_per_user, 0)
+		FROM promo_codes WHERE code = ANY($1::text[])
+		ORDER BY code
+		FOR UPDATE`, pq.Array(codes))
+	if err != nil {
+		return err
+	}
+	type limit struct{ uses, userUses int }
+	limits := make(map[string]limit, len(codes))
+	for rows.Next() {
+		var code string
+		var l limit
+		if err := rows.Scan(&code, &l.uses, &l.userUses); err != nil {
+			rows.Close()
+			return err
+		}
+		limits[code] = l
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT code, COUNT(*), COUNT(*) FILTER (WHERE user_id = $2)
+		FROM promo_redemptions WHERE code = ANY($1::text[])
+		GROUP BY code`, pq.Array(codes), userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		var uses, userUses int
+		if err := rows.Scan(&code, &uses, &userUses); err != nil {
+			return err
+		}
+		l := limits[code]
+		if l.uses > 0 && uses >= l.uses || l.userUses > 0 && userUses >= l.userUses {
+			return promo.ErrUsageLimit
+		}
+	}
+	return rows.Err()
+}
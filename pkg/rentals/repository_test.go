@@ -0,0 +1,145 @@
+package rentals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/pricing"
+	"movie-rental/pkg/promo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func reviewed(lines ...cart.Line) cart.Review {
+	review := cart.Review{Quote: pricing.Quote{Currency: "USD", Discounts: []pricing.Discount{}}}
+	for _, l := range lines {
+		price := int64(399)
+		review.Lines = append(review.Lines, cart.ReviewedLine{Line: l, Price: &price, Warnings: []cart.Warning{}})
+		review.Quote.Subtotal += price
+	}
+	review.Quote.Total = review.Quote.Subtotal
+	return review
+}
+
+func TestCheckout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ends := now.AddDate(0, 0, 2)
+	review := reviewed(
+		cart.Line{Movie: movies.Movie{MovieID: 2}, Format: cart.FormatDigital, RentalDays: 2},
+		cart.Line{Movie: movies.Movie{MovieID: 3}, Format: cart.FormatDVD, RentalDays: 7,
+			Gift: &cart.Gift{RecipientEmail: "friend@example.com", Message: "Enjoy"}})
+	review.Quote.Discounts = []pricing.Discount{{Code: "OCTHORROR", Description: "20% off horror", Amount: 80}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM cart WHERE user_id = \$1 RETURNING movie_id, format, rental_days`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "format", "rental_days"}).
+			AddRow(3, "dvd", 7).
+			AddRow(2, "digital", 2))
+	mock.ExpectQuery(`INSERT INTO orders \(user_id, region, currency, subtotal, discounts, discount_total, tax, total, created_at\) VALUES \(\$1, NULLIF\(\$2, ''\), (.+)\) RETURNING order_id`).
+		WithArgs(1, "GB", "USD", int64(798), []byte(`[{"code":"OCTHORROR","description":"20% off horror","amount":80}]`), int64(0), int64(0), int64(798), now).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO rentals \(order_id, user_id, movie_id, format, rental_days, price, gift_id, starts_at, ends_at\) VALUES (.+) RETURNING rental_id`).
+		WithArgs(7, 1, 2, cart.FormatDigital, 2, int64(399), nil, now, ends).
+		WillReturnRows(sqlmock.NewRows([]string{"rental_id"}).AddRow(8))
+	mock.ExpectQuery(`INSERT INTO gifts (.+) RETURNING gift_id, created_at`).
+		WithArgs(sqlmock.AnyArg(), 1, nil, "friend@example.com", 3, cart.FormatDVD, 7, "Enjoy").
+		WillReturnRows(sqlmock.NewRows([]string{"gift_id", "created_at"}).AddRow(10, now))
+	mock.ExpectQuery(`INSERT INTO rentals (.+) RETURNING rental_id`).
+		WithArgs(7, nil, 3, cart.FormatDVD, 7, int64(399), 10, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"rental_id"}).AddRow(9))
+	mock.ExpectExec(`INSERT INTO activity_events \(user_id, movie_id, kind, occurred_at\) SELECT \$1, unnest\(\$2::int\[\]\), 'rental', \$3`).
+		WithArgs(1, pq.Array([]int64{2, 3}), now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`SELECT code, COALESCE\(max_uses, 0\), COALESCE\(max_uses_per_user, 0\) FROM promo_codes WHERE code = ANY\(\$1::text\[\]\) ORDER BY code FOR UPDATE`).
+		WithArgs(pq.Array([]string{"OCTHORROR"})).
+		WillReturnRows(sqlmock.NewRows([]string{"code", "max_uses", "max_uses_per_user"}).AddRow("OCTHORROR", 100, 1))
+	mock.ExpectQuery(`SELECT code, COUNT\(\*\), COUNT\(\*\) FILTER \(WHERE user_id = \$2\) FROM promo_redemptions WHERE code = ANY\(\$1::text\[\]\) GROUP BY code`).
+		WithArgs(pq.Array([]string{"OCTHORROR"}), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"code", "count", "count"}).AddRow("OCTHORROR", 99, 0))
+	mock.ExpectExec(`INSERT INTO promo_redemptions \(code, user_id, redeemed_at\) SELECT code, \$1, \$3 FROM promo_codes WHERE code = ANY\(\$2::text\[\]\)`).
+		WithArgs(1, pq.Array([]string{"OCTHORROR"}), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM cart_promos WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	order, err := NewRepository(db).Checkout(context.Background(), 1, "GB", review, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, order.OrderID)
+	assert.Len(t, order.Rentals, 2)
+	assert.Equal(t, 8, order.Rentals[0].RentalID)
+	assert.Equal(t, ends, *order.Rentals[0].EndsAt)
+	assert.Nil(t, order.Rentals[1].StartsAt)
+	assert.Equal(t, 10, order.Rentals[1].Gift.GiftID)
+	assert.Equal(t, 3, order.Rentals[1].Gift.Movie.MovieID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckout_CartChanged(t *testing.T) {
+	review := reviewed(cart.Line{Movie: movies.Movie{MovieID: 2}, Format: cart.FormatDigital, RentalDays: 2})
+	cases := []*sqlmock.Rows{
+		sqlmock.NewRows([]string{"movie_id", "format", "rental_days"}),
+		sqlmock.NewRows([]string{"movie_id", "format", "rental_days"}).AddRow(2, "dvd", 7),
+		sqlmock.NewRows([]string{"movie_id", "format", "rental_days"}).AddRow(2, "digital", 2).AddRow(4, "digital", 2),
+	}
+	for _, rows := range cases {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM cart WHERE user_id = \$1`).WithArgs(1).WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		_, err = NewRepository(db).Checkout(context.Background(), 1, "", review, time.Now())
+		assert.ErrorIs(t, err, ErrCartChanged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	}
+}
+
+func TestCheckout_PromoUsedUp(t *testing.T) {
+	review := reviewed(cart.Line{Movie: movies.Movie{MovieID: 2}, Format: cart.FormatDigital, RentalDays: 2})
+	review.Quote.Discounts = []pricing.Discount{{Code: "OCTHORROR", Amount: 80}}
+	cases := []struct {
+		name             string
+		uses, userUses   int
+		maxUses, maxUser int
+	}{
+		{"max uses", 100, 0, 100, 0},
+		{"max uses per user", 3, 1, 0, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`DELETE FROM cart`).
+				WillReturnRows(sqlmock.NewRows([]string{"movie_id", "format", "rental_days"}).AddRow(2, "digital", 2))
+			mock.ExpectQuery(`INSERT INTO orders`).WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(7))
+			mock.ExpectQuery(`INSERT INTO rentals`).WillReturnRows(sqlmock.NewRows([]string{"rental_id"}).AddRow(8))
+			mock.ExpectExec(`INSERT INTO activity_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(`FROM promo_codes (.+) FOR UPDATE`).
+				WillReturnRows(sqlmock.NewRows([]string{"code", "max_uses", "max_uses_per_user"}).AddRow("OCTHORROR", tc.maxUses, tc.maxUser))
+			mock.ExpectQuery(`FROM promo_redemptions`).
+				WillReturnRows(sqlmock.NewRows([]string{"code", "count", "count"}).AddRow("OCTHORROR", tc.uses, tc.userUses))
+			mock.ExpectRollback()
+
+			_, err = NewRepository(db).Checkout(context.Background(), 1, "", review, time.Now())
+			assert.ErrorIs(t, err, promo.ErrUsageLimit)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
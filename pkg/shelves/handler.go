@@ -0,0 +1,155 @@
+package shelves
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/movies"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeDuplicateSlug = "duplicate_slug"
+
+	maxShelfMovies = 100
+)
+
+// StorefrontHandler lists the shelves to show right now in the caller's
+// region, optionally targeted with the rating query parameter.
+func StorefrontHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRegion, err := movies.RequestRegion(c)
+		if err != nil {
+			movies.RespondRegionError(c, err)
+			return
+		}
+
+		shelves, err := repo.ListActiveShelves(c.Request.Context(), time.Now(), userRegion, c.Query("rating"))
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		resp := NewStorefrontShelves(shelves)
+		api.List(c, resp, len(resp))
+	}
+}
+
+func CreateShelfHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindShelf(c)
+		if !ok {
+			return
+		}
+
+		shelf, err := repo.CreateShelf(c.Request.Context(), req.Shelf())
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusCreated, shelf)
+	}
+}
+
+func UpdateShelfHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelfID, ok := shelfIDParam(c)
+		if !ok {
+			return
+		}
+		req, ok := bindShelf(c)
+		if !ok {
+			return
+		}
+
+		shelf := req.Shelf()
+		shelf.ShelfID = shelfID
+		if err := repo.UpdateShelf(c.Request.Context(), shelf); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusOK, shelf)
+	}
+}
+
+func DeleteShelfHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelfID, ok := shelfIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := repo.DeleteShelf(c.Request.Context(), shelfID); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func SetShelfMoviesHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelfID, ok := shelfIDParam(c)
+		if !ok {
+			return
+		}
+		var req ShelfMoviesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie_ids must be a list of positive integers")
+			return
+		}
+		if len(req.MovieIDs) > maxShelfMovies {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "a shelf holds at most 100 movies")
+			return
+		}
+
+		if err := repo.SetShelfMovies(c.Request.Context(), shelfID, req.MovieIDs); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func bindShelf(c *gin.Context) (ShelfRequest, bool) {
+	var req ShelfRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "slug and title are required")
+		return req, false
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "ends_at must be after starts_at")
+		return req, false
+	}
+	return req, true
+}
+
+func shelfIDParam(c *gin.Context) (int, bool) {
+	shelfID, err := strconv.Atoi(c.Param("shelf_id"))
+	if err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "shelf_id must be an integer")
+		return 0, false
+	}
+	return shelfID, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrShelfNotFound):
+		api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+	case errors.Is(err, ErrMovieNotFound):
+		api.Error(c, http.StatusUnprocessableEntity, api.CodeNotFound, err.Error())
+	case errors.Is(err, ErrDuplicateSlug):
+		api.Error(c, http.StatusConflict, CodeDuplicateSlug, err.Error())
+	default:
+		api.InternalError(c, err)
+	}
+}
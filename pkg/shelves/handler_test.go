@@ -0,0 +1,207 @@
+package shelves
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"movie-rental/pkg/movies"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	ListActiveShelvesFunc func(now time.Time, region, rating string) ([]Shelf, error)
+	CreateShelfFunc       func(s Shelf) (*Shelf, error)
+	UpdateShelfFunc       func(s Shelf) error
+	DeleteShelfFunc       func(shelfID int) error
+	SetShelfMoviesFunc    func(shelfID int, movieIDs []int) error
+}
+
+func (m *mockRepository) ListActiveShelves(_ context.Context, now time.Time, region, rating string) ([]Shelf, error) {
+	return m.ListActiveShelvesFunc(now, region, rating)
+}
+func (m *mockRepository) CreateShelf(_ context.Context, s Shelf) (*Shelf, error) {
+	return m.CreateShelfFunc(s)
+}
+func (m *mockRepository) UpdateShelf(_ context.Context, s Shelf) error {
+	return m.UpdateShelfFunc(s)
+}
+func (m *mockRepository) DeleteShelf(_ context.Context, shelfID int) error {
+	return m.DeleteShelfFunc(shelfID)
+}
+func (m *mockRepository) SetShelfMovies(_ context.Context, shelfID int, movieIDs []int) error {
+	return m.SetShelfMoviesFunc(shelfID, movieIDs)
+}
+
+func setupRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/shelves", StorefrontHandler(repo))
+	router.POST("/v2/admin/shelves", CreateShelfHandler(repo))
+	router.PUT("/v2/admin/shelves/:shelf_id", UpdateShelfHandler(repo))
+	router.DELETE("/v2/admin/shelves/:shelf_id", DeleteShelfHandler(repo))
+	router.PUT("/v2/admin/shelves/:shelf_id/movies", SetShelfMoviesHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestStorefrontHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListActiveShelvesFunc: func(now time.Time, region, rating string) ([]Shelf, error) {
+			assert.Equal(t, "US", region)
+			assert.Equal(t, "PG-13", rating)
+			return []Shelf{{
+				ShelfID: 1, Slug: "staff-picks", Title: "Staff Picks",
+				Movies: []movies.Movie{{MovieID: 10, Title: "Movie 10"}},
+			}}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/shelves?region=FR&rating=PG-13", nil)
+	req.Header.Set("X-Region", "us")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"slug":"staff-picks"`)
+	assert.Contains(t, recorder.Body.String(), `"movies":[{"movie_id":10,"title":"Movie 10"`)
+}
+
+func TestStorefrontHandler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListActiveShelvesFunc: func(now time.Time, region, rating string) ([]Shelf, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/shelves", "")
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestCreateShelfHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		CreateShelfFunc: func(s Shelf) (*Shelf, error) {
+			assert.Equal(t, "oscar-winners", s.Slug)
+			assert.Equal(t, []string{"US", "CA"}, s.Regions)
+			assert.Equal(t, []string{}, s.ContentRatings)
+			s.ShelfID = 2
+			return &s, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/admin/shelves",
+		`{"slug":"oscar-winners","title":"Oscar Winners","starts_at":"2027-02-01T00:00:00Z","regions":["US","CA"]}`)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"shelf_id":2`)
+}
+
+func TestCreateShelfHandler_InvalidSchedule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{})
+
+	recorder := serve(router, "POST", "/v2/admin/shelves",
+		`{"slug":"x","title":"X","starts_at":"2027-02-01T00:00:00Z","ends_at":"2027-01-01T00:00:00Z"}`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestCreateShelfHandler_DuplicateSlug(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		CreateShelfFunc: func(s Shelf) (*Shelf, error) {
+			return nil, ErrDuplicateSlug
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/admin/shelves", `{"slug":"x","title":"X"}`)
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+}
+
+func TestUpdateShelfHandler_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		UpdateShelfFunc: func(s Shelf) error {
+			assert.Equal(t, 9, s.ShelfID)
+			return ErrShelfNotFound
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/shelves/9", `{"slug":"x","title":"X"}`)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestDeleteShelfHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		DeleteShelfFunc: func(shelfID int) error {
+			assert.Equal(t, 1, shelfID)
+			return nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "DELETE", "/v2/admin/shelves/1", "")
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+}
+
+func TestSetShelfMoviesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		SetShelfMoviesFunc: func(shelfID int, movieIDs []int) error {
+			assert.Equal(t, []int{12, 10}, movieIDs)
+			return nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/shelves/1/movies", `{"movie_ids":[12,10]}`)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+}
+
+func TestSetShelfMoviesHandler_InvalidIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{})
+
+	recorder := serve(router, "PUT", "/v2/admin/shelves/1/movies", `{"movie_ids":[0]}`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestSetShelfMoviesHandler_UnknownMovie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		SetShelfMoviesFunc: func(shelfID int, movieIDs []int) error {
+			return ErrMovieNotFound
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/shelves/1/movies", `{"movie_ids":[99]}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+}
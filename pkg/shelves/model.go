@@ -0,0 +1,81 @@
+package shelves
+
+import (
+	"encoding/xml"
+	"time"
+
+	"movie-rental/pkg/movies"
+)
+
+// Shelf is an editorially curated row of movies. It is shown while the
+// current time is within [StartsAt, EndsAt) and, when Regions or
+// ContentRatings are set, only to audiences matching one of them.
+type Shelf struct {
+	XMLName        xml.Name   `json:"-" xml:"shelf"`
+	ShelfID        int        `json:"shelf_id" xml:"shelf_id"`
+	Slug           string     `json:"slug" xml:"slug"`
+	Title          string     `json:"title" xml:"title"`
+	Position       int        `json:"position" xml:"position"`
+	StartsAt       *time.Time `json:"starts_at" xml:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at" xml:"ends_at,omitempty"`
+	Regions        []string   `json:"regions" xml:"regions>region"`
+	ContentRatings []string   `json:"content_ratings" xml:"content_ratings>content_rating"`
+
+	Movies []movies.Movie `json:"-" xml:"-"`
+}
+
+// ShelfRequest creates or replaces a shelf's settings.
+type ShelfRequest struct {
+	Slug           string     `json:"slug" binding:"required,max=64"`
+	Title          string     `json:"title" binding:"required,max=255"`
+	Position       int        `json:"position"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	Regions        []string   `json:"regions"`
+	ContentRatings []string   `json:"content_ratings"`
+}
+
+func (r ShelfRequest) Shelf() Shelf {
+	s := Shelf{
+		Slug:           r.Slug,
+		Title:          r.Title,
+		Position:       r.Position,
+		StartsAt:       r.StartsAt,
+		EndsAt:         r.EndsAt,
+		Regions:        r.Regions,
+		ContentRatings: r.ContentRatings,
+	}
+	if s.Regions == nil {
+		s.Regions = []string{}
+	}
+	if s.ContentRatings == nil {
+		s.ContentRatings = []string{}
+	}
+	return s
+}
+
+type ShelfMoviesRequest struct {
+	MovieIDs []int `json:"movie_ids" binding:"required,dive,gt=0"`
+}
+
+// StorefrontShelf is a shelf as the storefront renders it.
+type StorefrontShelf struct {
+	XMLName xml.Name               `json:"-" xml:"shelf"`
+	ShelfID int                    `json:"shelf_id" xml:"shelf_id"`
+	Slug    string                 `json:"slug" xml:"slug"`
+	Title   string                 `json:"title" xml:"title"`
+	Movies  []movies.MovieResponse `json:"movies" xml:"movies>movie"`
+}
+
+func NewStorefrontShelves(shelves []Shelf) []StorefrontShelf {
+	out := make([]StorefrontShelf, len(shelves))
+	for i, s := range shelves {
+		out[i] = StorefrontShelf{
+			ShelfID: s.ShelfID,
+			Slug:    s.Slug,
+			Title:   s.Title,
+			Movies:  movies.NewMovieResponses(s.Movies),
+		}
+	}
+	return out
+}
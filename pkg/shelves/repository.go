@@ -0,0 +1,186 @@
+package shelves
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"movie-rental/pkg/movies"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrShelfNotFound = errors.New("shelf not found")
+	ErrDuplicateSlug = errors.New("a shelf with this slug already exists")
+	ErrMovieNotFound = errors.New("one or more movies do not exist")
+)
+
+type Repository interface {
+	ListActiveShelves(ctx context.Context, now time.Time, region, rating string) ([]Shelf, error)
+	CreateShelf(ctx context.Context, s Shelf) (*Shelf, error)
+	UpdateShelf(ctx context.Context, s Shelf) error
+	DeleteShelf(ctx context.Context, shelfID int) error
+	SetShelfMovies(ctx context.Context, shelfID int, movieIDs []int) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// ListActiveShelves returns the shelves scheduled at now that target region
+// and rating, each hydrated with its movies licensed in region, in shelf
+// order. All movies are loaded with a single query regardless of the number
+// of shelves.
+func (r *repository) ListActiveShelves(ctx context.Context, now time.Time, region, rating string) ([]Shelf, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT shelf_id, slug, title, position, starts_at, ends_at, regions, content_ratings
+		FROM shelves
+		WHERE (starts_at IS NULL OR starts_at <= $1)
+		AND (ends_at IS NULL OR ends_at > $1)
+		AND (cardinality(regions) = 0 OR $2 = ANY(regions))
+		AND (cardinality(content_ratings) = 0 OR $3 = ANY(content_ratings))
+		ORDER BY position, shelf_id`, now, region, rating)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shelves []Shelf
+	index := make(map[int]int)
+	var ids []int64
+	for rows.Next() {
+		var s Shelf
+		if err := rows.Scan(&s.ShelfID, &s.Slug, &s.Title, &s.Position, &s.StartsAt, &s.EndsAt,
+			pq.Array(&s.Regions), pq.Array(&s.ContentRatings)); err != nil {
+			return nil, err
+		}
+		s.Movies = []movies.Movie{}
+		index[s.ShelfID] = len(shelves)
+		ids = append(ids, int64(s.ShelfID))
+		shelves = append(shelves, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(shelves) == 0 {
+		return shelves, nil
+	}
+
+	movieRows, err := r.db.QueryContext(ctx, `
+		SELECT sm.shelf_id, `+movies.SelectColumns("m")+`
+		FROM shelf_movies sm
+		JOIN movies m ON m.movie_id = sm.movie_id
+		WHERE sm.shelf_id = ANY($1) AND `+movies.LicensedClause("m.movie_id", 2)+`
+		ORDER BY sm.shelf_id, sm.position`, pq.Array(ids), region)
+	if err != nil {
+		return nil, err
+	}
+	defer movieRows.Close()
+
+	for movieRows.Next() {
+		var shelfID int
+		var m movies.Movie
+		if err := movieRows.Scan(append([]interface{}{&shelfID}, movies.ScanFields(&m)...)...); err != nil {
+			return nil, err
+		}
+		s := &shelves[index[shelfID]]
+		s.Movies = append(s.Movies, m)
+	}
+	return shelves, movieRows.Err()
+}
+
+func (r *repository) CreateShelf(ctx context.Context, s Shelf) (*Shelf, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO shelves (slug, title, position, starts_at, ends_at, regions, content_ratings)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING shelf_id`,
+		s.Slug, s.Title, s.Position, s.StartsAt, s.EndsAt, pq.Array(s.Regions), pq.Array(s.ContentRatings),
+	).Scan(&s.ShelfID)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &s, nil
+}
+
+func (r *repository) UpdateShelf(ctx context.Context, s Shelf) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE shelves
+		SET slug = $2, title = $3, position = $4, starts_at = $5, ends_at = $6, regions = $7, content_ratings = $8
+		WHERE shelf_id = $1`,
+		s.ShelfID, s.Slug, s.Title, s.Position, s.StartsAt, s.EndsAt, pq.Array(s.Regions), pq.Array(s.ContentRatings))
+	if err != nil {
+		return mapError(err)
+	}
+	return expectOneRow(res)
+}
+
+func (r *repository) DeleteShelf(ctx context.Context, shelfID int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM shelves WHERE shelf_id = $1", shelfID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// SetShelfMovies replaces the movies on a shelf; their order in movieIDs
+// becomes the display order.
+func (r *repository) SetShelfMovies(ctx context.Context, shelfID int, movieIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "SELECT shelf_id FROM shelves WHERE shelf_id = $1 FOR UPDATE", shelfID).Scan(&shelfID)
+	if err == sql.ErrNoRows {
+		return ErrShelfNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shelf_movies WHERE shelf_id = $1", shelfID); err != nil {
+		return err
+	}
+	ids := make([]int64, len(movieIDs))
+	for i, id := range movieIDs {
+		ids[i] = int64(id)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO shelf_movies (shelf_id, movie_id, position)
+		SELECT $1, movie_id, MIN(position) FROM unnest($2::int[]) WITH ORDINALITY AS t(movie_id, position)
+		GROUP BY movie_id`, shelfID, pq.Array(ids)); err != nil {
+		return mapError(err)
+	}
+
+	return tx.Commit()
+}
+
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrShelfNotFound
+	}
+	return nil
+}
+
+func mapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrDuplicateSlug
+		case "23503":
+			return ErrMovieNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+package shelves
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShelfRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"shelf_id", "slug", "title", "position", "starts_at", "ends_at", "regions", "content_ratings"})
+}
+
+func newShelfMovieRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"shelf_id", "movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until"})
+}
+
+func TestListActiveShelves_HydratesInOneQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT shelf_id, slug, title, position, starts_at, ends_at, regions, content_ratings FROM shelves`).
+		WithArgs(now, "US", "PG").
+		WillReturnRows(newShelfRows().
+			AddRow(1, "staff-picks", "Staff Picks", 0, nil, nil, "{}", "{}").
+			AddRow(2, "oscar-winners", "Oscar Winners", 1, nil, nil, "{US}", "{}"))
+	mock.ExpectQuery(`SELECT sm.shelf_id, m.movie_id, (.+) FROM shelf_movies sm JOIN movies m ON m.movie_id = sm.movie_id WHERE sm.shelf_id = ANY\(\$1\) `+
+		`AND \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = m.movie_id\) OR EXISTS (.+) region = \$2`).
+		WithArgs(pq.Array([]int64{1, 2}), "US").
+		WillReturnRows(newShelfMovieRows().
+			AddRow(1, 10, "Movie 10", 2020, "", "", "", "", nil, nil).
+			AddRow(1, 11, "Movie 11", 2021, "", "", "", "", nil, nil).
+			AddRow(2, 12, "Movie 12", 2022, "", "", "", "", nil, nil))
+
+	repo := NewRepository(db)
+	shelves, err := repo.ListActiveShelves(context.Background(), now, "US", "PG")
+	assert.NoError(t, err)
+	assert.Len(t, shelves, 2)
+	assert.Equal(t, []string{"US"}, shelves[1].Regions)
+	assert.Len(t, shelves[0].Movies, 2)
+	assert.Equal(t, "Movie 11", shelves[0].Movies[1].Title)
+	assert.Len(t, shelves[1].Movies, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListActiveShelves_NoShelvesSkipsHydration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM shelves`).WillReturnRows(newShelfRows())
+
+	repo := NewRepository(db)
+	shelves, err := repo.ListActiveShelves(context.Background(), time.Now(), "", "")
+	assert.NoError(t, err)
+	assert.Empty(t, shelves)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListActiveShelves_DBError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM shelves`).WillReturnError(errors.New("db error"))
+
+	repo := NewRepository(db)
+	shelves, err := repo.ListActiveShelves(context.Background(), time.Now(), "", "")
+	assert.Error(t, err)
+	assert.Nil(t, shelves)
+}
+
+func TestCreateShelf(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO shelves`).
+		WithArgs("staff-picks", "Staff Picks", 0, nil, nil, pq.Array([]string{}), pq.Array([]string{"G", "PG"})).
+		WillReturnRows(sqlmock.NewRows([]string{"shelf_id"}).AddRow(3))
+
+	repo := NewRepository(db)
+	shelf, err := repo.CreateShelf(context.Background(), Shelf{
+		Slug: "staff-picks", Title: "Staff Picks", Regions: []string{}, ContentRatings: []string{"G", "PG"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, shelf.ShelfID)
+}
+
+func TestCreateShelf_DuplicateSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO shelves`).WillReturnError(&pq.Error{Code: "23505"})
+
+	repo := NewRepository(db)
+	shelf, err := repo.CreateShelf(context.Background(), Shelf{Slug: "staff-picks", Title: "Staff Picks"})
+	assert.ErrorIs(t, err, ErrDuplicateSlug)
+	assert.Nil(t, shelf)
+}
+
+func TestDeleteShelf_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM shelves WHERE shelf_id = \$1`).
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewRepository(db)
+	err = repo.DeleteShelf(context.Background(), 9)
+	assert.ErrorIs(t, err, ErrShelfNotFound)
+}
+
+func TestSetShelfMovies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT shelf_id FROM shelves WHERE shelf_id = \$1 FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"shelf_id"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM shelf_movies WHERE shelf_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO shelf_movies \(shelf_id, movie_id, position\) SELECT \$1, movie_id, MIN\(position\) FROM unnest\(\$2::int\[\]\) WITH ORDINALITY`).
+		WithArgs(1, pq.Array([]int64{12, 10})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.SetShelfMovies(context.Background(), 1, []int{12, 10})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetShelfMovies_UnknownMovieRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT shelf_id FROM shelves`).
+		WillReturnRows(sqlmock.NewRows([]string{"shelf_id"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM shelf_movies`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO shelf_movies`).WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.SetShelfMovies(context.Background(), 1, []int{99})
+	assert.ErrorIs(t, err, ErrMovieNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetShelfMovies_ShelfNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT shelf_id FROM shelves`).
+		WillReturnRows(sqlmock.NewRows([]string{"shelf_id"}))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.SetShelfMovies(context.Background(), 1, []int{10})
+	assert.ErrorIs(t, err, ErrShelfNotFound)
+}
@@ -0,0 +1,166 @@
+package tags
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeAlreadyTagged = "already_tagged"
+
+	defaultCloudSize = 50
+	maxCloudSize     = 200
+	pendingPageSize  = 100
+)
+
+func AddMovieTagHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie id must be an integer")
+			return
+		}
+		var req AddTagRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "user_id and name are required; visibility must be public or private")
+			return
+		}
+		name, err := NormalizeName(req.Name)
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, err.Error())
+			return
+		}
+		if req.Visibility == "" {
+			req.Visibility = Public
+		}
+
+		tag, err := repo.AddTag(c.Request.Context(), movieID, req.UserID, name, req.Visibility)
+		switch {
+		case errors.Is(err, ErrMovieNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case errors.Is(err, ErrAlreadyTagged):
+			api.Error(c, http.StatusConflict, CodeAlreadyTagged, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			api.OK(c, http.StatusCreated, tag)
+		}
+	}
+}
+
+// ListMovieTagsHandler shows a movie's approved public tags, plus the private
+// tags of the user named by the user_id query parameter.
+func ListMovieTagsHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie id must be an integer")
+			return
+		}
+		userID, ok := optionalInt(c, "user_id")
+		if !ok {
+			return
+		}
+
+		counts, err := repo.ListMovieTags(c.Request.Context(), movieID, userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if counts == nil {
+			counts = []TagCount{}
+		}
+
+		api.List(c, counts, len(counts))
+	}
+}
+
+func TagCloudHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := optionalInt(c, "user_id")
+		if !ok {
+			return
+		}
+		limit, ok := optionalInt(c, "limit")
+		if !ok {
+			return
+		}
+		if limit <= 0 {
+			limit = defaultCloudSize
+		}
+		if limit > maxCloudSize {
+			limit = maxCloudSize
+		}
+
+		counts, err := repo.TagCloud(c.Request.Context(), userID, limit)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if counts == nil {
+			counts = []TagCount{}
+		}
+
+		api.List(c, counts, len(counts))
+	}
+}
+
+func ListPendingTagsHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pending, err := repo.ListPending(c.Request.Context(), pendingPageSize)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+		if pending == nil {
+			pending = []MovieTag{}
+		}
+
+		api.List(c, pending, len(pending))
+	}
+}
+
+func ModerateTagHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieTagID, err := strconv.Atoi(c.Param("movie_tag_id"))
+		if err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie_tag_id must be an integer")
+			return
+		}
+		var req ModerateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "status must be approved or rejected")
+			return
+		}
+
+		err = repo.Moderate(c.Request.Context(), movieTagID, req.Status)
+		switch {
+		case errors.Is(err, ErrTagNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+// optionalInt reads an integer query parameter, defaulting to zero. It
+// responds with 400 and returns false when the value is malformed.
+func optionalInt(c *gin.Context, name string) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, name+" must be an integer")
+		return 0, false
+	}
+	return v, true
+}
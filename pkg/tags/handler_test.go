@@ -0,0 +1,222 @@
+package tags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	AddTagFunc        func(movieID, userID int, name string, visibility Visibility) (*MovieTag, error)
+	ListMovieTagsFunc func(movieID, userID int) ([]TagCount, error)
+	TagCloudFunc      func(userID, limit int) ([]TagCount, error)
+	ListPendingFunc   func(limit int) ([]MovieTag, error)
+	ModerateFunc      func(movieTagID int, status Status) error
+}
+
+func (m *mockRepository) AddTag(_ context.Context, movieID, userID int, name string, visibility Visibility) (*MovieTag, error) {
+	return m.AddTagFunc(movieID, userID, name, visibility)
+}
+func (m *mockRepository) ListMovieTags(_ context.Context, movieID, userID int) ([]TagCount, error) {
+	return m.ListMovieTagsFunc(movieID, userID)
+}
+func (m *mockRepository) TagCloud(_ context.Context, userID, limit int) ([]TagCount, error) {
+	return m.TagCloudFunc(userID, limit)
+}
+func (m *mockRepository) ListPending(_ context.Context, limit int) ([]MovieTag, error) {
+	return m.ListPendingFunc(limit)
+}
+func (m *mockRepository) Moderate(_ context.Context, movieTagID int, status Status) error {
+	return m.ModerateFunc(movieTagID, status)
+}
+
+func setupRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.POST("/v2/movies/:id/tags", AddMovieTagHandler(repo))
+	router.GET("/v2/movies/:id/tags", ListMovieTagsHandler(repo))
+	router.GET("/v2/tags/cloud", TagCloudHandler(repo))
+	router.GET("/v2/admin/tags/pending", ListPendingTagsHandler(repo))
+	router.PUT("/v2/admin/tags/:movie_tag_id", ModerateTagHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAddMovieTagHandler_DefaultsToPublic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddTagFunc: func(movieID, userID int, name string, visibility Visibility) (*MovieTag, error) {
+			assert.Equal(t, 1, movieID)
+			assert.Equal(t, 2, userID)
+			assert.Equal(t, "cult classic", name)
+			assert.Equal(t, Public, visibility)
+			return &MovieTag{MovieTagID: 7, MovieID: 1, UserID: 2, Name: name, Visibility: visibility, Status: StatusPending}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/movies/1/tags", `{"user_id":2,"name":"Cult Classic"}`)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"status":"pending"`)
+}
+
+func TestAddMovieTagHandler_InvalidVisibility(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{})
+
+	recorder := serve(router, "POST", "/v2/movies/1/tags", `{"user_id":2,"name":"x","visibility":"friends"}`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestAddMovieTagHandler_AlreadyTagged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddTagFunc: func(movieID, userID int, name string, visibility Visibility) (*MovieTag, error) {
+			return nil, ErrAlreadyTagged
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/movies/1/tags", `{"user_id":2,"name":"christmas","visibility":"private"}`)
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeAlreadyTagged)
+}
+
+func TestAddMovieTagHandler_MovieNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddTagFunc: func(movieID, userID int, name string, visibility Visibility) (*MovieTag, error) {
+			return nil, ErrMovieNotFound
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/movies/99/tags", `{"user_id":2,"name":"christmas"}`)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestListMovieTagsHandler_PassesOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListMovieTagsFunc: func(movieID, userID int) ([]TagCount, error) {
+			assert.Equal(t, 1, movieID)
+			assert.Equal(t, 2, userID)
+			return []TagCount{{Name: "rewatch", Visibility: Private, Count: 1}}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/movies/1/tags?user_id=2", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"name":"rewatch"`)
+}
+
+func TestListMovieTagsHandler_InvalidUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{})
+
+	recorder := serve(router, "GET", "/v2/movies/1/tags?user_id=me", "")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestTagCloudHandler_ClampsLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		TagCloudFunc: func(userID, limit int) ([]TagCount, error) {
+			assert.Equal(t, maxCloudSize, limit)
+			return nil, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/tags/cloud?limit=10000", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"api_version":"v2","count":0}}`, recorder.Body.String())
+}
+
+func TestTagCloudHandler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		TagCloudFunc: func(userID, limit int) ([]TagCount, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/tags/cloud", "")
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestListPendingTagsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListPendingFunc: func(limit int) ([]MovieTag, error) {
+			return []MovieTag{{MovieTagID: 7, Name: "cult classic", Status: StatusPending}}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/admin/tags/pending", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"movie_tag_id":7`)
+}
+
+func TestModerateTagHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ModerateFunc: func(movieTagID int, status Status) error {
+			assert.Equal(t, 7, movieTagID)
+			assert.Equal(t, StatusApproved, status)
+			return nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/tags/7", `{"status":"approved"}`)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+}
+
+func TestModerateTagHandler_InvalidStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{})
+
+	recorder := serve(router, "PUT", "/v2/admin/tags/7", `{"status":"pending"}`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestModerateTagHandler_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ModerateFunc: func(movieTagID int, status Status) error {
+			return ErrTagNotFound
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "PUT", "/v2/admin/tags/7", `{"status":"rejected"}`)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,71 @@
+package tags
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+type Visibility string
+
+const (
+	Public  Visibility = "public"
+	Private Visibility = "private"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+const maxNameLength = 64
+
+// MovieTag is one user's tag on one movie. Public tags need moderator
+// approval before anyone else sees them; private tags are approved on
+// creation and only ever shown to their owner.
+type MovieTag struct {
+	XMLName    xml.Name   `json:"-" xml:"movie_tag"`
+	MovieTagID int        `json:"movie_tag_id" xml:"movie_tag_id"`
+	MovieID    int        `json:"movie_id" xml:"movie_id"`
+	UserID     int        `json:"user_id" xml:"user_id"`
+	Name       string     `json:"name" xml:"name"`
+	Visibility Visibility `json:"visibility" xml:"visibility"`
+	Status     Status     `json:"status" xml:"status"`
+	CreatedAt  time.Time  `json:"created_at" xml:"created_at"`
+}
+
+// TagCount is how many times a tag was applied, either to one movie or
+// across the catalog for a tag cloud.
+type TagCount struct {
+	XMLName    xml.Name   `json:"-" xml:"tag"`
+	Name       string     `json:"name" xml:"name"`
+	Visibility Visibility `json:"visibility" xml:"visibility"`
+	Count      int        `json:"count" xml:"count"`
+}
+
+type AddTagRequest struct {
+	UserID     int        `json:"user_id" binding:"required,gt=0"`
+	Name       string     `json:"name" binding:"required"`
+	Visibility Visibility `json:"visibility" binding:"omitempty,oneof=public private"`
+}
+
+type ModerateRequest struct {
+	Status Status `json:"status" binding:"required,oneof=approved rejected"`
+}
+
+// NormalizeName lowercases a tag and collapses its whitespace so that
+// "Cult  Classic" and "cult classic" are the same tag.
+func NormalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" {
+		return "", errors.New("tag name must not be empty")
+	}
+	if len(name) > maxNameLength {
+		return "", errors.New("tag name must be at most 64 characters")
+	}
+	return name, nil
+}
@@ -0,0 +1,20 @@
+package tags
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeName(t *testing.T) {
+	name, err := NormalizeName("  Cult   Classic ")
+	assert.NoError(t, err)
+	assert.Equal(t, "cult classic", name)
+
+	_, err = NormalizeName("   ")
+	assert.Error(t, err)
+
+	_, err = NormalizeName(strings.Repeat("a", 65))
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+package tags
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrMovieNotFound = errors.New("movie not found")
+	ErrAlreadyTagged = errors.New("movie already has this tag")
+	ErrTagNotFound   = errors.New("public tag not found")
+)
+
+type Repository interface {
+	AddTag(ctx context.Context, movieID, userID int, name string, visibility Visibility) (*MovieTag, error)
+	ListMovieTags(ctx context.Context, movieID, userID int) ([]TagCount, error)
+	TagCloud(ctx context.Context, userID, limit int) ([]TagCount, error)
+	ListPending(ctx context.Context, limit int) ([]MovieTag, error)
+	Moderate(ctx context.Context, movieTagID int, status Status) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) AddTag(ctx context.Context, movieID, userID int, name string, visibility Visibility) (*MovieTag, error) {
+	t := MovieTag{MovieID: movieID, UserID: userID, Name: name, Visibility: visibility, Status: StatusPending}
+	if visibility == Private {
+		t.Status = StatusApproved
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		WITH t AS (
+			INSERT INTO tags (name) VALUES ($3)
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING tag_id
+		)
+		INSERT INTO movie_tags (movie_id, tag_id, user_id, visibility, status)
+		SELECT $1, tag_id, $2, $4, $5 FROM t
+		ON CONFLICT (movie_id, tag_id, user_id) DO NOTHING
+		RETURNING movie_tag_id, created_at`,
+		movieID, userID, name, visibility, t.Status,
+	).Scan(&t.MovieTagID, &t.CreatedAt)
+
+	var pqErr *pq.Error
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, ErrAlreadyTagged
+	case errors.As(err, &pqErr) && pqErr.Code == "23503":
+		return nil, ErrMovieNotFound
+	case err != nil:
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListMovieTags returns the approved public tags of a movie together with
+// the private tags userID put on it.
+func (r *repository) ListMovieTags(ctx context.Context, movieID, userID int) ([]TagCount, error) {
+	return r.queryCounts(ctx, `
+		SELECT t.name, mt.visibility, COUNT(*)
+		FROM movie_tags mt
+		JOIN tags t ON t.tag_id = mt.tag_id
+		WHERE mt.movie_id = $1
+		AND ((mt.visibility = 'public' AND mt.status = 'approved') OR (mt.visibility = 'private' AND mt.user_id = $2))
+		GROUP BY t.name, mt.visibility
+		ORDER BY COUNT(*) DESC, t.name`, movieID, userID)
+}
+
+// TagCloud returns the most used approved public tags across the catalog
+// together with userID's private tags.
+func (r *repository) TagCloud(ctx context.Context, userID, limit int) ([]TagCount, error) {
+	return r.queryCounts(ctx, `
+		SELECT t.name, mt.visibility, COUNT(*)
+		FROM movie_tags mt
+		JOIN tags t ON t.tag_id = mt.tag_id
+		WHERE (mt.visibility = 'public' AND mt.status = 'approved') OR (mt.visibility = 'private' AND mt.user_id = $1)
+		GROUP BY t.name, mt.visibility
+		ORDER BY COUNT(*) DESC, t.name
+		LIMIT $2`, userID, limit)
+}
+
+func (r *repository) ListPending(ctx context.Context, limit int) ([]MovieTag, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT mt.movie_tag_id, mt.movie_id, mt.user_id, t.name, mt.visibility, mt.status, mt.created_at
+		FROM movie_tags mt
+		JOIN tags t ON t.tag_id = mt.tag_id
+		WHERE mt.status = 'pending'
+		ORDER BY mt.created_at, mt.movie_tag_id
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []MovieTag
+	for rows.Next() {
+		var t MovieTag
+		if err := rows.Scan(&t.MovieTagID, &t.MovieID, &t.UserID, &t.Name, &t.Visibility, &t.Status, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		pending = append(pending, t)
+	}
+	return pending, rows.Err()
+}
+
+func (r *repository) Moderate(ctx context.Context, movieTagID int, status Status) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE movie_tags SET status = $2 WHERE movie_tag_id = $1 AND visibility = 'public'",
+		movieTagID, status)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+func (r *repository) queryCounts(ctx context.Context, query string, args ...interface{}) ([]TagCount, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []TagCount
+	for rows.Next() {
+		var c TagCount
+		if err := rows.Scan(&c.Name, &c.Visibility, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,156 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const addTagQuery = `WITH t AS \( INSERT INTO tags \(name\) VALUES \(\$3\) ON CONFLICT \(name\) DO UPDATE SET name = EXCLUDED.name RETURNING tag_id \) INSERT INTO movie_tags`
+
+func TestAddTag_PublicIsPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	created := time.Now()
+	mock.ExpectQuery(addTagQuery).
+		WithArgs(1, 2, "cult classic", Public, StatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_tag_id", "created_at"}).AddRow(7, created))
+
+	repo := NewRepository(db)
+	tag, err := repo.AddTag(context.Background(), 1, 2, "cult classic", Public)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, tag.MovieTagID)
+	assert.Equal(t, StatusPending, tag.Status)
+}
+
+func TestAddTag_PrivateIsApproved(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(addTagQuery).
+		WithArgs(1, 2, "date night", Private, StatusApproved).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_tag_id", "created_at"}).AddRow(8, time.Now()))
+
+	repo := NewRepository(db)
+	tag, err := repo.AddTag(context.Background(), 1, 2, "date night", Private)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusApproved, tag.Status)
+}
+
+func TestAddTag_AlreadyTagged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(addTagQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_tag_id", "created_at"}))
+
+	repo := NewRepository(db)
+	tag, err := repo.AddTag(context.Background(), 1, 2, "christmas", Public)
+	assert.ErrorIs(t, err, ErrAlreadyTagged)
+	assert.Nil(t, tag)
+}
+
+func TestAddTag_MovieNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(addTagQuery).
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	repo := NewRepository(db)
+	tag, err := repo.AddTag(context.Background(), 99, 2, "christmas", Public)
+	assert.ErrorIs(t, err, ErrMovieNotFound)
+	assert.Nil(t, tag)
+}
+
+func TestListMovieTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"name", "visibility", "count"}).
+		AddRow("cult classic", "public", 3).
+		AddRow("rewatch", "private", 1)
+	mock.ExpectQuery(`SELECT t.name, mt.visibility, COUNT\(\*\) FROM movie_tags mt JOIN tags t ON t.tag_id = mt.tag_id WHERE mt.movie_id = \$1`).
+		WithArgs(1, 2).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db)
+	counts, err := repo.ListMovieTags(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []TagCount{
+		{Name: "cult classic", Visibility: Public, Count: 3},
+		{Name: "rewatch", Visibility: Private, Count: 1},
+	}, counts)
+}
+
+func TestTagCloud_DBError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT t.name, mt.visibility, COUNT\(\*\) FROM movie_tags mt`).
+		WithArgs(0, 50).
+		WillReturnError(errors.New("db error"))
+
+	repo := NewRepository(db)
+	counts, err := repo.TagCloud(context.Background(), 0, 50)
+	assert.Error(t, err)
+	assert.Nil(t, counts)
+}
+
+func TestListPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"movie_tag_id", "movie_id", "user_id", "name", "visibility", "status", "created_at"}).
+		AddRow(7, 1, 2, "cult classic", "public", "pending", time.Now())
+	mock.ExpectQuery(`SELECT (.+) FROM movie_tags mt JOIN tags t ON t.tag_id = mt.tag_id WHERE mt.status = 'pending'`).
+		WithArgs(100).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db)
+	pending, err := repo.ListPending(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, "cult classic", pending[0].Name)
+}
+
+func TestModerate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE movie_tags SET status = \$2 WHERE movie_tag_id = \$1 AND visibility = 'public'`).
+		WithArgs(7, StatusApproved).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewRepository(db)
+	err = repo.Moderate(context.Background(), 7, StatusApproved)
+	assert.NoError(t, err)
+}
+
+func TestModerate_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE movie_tags SET status`).
+		WithArgs(7, StatusRejected).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewRepository(db)
+	err = repo.Moderate(context.Background(), 7, StatusRejected)
+	assert.ErrorIs(t, err, ErrTagNotFound)
+}
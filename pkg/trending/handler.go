@@ -0,0 +1,51 @@
+package trending
+
+import (
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/movies"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// TrendingHandler serves the precomputed ranking for ?window=24h|7d,
+// defaulting to 24h, of the movies licensed in the caller's region.
+func TrendingHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, ok := WindowByName(c.DefaultQuery("window", Day.Name))
+		if !ok {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "window must be 24h or 7d")
+			return
+		}
+		limit := defaultLimit
+		if raw := c.Query("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "limit must be a positive integer")
+				return
+			}
+			limit = min(n, maxLimit)
+		}
+		userRegion, err := movies.RequestRegion(c)
+		if err != nil {
+			movies.RespondRegionError(c, err)
+			return
+		}
+
+		ranked, err := repo.Top(c.Request.Context(), w, userRegion, limit)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		resp := NewTrendingResponses(ranked)
+		api.List(c, resp, len(resp))
+	}
+}
@@ -0,0 +1,120 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"movie-rental/pkg/movies"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	RecomputeFunc func(w Window, now time.Time) error
+	TopFunc       func(w Window, region string, limit int) ([]TrendingMovie, error)
+}
+
+func (m *mockRepository) Recompute(_ context.Context, w Window, now time.Time) error {
+	return m.RecomputeFunc(w, now)
+}
+func (m *mockRepository) Top(_ context.Context, w Window, region string, limit int) ([]TrendingMovie, error) {
+	return m.TopFunc(w, region, limit)
+}
+
+func setupRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/movies/trending", TrendingHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestTrendingHandler_DefaultsToDay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		TopFunc: func(w Window, region string, limit int) ([]TrendingMovie, error) {
+			assert.Equal(t, Day, w)
+			assert.Equal(t, defaultLimit, limit)
+			return []TrendingMovie{
+				{Score: 9, Movie: movies.Movie{MovieID: 1, Title: "Movie 1"}},
+				{Score: 4, Movie: movies.Movie{MovieID: 2, Title: "Movie 2"}},
+			}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "/v2/movies/trending")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `{"rank":1,"score":9,"movie":{"movie_id":1`)
+	assert.Contains(t, recorder.Body.String(), `{"rank":2,"score":4,"movie":{"movie_id":2`)
+}
+
+func TestTrendingHandler_Week(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		TopFunc: func(w Window, region string, limit int) ([]TrendingMovie, error) {
+			assert.Equal(t, Week, w)
+			assert.Equal(t, maxLimit, limit)
+			return nil, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "/v2/movies/trending?window=7d&limit=500")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"api_version":"v2","count":0}}`, recorder.Body.String())
+}
+
+func TestTrendingHandler_InvalidWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{})
+
+	recorder := serve(router, "/v2/movies/trending?window=1h")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestTrendingHandler_DBError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		TopFunc: func(w Window, region string, limit int) ([]TrendingMovie, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "/v2/movies/trending")
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestTrendingHandler_Region(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		TopFunc: func(w Window, region string, limit int) ([]TrendingMovie, error) {
+			assert.Equal(t, "GB", region)
+			return nil, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	req, _ := http.NewRequest("GET", "/v2/movies/trending", nil)
+	req.Header.Set("X-Region", "gb")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = serve(router, "/v2/movies/trending?user_id=abc")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,62 @@
+package trending
+
+import (
+	"encoding/xml"
+	"time"
+
+	"movie-rental/pkg/movies"
+)
+
+// Window is a ranking period. Events older than Span are ignored and the
+// weight of the rest halves every HalfLife, so recent activity dominates.
+type Window struct {
+	Name     string
+	Span     time.Duration
+	HalfLife time.Duration
+}
+
+var (
+	Day  = Window{Name: "24h", Span: 24 * time.Hour, HalfLife: 6 * time.Hour}
+	Week = Window{Name: "7d", Span: 7 * 24 * time.Hour, HalfLife: 2 * 24 * time.Hour}
+
+	Windows = []Window{Day, Week}
+)
+
+func WindowByName(name string) (Window, bool) {
+	for _, w := range Windows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+// Event kinds recorded in activity_events that count towards a movie's
+// score, with how much each counts. Other kinds are ignored.
+const (
+	KindAddToCart = "add_to_cart"
+	KindRental    = "rental"
+
+	addToCartWeight = 1
+	rentalWeight    = 3
+)
+
+type TrendingMovie struct {
+	Score float64
+	Movie movies.Movie
+}
+
+type TrendingResponse struct {
+	XMLName xml.Name             `json:"-" xml:"trending_movie"`
+	Rank    int                  `json:"rank" xml:"rank"`
+	Score   float64              `json:"score" xml:"score"`
+	Movie   movies.MovieResponse `json:"movie" xml:"movie"`
+}
+
+func NewTrendingResponses(ranked []TrendingMovie) []TrendingResponse {
+	out := make([]TrendingResponse, len(ranked))
+	for i, t := range ranked {
+		out[i] = TrendingResponse{Rank: i + 1, Score: t.Score, Movie: movies.NewMovieResponse(t.Movie)}
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package trending
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Refresher periodically recomputes the stored rankings so that serving
+// them stays a cheap indexed read.
+type Refresher struct {
+	repo     Repository
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewRefresher(repo Repository, interval time.Duration) *Refresher {
+	return &Refresher{repo: repo, interval: interval, now: time.Now}
+}
+
+// Run refreshes every window immediately and then once per interval until
+// ctx is cancelled.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.RefreshAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Refresher) RefreshAll(ctx context.Context) {
+	now := r.now()
+	for _, w := range Windows {
+		if err := r.repo.Recompute(ctx, w, now); err != nil {
+			log.Printf("trending: recompute %s: %v", w.Name, err)
+		}
+	}
+}
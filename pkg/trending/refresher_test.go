@@ -0,0 +1,56 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefresher_RefreshAllRecomputesEveryWindow(t *testing.T) {
+	now := time.Now()
+	var refreshed []string
+	repo := &mockRepository{
+		RecomputeFunc: func(w Window, at time.Time) error {
+			assert.Equal(t, now, at)
+			refreshed = append(refreshed, w.Name)
+			if w == Day {
+				return errors.New("db error")
+			}
+			return nil
+		},
+	}
+	r := NewRefresher(repo, time.Minute)
+	r.now = func() time.Time { return now }
+
+	r.RefreshAll(context.Background())
+
+	assert.Equal(t, []string{"24h", "7d"}, refreshed)
+}
+
+func TestRefresher_RunStopsWithContext(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	repo := &mockRepository{
+		RecomputeFunc: func(w Window, at time.Time) error {
+			calls <- struct{}{}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewRefresher(repo, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	<-calls
+	<-calls
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
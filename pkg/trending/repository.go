@@ -0,0 +1,78 @@
+package trending
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"movie-rental/pkg/movies"
+)
+
+type Repository interface {
+	// Recompute replaces the stored ranking for w with one computed from
+	// the activity events as of now.
+	Recompute(ctx context.Context, w Window, now time.Time) error
+	// Top returns the highest scored movies of w that are licensed in
+	// region.
+	Top(ctx context.Context, w Window, region string, limit int) ([]TrendingMovie, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Recompute(ctx context.Context, w Window, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM trending_scores WHERE time_window = $1", w.Name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO trending_scores (time_window, movie_id, score, computed_at)
+		SELECT $1, movie_id,
+			SUM(CASE kind WHEN $5::text THEN $6::float8 ELSE $7::float8 END
+				* POWER(0.5, EXTRACT(EPOCH FROM $2::timestamptz - occurred_at) / $4::float8)),
+			$2
+		FROM activity_events
+		WHERE occurred_at > $2::timestamptz - make_interval(secs => $3) AND occurred_at <= $2
+		AND kind IN ($5, $8)
+		GROUP BY movie_id`,
+		w.Name, now, w.Span.Seconds(), w.HalfLife.Seconds(), KindRental, rentalWeight, addToCartWeight, KindAddToCart,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) Top(ctx context.Context, w Window, region string, limit int) ([]TrendingMovie, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ts.score, `+movies.SelectColumns("m")+`
+		FROM trending_scores ts
+		JOIN movies m ON m.movie_id = ts.movie_id
+		WHERE ts.time_window = $1 AND `+movies.LicensedClause("m.movie_id", 3)+`
+		ORDER BY ts.score DESC, m.movie_id
+		LIMIT $2`, w.Name, limit, region)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranked []TrendingMovie
+	for rows.Next() {
+		var t TrendingMovie
+		if err := rows.Scan(append([]interface{}{&t.Score}, movies.ScanFields(&t.Movie)...)...); err != nil {
+			return nil, err
+		}
+		ranked = append(ranked, t)
+	}
+	return ranked, rows.Err()
+}
@@ -0,0 +1,82 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecompute(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM trending_scores WHERE time_window = \$1`).
+		WithArgs("24h").
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(`INSERT INTO trending_scores \(time_window, movie_id, score, computed_at\) SELECT (.+) FROM activity_events`).
+		WithArgs("24h", now, 86400.0, 21600.0, KindRental, rentalWeight, addToCartWeight, KindAddToCart).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.Recompute(context.Background(), Day, now)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecompute_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM trending_scores`).WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(`INSERT INTO trending_scores`).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.Recompute(context.Background(), Week, time.Now())
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"score", "movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until"}).
+		AddRow(12.5, 1, "Movie 1", 2020, "", "", "", "", nil, nil).
+		AddRow(3.0, 2, "Movie 2", 2021, "", "", "", "", nil, nil)
+	mock.ExpectQuery(`SELECT ts.score, m.movie_id, (.+) FROM trending_scores ts JOIN movies m ON m.movie_id = ts.movie_id WHERE ts.time_window = \$1 `+
+		`AND \(NOT EXISTS \(SELECT 1 FROM movie_territories WHERE movie_id = m.movie_id\) OR EXISTS (.+) region = \$3 (.+) ORDER BY ts.score DESC`).
+		WithArgs("7d", 20, "GB").
+		WillReturnRows(rows)
+
+	repo := NewRepository(db)
+	ranked, err := repo.Top(context.Background(), Week, "GB", 20)
+	assert.NoError(t, err)
+	assert.Len(t, ranked, 2)
+	assert.Equal(t, 12.5, ranked[0].Score)
+	assert.Equal(t, "Movie 1", ranked[0].Movie.Title)
+}
+
+func TestTop_DBError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT ts.score`).WillReturnError(errors.New("db error"))
+
+	repo := NewRepository(db)
+	ranked, err := repo.Top(context.Background(), Day, "GB", 20)
+	assert.Error(t, err)
+	assert.Nil(t, ranked)
+}
@@ -0,0 +1,173 @@
+package wishlist
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"movie-rental/pkg/api"
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+const CodeAlreadyInWishlist = "already_in_wishlist"
+
+// ItemResponse acknowledges a movie saved to a wishlist.
+type ItemResponse struct {
+	XMLName    xml.Name    `json:"-" xml:"wishlist_item"`
+	UserID     int         `json:"user_id" xml:"user_id"`
+	MovieID    int         `json:"movie_id" xml:"movie_id"`
+	Format     cart.Format `json:"format" xml:"format"`
+	RentalDays int         `json:"rental_days" xml:"rental_days"`
+}
+
+func ListHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := intParam(c, "user_id")
+		if !ok {
+			return
+		}
+
+		entries, err := repo.List(c.Request.Context(), userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		api.List(c, NewEntryResponses(entries), len(entries))
+	}
+}
+
+func AddHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := intParam(c, "user_id")
+		if !ok {
+			return
+		}
+		var req AddRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, "movie_id must be a positive integer")
+			return
+		}
+
+		item := cart.NewItem(req.MovieID, req.Format, req.RentalDays)
+		err := repo.Add(c.Request.Context(), userID, item)
+		switch {
+		case errors.Is(err, ErrMovieNotFound):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case errors.Is(err, ErrAlreadyInWishlist):
+			api.Error(c, http.StatusConflict, CodeAlreadyInWishlist, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			api.OK(c, http.StatusCreated, newItemResponse(userID, item))
+		}
+	}
+}
+
+func RemoveHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := intParam(c, "user_id")
+		if !ok {
+			return
+		}
+		movieID, ok := intParam(c, "movie_id")
+		if !ok {
+			return
+		}
+
+		err := repo.Remove(c.Request.Context(), userID, movieID)
+		switch {
+		case errors.Is(err, ErrNotInWishlist):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+// MoveToCartHandler moves a movie from the user's wishlist to their cart.
+// It is refused for the same reasons as adding the movie to the cart
+// directly, limits included, and the movie then stays on the wishlist.
+func MoveToCartHandler(repo Repository, limiter cart.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := intParam(c, "user_id")
+		if !ok {
+			return
+		}
+		movieID, ok := intParam(c, "movie_id")
+		if !ok {
+			return
+		}
+
+		userRegion, err := region.Resolve(c, userID)
+		if err != nil {
+			api.InternalError(c, err)
+			return
+		}
+
+		item, err := repo.Get(c.Request.Context(), userID, movieID)
+		if err == nil {
+			err = limiter.CheckAdd(c.Request.Context(), userID, cart.PolicyItem(item))
+		}
+		if err == nil {
+			item, err = repo.MoveToCart(c.Request.Context(), userID, movieID, userRegion)
+		}
+		if errors.Is(err, ErrNotInWishlist) {
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+			return
+		} else if err != nil {
+			cart.RespondAddError(c, err)
+			return
+		}
+
+		api.OK(c, http.StatusOK, cart.CartItemResponse{
+			UserID:     userID,
+			MovieID:    item.MovieID,
+			Format:     item.Format,
+			RentalDays: item.RentalDays,
+		})
+	}
+}
+
+// SaveForLaterHandler moves a movie from the user's cart to their wishlist.
+func SaveForLaterHandler(repo Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := intParam(c, "user_id")
+		if !ok {
+			return
+		}
+		movieID, ok := intParam(c, "movie_id")
+		if !ok {
+			return
+		}
+
+		item, err := repo.SaveForLater(c.Request.Context(), userID, movieID)
+		switch {
+		case errors.Is(err, cart.ErrNotInCart):
+			api.Error(c, http.StatusNotFound, api.CodeNotFound, err.Error())
+		case err != nil:
+			api.InternalError(c, err)
+		default:
+			api.OK(c, http.StatusOK, newItemResponse(userID, item))
+		}
+	}
+}
+
+func newItemResponse(userID int, item cart.Item) ItemResponse {
+	return ItemResponse{UserID: userID, MovieID: item.MovieID, Format: item.Format, RentalDays: item.RentalDays}
+}
+
+func intParam(c *gin.Context, name string) (int, bool) {
+	v, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		api.Error(c, http.StatusBadRequest, api.CodeInvalidRequest, name+" must be an integer")
+		return 0, false
+	}
+	return v, true
+}
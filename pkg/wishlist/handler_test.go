@@ -0,0 +1,225 @@
+package wishlist
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+	"movie-rental/pkg/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRepository struct {
+	AddFunc          func(userID int, item cart.Item) error
+	RemoveFunc       func(userID, movieID int) error
+	GetFunc          func(userID, movieID int) (cart.Item, error)
+	ListFunc         func(userID int) ([]Entry, error)
+	MoveToCartFunc   func(userID, movieID int, region string) (cart.Item, error)
+	SaveForLaterFunc func(userID, movieID int) (cart.Item, error)
+}
+
+func (m *mockRepository) Add(_ context.Context, userID int, item cart.Item) error {
+	return m.AddFunc(userID, item)
+}
+func (m *mockRepository) Remove(_ context.Context, userID, movieID int) error {
+	return m.RemoveFunc(userID, movieID)
+}
+func (m *mockRepository) Get(_ context.Context, userID, movieID int) (cart.Item, error) {
+	if m.GetFunc == nil {
+		return cart.Item{MovieID: movieID, Format: cart.FormatDigital, RentalDays: 2}, nil
+	}
+	return m.GetFunc(userID, movieID)
+}
+func (m *mockRepository) List(_ context.Context, userID int) ([]Entry, error) {
+	return m.ListFunc(userID)
+}
+func (m *mockRepository) MoveToCart(_ context.Context, userID, movieID int, region string) (cart.Item, error) {
+	return m.MoveToCartFunc(userID, movieID, region)
+}
+func (m *mockRepository) SaveForLater(_ context.Context, userID, movieID int) (cart.Item, error) {
+	return m.SaveForLaterFunc(userID, movieID)
+}
+
+// limiter refuses movie 9 as one too many for the cart.
+type limiter struct{}
+
+func (limiter) CheckAdd(_ context.Context, userID int, items ...policy.Item) error {
+	if items[0].MovieID == 9 {
+		return &policy.Violation{Rule: policy.RuleMaxCartItems, Tier: policy.DefaultTier, Limit: 5}
+	}
+	return nil
+}
+func (l limiter) CheckEach(ctx context.Context, userID int, items ...policy.Item) ([]error, error) {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = l.CheckAdd(ctx, userID, item)
+	}
+	return errs, nil
+}
+
+func setupRouter(repo Repository) *gin.Engine {
+	router := gin.Default()
+	router.GET("/v2/wishlist/:user_id", ListHandler(repo))
+	router.POST("/v2/wishlist/:user_id", AddHandler(repo))
+	router.DELETE("/v2/wishlist/:user_id/items/:movie_id", RemoveHandler(repo))
+	router.POST("/v2/wishlist/:user_id/items/:movie_id/move-to-cart", MoveToCartHandler(repo, limiter{}))
+	router.POST("/v2/cart/:user_id/items/:movie_id/save-for-later", SaveForLaterHandler(repo))
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Region", "us")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestListHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		ListFunc: func(userID int) ([]Entry, error) {
+			assert.Equal(t, 1, userID)
+			return []Entry{{Movie: movies.Movie{MovieID: 2, Title: "Movie 2"}, Format: cart.FormatDVD, RentalDays: 7}}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "GET", "/v2/wishlist/1", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"movie":{"movie_id":2,"title":"Movie 2"`)
+	assert.Contains(t, recorder.Body.String(), `"format":"dvd","rental_days":7`)
+	assert.Contains(t, recorder.Body.String(), `"count":1`)
+}
+
+func TestAddHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		AddFunc: func(userID int, item cart.Item) error {
+			assert.Equal(t, cart.Item{MovieID: 2, Format: cart.FormatDigital, RentalDays: 2}, item)
+			return nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/wishlist/1", `{"movie_id":2}`)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.JSONEq(t, `{"data":{"user_id":1,"movie_id":2,"format":"digital","rental_days":2},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestAddHandler_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var err error
+	router := setupRouter(&mockRepository{
+		AddFunc: func(userID int, item cart.Item) error { return err },
+	})
+
+	err = ErrAlreadyInWishlist
+	recorder := serve(router, "POST", "/v2/wishlist/1", `{"movie_id":2}`)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), CodeAlreadyInWishlist)
+
+	err = ErrMovieNotFound
+	recorder = serve(router, "POST", "/v2/wishlist/1", `{"movie_id":2}`)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = serve(router, "POST", "/v2/wishlist/1", `{"movie_id":2,"format":"vhs"}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRemoveHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		RemoveFunc: func(userID, movieID int) error {
+			if movieID == 3 {
+				return ErrNotInWishlist
+			}
+			return nil
+		},
+	}
+	router := setupRouter(repo)
+
+	assert.Equal(t, http.StatusNoContent, serve(router, "DELETE", "/v2/wishlist/1/items/2", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "DELETE", "/v2/wishlist/1/items/3", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, "DELETE", "/v2/wishlist/1/items/x", "").Code)
+}
+
+func TestMoveToCartHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		MoveToCartFunc: func(userID, movieID int, region string) (cart.Item, error) {
+			assert.Equal(t, "US", region)
+			return cart.Item{MovieID: movieID, Format: cart.FormatBluRay, RentalDays: 7}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/wishlist/1/items/2/move-to-cart", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"user_id":1,"movie_id":2,"format":"bluray","rental_days":7},"meta":{"api_version":"v2"}}`, recorder.Body.String())
+}
+
+func TestMoveToCartHandler_Refused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var err error
+	router := setupRouter(&mockRepository{
+		MoveToCartFunc: func(userID, movieID int, region string) (cart.Item, error) { return cart.Item{}, err },
+	})
+
+	for want, e := range map[int]error{
+		http.StatusNotFound:                   ErrNotInWishlist,
+		http.StatusConflict:                   cart.ErrAlreadyInCart,
+		http.StatusUnavailableForLegalReasons: cart.ErrNotLicensed,
+		http.StatusInternalServerError:        errors.New("db error"),
+	} {
+		err = e
+		recorder := serve(router, "POST", "/v2/wishlist/1/items/2/move-to-cart", "")
+		assert.Equal(t, want, recorder.Code, e.Error())
+	}
+}
+
+func TestMoveToCartHandler_LimitExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&mockRepository{
+		MoveToCartFunc: func(userID, movieID int, region string) (cart.Item, error) {
+			t.Fatal("moved despite the limit")
+			return cart.Item{}, nil
+		},
+	})
+
+	recorder := serve(router, "POST", "/v2/wishlist/1/items/9/move-to-cart", "")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"rule":"max_cart_items"`)
+}
+
+func TestSaveForLaterHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepository{
+		SaveForLaterFunc: func(userID, movieID int) (cart.Item, error) {
+			if movieID == 3 {
+				return cart.Item{}, cart.ErrNotInCart
+			}
+			return cart.Item{MovieID: movieID, Format: cart.FormatDigital, RentalDays: 2}, nil
+		},
+	}
+	router := setupRouter(repo)
+
+	recorder := serve(router, "POST", "/v2/cart/1/items/2/save-for-later", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"movie_id":2`)
+
+	recorder = serve(router, "POST", "/v2/cart/1/items/3/save-for-later", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,54 @@
+package wishlist
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+)
+
+var (
+	ErrMovieNotFound     = errors.New("movie not found")
+	ErrNotInWishlist     = errors.New("movie is not in the wishlist")
+	ErrAlreadyInWishlist = errors.New("movie is already in the wishlist")
+)
+
+// Entry is a movie on a user's wishlist. Format and RentalDays are how it
+// will be rented once moved to the cart.
+type Entry struct {
+	Movie      movies.Movie
+	Format     cart.Format
+	RentalDays int
+	AddedAt    time.Time
+}
+
+// AddRequest is the request body for adding a movie to a wishlist. Format
+// and RentalDays default like they do for carts.
+type AddRequest struct {
+	MovieID    int         `json:"movie_id" binding:"required,gt=0"`
+	Format     cart.Format `json:"format" binding:"omitempty,oneof=digital dvd bluray"`
+	RentalDays int         `json:"rental_days" binding:"omitempty,gt=0"`
+}
+
+type EntryResponse struct {
+	XMLName    xml.Name             `json:"-" xml:"item"`
+	Movie      movies.MovieResponse `json:"movie" xml:"movie"`
+	Format     cart.Format          `json:"format" xml:"format"`
+	RentalDays int                  `json:"rental_days" xml:"rental_days"`
+	AddedAt    time.Time            `json:"added_at" xml:"added_at"`
+}
+
+func NewEntryResponses(entries []Entry) []EntryResponse {
+	out := make([]EntryResponse, len(entries))
+	for i, e := range entries {
+		out[i] = EntryResponse{
+			Movie:      movies.NewMovieResponse(e.Movie),
+			Format:     e.Format,
+			RentalDays: e.RentalDays,
+			AddedAt:    e.AddedAt,
+		}
+	}
+	return out
+}
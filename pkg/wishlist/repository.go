@@ -0,0 +1,178 @@
+package wishlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"movie-rental/pkg/cart"
+	"movie-rental/pkg/movies"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	Add(ctx context.Context, userID int, item cart.Item) error
+	Remove(ctx context.Context, userID, movieID int) error
+	// Get returns how the user's wishlisted movie is to be rented.
+	Get(ctx context.Context, userID, movieID int) (cart.Item, error)
+	// List returns the user's wishlist, most recently added first.
+	List(ctx context.Context, userID int) ([]Entry, error)
+	// MoveToCart moves a movie from the user's wishlist to their cart,
+	// keeping its format and rental duration. The movie must be rentable in
+	// region; if it is not, or is already in the cart, it stays on the
+	// wishlist.
+	MoveToCart(ctx context.Context, userID, movieID int, region string) (cart.Item, error)
+	// SaveForLater moves a movie from the user's cart to their wishlist,
+	// replacing the options of any entry it already has there.
+	SaveForLater(ctx context.Context, userID, movieID int) (cart.Item, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// Add does not check that the movie is rentable: users may wish for movies
+// that are not out yet.
+func (r *repository) Add(ctx context.Context, userID int, item cart.Item) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO wishlist (user_id, movie_id, format, rental_days) VALUES ($1, $2, $3, $4)",
+		userID, item.MovieID, item.Format, item.RentalDays)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503":
+			return ErrMovieNotFound
+		case "23505":
+			return ErrAlreadyInWishlist
+		}
+	}
+	return err
+}
+
+func (r *repository) Remove(ctx context.Context, userID, movieID int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM wishlist WHERE user_id = $1 AND movie_id = $2", userID, movieID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotInWishlist
+	}
+	return nil
+}
+
+func (r *repository) Get(ctx context.Context, userID, movieID int) (cart.Item, error) {
+	item := cart.Item{MovieID: movieID}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT format, rental_days FROM wishlist WHERE user_id = $1 AND movie_id = $2",
+		userID, movieID).Scan(&item.Format, &item.RentalDays)
+	if err == sql.ErrNoRows {
+		return item, ErrNotInWishlist
+	}
+	return item, err
+}
+
+func (r *repository) List(ctx context.Context, userID int) ([]Entry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+movies.SelectColumns("m")+`, w.format, w.rental_days, w.added_at
+		FROM wishlist w
+		JOIN movies m ON w.movie_id = m.movie_id
+		WHERE w.user_id = $1
+		ORDER BY w.added_at DESC, m.movie_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(append(movies.ScanFields(&e.Movie), &e.Format, &e.RentalDays, &e.AddedAt)...); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *repository) MoveToCart(ctx context.Context, userID, movieID int, region string) (cart.Item, error) {
+	item := cart.Item{MovieID: movieID}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return item, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM wishlist WHERE user_id = $1 AND movie_id = $2 RETURNING format, rental_days",
+		userID, movieID).Scan(&item.Format, &item.RentalDays)
+	if err == sql.ErrNoRows {
+		return item, ErrNotInWishlist
+	} else if err != nil {
+		return item, err
+	}
+
+	if err := cart.CheckItem(ctx, tx, item, region); err != nil {
+		return item, err
+	}
+
+	// Like cart.AddToCart, the add_to_cart event is written with the insert
+	// for trending.
+	res, err := tx.ExecContext(ctx, `
+		WITH added AS (
+			INSERT INTO cart (user_id, movie_id, format, rental_days, price) VALUES ($1, $2, $3, $4, `+cart.ListPrice+`)
+			ON CONFLICT (user_id, movie_id) DO NOTHING
+			RETURNING user_id, movie_id
+		)
+		INSERT INTO activity_events (user_id, movie_id, kind)
+		SELECT user_id, movie_id, 'add_to_cart' FROM added`,
+		userID, item.MovieID, item.Format, item.RentalDays)
+	if err != nil {
+		return item, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return item, err
+	}
+	if n == 0 {
+		return item, cart.ErrAlreadyInCart
+	}
+
+	return item, tx.Commit()
+}
+
+func (r *repository) SaveForLater(ctx context.Context, userID, movieID int) (cart.Item, error) {
+	item := cart.Item{MovieID: movieID}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return item, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM cart WHERE user_id = $1 AND movie_id = $2 RETURNING format, rental_days",
+		userID, movieID).Scan(&item.Format, &item.RentalDays)
+	if err == sql.ErrNoRows {
+		return item, cart.ErrNotInCart
+	} else if err != nil {
+		return item, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO wishlist (user_id, movie_id, format, rental_days) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, movie_id) DO UPDATE
+		SET format = EXCLUDED.format, rental_days = EXCLUDED.rental_days, added_at = NOW()`,
+		userID, item.MovieID, item.Format, item.RentalDays); err != nil {
+		return item, err
+	}
+
+	return item, tx.Commit()
+}
@@ -0,0 +1,163 @@
+package wishlist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movie-rental/pkg/cart"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectCartCheck(mock sqlmock.Sqlmock, movieID int, region string, licensed, offered bool) {
+	mock.ExpectQuery(`SELECT available_from, available_until, (.+) FROM movies WHERE movie_id = \$1`).
+		WithArgs(movieID, region, cart.FormatBluRay, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"available_from", "available_until", "licensed", "offered"}).AddRow(nil, nil, licensed, offered))
+}
+
+func TestAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO wishlist \(user_id, movie_id, format, rental_days\) VALUES \(\$1, \$2, \$3, \$4\)`).
+		WithArgs(1, 2, cart.FormatDigital, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO wishlist`).
+		WithArgs(1, 2, cart.FormatDigital, 2).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectExec(`INSERT INTO wishlist`).
+		WithArgs(1, 99, cart.FormatDigital, 2).WillReturnError(&pq.Error{Code: "23503"})
+
+	repo := NewRepository(db)
+	assert.NoError(t, repo.Add(context.Background(), 1, cart.NewItem(2, "", 0)))
+	assert.ErrorIs(t, repo.Add(context.Background(), 1, cart.NewItem(2, "", 0)), ErrAlreadyInWishlist)
+	assert.ErrorIs(t, repo.Add(context.Background(), 1, cart.NewItem(99, "", 0)), ErrMovieNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemove_NotInWishlist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM wishlist WHERE user_id = \$1 AND movie_id = \$2`).
+		WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewRepository(db).Remove(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, ErrNotInWishlist)
+}
+
+func TestGet_NotInWishlist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT format, rental_days FROM wishlist WHERE user_id = \$1 AND movie_id = \$2`).
+		WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"format", "rental_days"}))
+
+	_, err = NewRepository(db).Get(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, ErrNotInWishlist)
+}
+
+func TestList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	added := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT m.movie_id, (.+), w.format, w.rental_days, w.added_at FROM wishlist w JOIN movies m ON w.movie_id = m.movie_id WHERE w.user_id = \$1 ORDER BY w.added_at DESC`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "title", "year", "plot", "genre", "imdbid", "actors", "available_from", "available_until", "format", "rental_days", "added_at"}).
+			AddRow(2, "Movie 2", 2021, "Plot 2", "Drama", "tt7654321", "Actor C", nil, nil, "bluray", 7, added))
+
+	entries, err := NewRepository(db).List(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Movie 2", entries[0].Movie.Title)
+	assert.Equal(t, cart.FormatBluRay, entries[0].Format)
+	assert.Equal(t, added, entries[0].AddedAt)
+}
+
+func TestMoveToCart(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM wishlist WHERE user_id = \$1 AND movie_id = \$2 RETURNING format, rental_days`).
+		WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"format", "rental_days"}).AddRow("bluray", 7))
+	expectCartCheck(mock, 2, "US", true, true)
+	mock.ExpectExec(`INSERT INTO cart (.+) ON CONFLICT \(user_id, movie_id\) DO NOTHING (.+) 'add_to_cart'`).
+		WithArgs(1, 2, cart.FormatBluRay, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	item, err := NewRepository(db).MoveToCart(context.Background(), 1, 2, "US")
+	assert.NoError(t, err)
+	assert.Equal(t, cart.Item{MovieID: 2, Format: cart.FormatBluRay, RentalDays: 7}, item)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveToCart_KeptWhenRefused(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"format", "rental_days"}).AddRow("bluray", 7) }
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM wishlist`).WithArgs(1, 2).WillReturnRows(rows())
+	expectCartCheck(mock, 2, "US", false, true)
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM wishlist`).WithArgs(1, 2).WillReturnRows(rows())
+	expectCartCheck(mock, 2, "US", true, true)
+	mock.ExpectExec(`INSERT INTO cart`).WithArgs(1, 2, cart.FormatBluRay, 7).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	_, err = repo.MoveToCart(context.Background(), 1, 2, "US")
+	assert.ErrorIs(t, err, cart.ErrNotLicensed)
+	_, err = repo.MoveToCart(context.Background(), 1, 2, "US")
+	assert.ErrorIs(t, err, cart.ErrAlreadyInCart)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveToCart_NotInWishlist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM wishlist`).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"format", "rental_days"}))
+	mock.ExpectRollback()
+
+	_, err = NewRepository(db).MoveToCart(context.Background(), 1, 2, "")
+	assert.ErrorIs(t, err, ErrNotInWishlist)
+}
+
+func TestSaveForLater(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM cart WHERE user_id = \$1 AND movie_id = \$2 RETURNING format, rental_days`).
+		WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"format", "rental_days"}).AddRow("bluray", 7))
+	mock.ExpectExec(`INSERT INTO wishlist (.+) ON CONFLICT \(user_id, movie_id\) DO UPDATE`).
+		WithArgs(1, 2, cart.FormatBluRay, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM cart`).WithArgs(1, 3).WillReturnRows(sqlmock.NewRows([]string{"format", "rental_days"}))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	item, err := repo.SaveForLater(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, item.RentalDays)
+	_, err = repo.SaveForLater(context.Background(), 1, 3)
+	assert.ErrorIs(t, err, cart.ErrNotInCart)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}